@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+)
+
+func (c *client) ListQuarantine(ctx context.Context) ([]*domain.QuarantinedMessage, error) {
+	messages := []*domain.QuarantinedMessage{}
+
+	if _, err := c.do(ctx, http.MethodGet, "/admin/quarantine", nil, &messages); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (c *client) GetQuarantinedMessage(ctx context.Context, messageId string) (*domain.QuarantinedMessage, error) {
+	message := domain.QuarantinedMessage{}
+
+	if _, err := c.do(ctx, http.MethodGet, "/admin/quarantine/"+url.PathEscape(messageId), nil, &message); err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+func (c *client) DeleteQuarantinedMessage(ctx context.Context, messageId string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/admin/quarantine/"+url.PathEscape(messageId), nil, nil)
+	return err
+}
+
+func (c *client) IssueApiKey(ctx context.Context, apiKeyRequest domain.ApiKeyRequest) (*domain.IssuedApiKey, error) {
+	issued := domain.IssuedApiKey{}
+
+	if _, err := c.do(ctx, http.MethodPost, "/admin/keys", apiKeyRequest, &issued); err != nil {
+		return nil, err
+	}
+
+	return &issued, nil
+}
+
+func (c *client) ListApiKeys(ctx context.Context) ([]*domain.ApiKey, error) {
+	apiKeys := []*domain.ApiKey{}
+
+	if _, err := c.do(ctx, http.MethodGet, "/admin/keys", nil, &apiKeys); err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+func (c *client) RotateApiKey(ctx context.Context, keyId string) (*domain.IssuedApiKey, error) {
+	issued := domain.IssuedApiKey{}
+
+	if _, err := c.do(ctx, http.MethodPost, "/admin/keys/"+url.PathEscape(keyId)+"/rotate", nil, &issued); err != nil {
+		return nil, err
+	}
+
+	return &issued, nil
+}
+
+func (c *client) RevokeApiKey(ctx context.Context, keyId string) (*domain.ApiKey, error) {
+	apiKey := domain.ApiKey{}
+
+	if _, err := c.do(ctx, http.MethodDelete, "/admin/keys/"+url.PathEscape(keyId), nil, &apiKey); err != nil {
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
@@ -0,0 +1,29 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+)
+
+func (c *client) CreateBatch(ctx context.Context, batchRequest domain.BatchRequest) (*domain.Batch, error) {
+	batch := domain.Batch{}
+
+	if _, err := c.do(ctx, http.MethodPost, "/batches", batchRequest, &batch); err != nil {
+		return nil, err
+	}
+
+	return &batch, nil
+}
+
+func (c *client) GetBatch(ctx context.Context, batchId string) (*domain.Batch, error) {
+	batch := domain.Batch{}
+
+	if _, err := c.do(ctx, http.MethodGet, "/batches/"+url.PathEscape(batchId), nil, &batch); err != nil {
+		return nil, err
+	}
+
+	return &batch, nil
+}
@@ -0,0 +1,282 @@
+// Package client is the Go client of the hasty api server. It uses the
+// server's own domain types, so requests and responses cannot drift from
+// what the server sends.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+)
+
+const (
+	DefaultMaxRetries = 3
+	DefaultRetryWait  = 500 * time.Millisecond
+	MaxRetryWait      = 30 * time.Second
+)
+
+// Client is safe for concurrent use. Requests that fail with a 5xx or
+// never get a response are retried with backoff, honouring Retry-After;
+// every other error is returned right away, as an *Error when the server
+// answered.
+type Client interface {
+	// CreateJob returns false when the job type's rerun policy handed back
+	// an existing job instead of creating one.
+	CreateJob(ctx context.Context, jobRequest domain.JobRequest) (*domain.ResponseJob, bool, error)
+	GetJob(ctx context.Context, jobId string) (*domain.Job, error)
+	ListJobs(ctx context.Context, filter domain.JobFilter) ([]*domain.Job, error)
+	CancelJob(ctx context.Context, jobId string) (*domain.Job, error)
+	// GetJobResult streams the result document of a finished job, which
+	// the caller must close. It is not retried. A failed job returns an
+	// *Error with the code "job_failed", and the executor's error in its
+	// Problem.
+	GetJobResult(ctx context.Context, jobId string) (io.ReadCloser, error)
+	// Wait blocks until the job is finished, failed, cancelled or skipped
+	// and returns it. It follows the job's event stream and reconnects when
+	// the stream drops, until ctx is done.
+	Wait(ctx context.Context, jobId string) (*domain.Job, error)
+	// Watch is Wait calling onChange with every state of the job it sees.
+	Watch(ctx context.Context, jobId string, onChange func(job *domain.Job)) (*domain.Job, error)
+
+	CreateBatch(ctx context.Context, batchRequest domain.BatchRequest) (*domain.Batch, error)
+	GetBatch(ctx context.Context, batchId string) (*domain.Batch, error)
+
+	CreateSchedule(ctx context.Context, scheduleRequest domain.ScheduleRequest) (*domain.Schedule, error)
+	GetSchedule(ctx context.Context, scheduleId string) (*domain.Schedule, error)
+	ListSchedules(ctx context.Context) ([]*domain.Schedule, error)
+	PauseSchedule(ctx context.Context, scheduleId string) (*domain.Schedule, error)
+	ResumeSchedule(ctx context.Context, scheduleId string) (*domain.Schedule, error)
+	DeleteSchedule(ctx context.Context, scheduleId string) error
+
+	// The quarantine holds the messages the api server could not decode.
+	ListQuarantine(ctx context.Context) ([]*domain.QuarantinedMessage, error)
+	GetQuarantinedMessage(ctx context.Context, messageId string) (*domain.QuarantinedMessage, error)
+	DeleteQuarantinedMessage(ctx context.Context, messageId string) error
+
+	IssueApiKey(ctx context.Context, apiKeyRequest domain.ApiKeyRequest) (*domain.IssuedApiKey, error)
+	ListApiKeys(ctx context.Context) ([]*domain.ApiKey, error)
+	RotateApiKey(ctx context.Context, keyId string) (*domain.IssuedApiKey, error)
+	RevokeApiKey(ctx context.Context, keyId string) (*domain.ApiKey, error)
+}
+
+type client struct {
+	baseUrl    string
+	apiKey     string
+	httpClient *http.Client
+	maxRetries int
+	retryWait  time.Duration
+}
+
+type Option func(*client)
+
+// WithHttpClient replaces http.DefaultClient. Wait keeps a request open
+// while the job runs, so the client should not set a Timeout; use contexts
+// instead.
+func WithHttpClient(httpClient *http.Client) Option {
+	return func(c *client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries sets how often a request is retried and the first wait,
+// which doubles up to MaxRetryWait. maxRetries 0 disables retries.
+func WithRetries(maxRetries int, retryWait time.Duration) Option {
+	return func(c *client) {
+		c.maxRetries = maxRetries
+		c.retryWait = retryWait
+	}
+}
+
+// NewClient talks to the api server at baseUrl (e.g. "http://localhost")
+// and authenticates every request with apiKey.
+func NewClient(baseUrl, apiKey string, opts ...Option) Client {
+	c := &client{
+		baseUrl:    strings.TrimSuffix(baseUrl, "/"),
+		apiKey:     apiKey,
+		httpClient: http.DefaultClient,
+		maxRetries: DefaultMaxRetries,
+		retryWait:  DefaultRetryWait,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func (c *client) CreateJob(ctx context.Context, jobRequest domain.JobRequest) (*domain.ResponseJob, bool, error) {
+	responseJob := domain.ResponseJob{}
+
+	status, err := c.do(ctx, http.MethodPost, "/", jobRequest, &responseJob)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &responseJob, status == http.StatusCreated, nil
+}
+
+func (c *client) GetJob(ctx context.Context, jobId string) (*domain.Job, error) {
+	job := domain.Job{}
+
+	if _, err := c.do(ctx, http.MethodGet, "/"+url.PathEscape(jobId), nil, &job); err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (c *client) ListJobs(ctx context.Context, filter domain.JobFilter) ([]*domain.Job, error) {
+	query := url.Values{}
+	if filter.ObjectId != "" {
+		query.Set("object_id", filter.ObjectId)
+	}
+	if filter.Status != "" {
+		query.Set("status", filter.Status)
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.FormatInt(filter.Limit, 10))
+	}
+
+	path := "/"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	jobs := []*domain.Job{}
+	if _, err := c.do(ctx, http.MethodGet, path, nil, &jobs); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (c *client) CancelJob(ctx context.Context, jobId string) (*domain.Job, error) {
+	job := domain.Job{}
+
+	if _, err := c.do(ctx, http.MethodPost, "/"+url.PathEscape(jobId)+"/cancel", nil, &job); err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (c *client) GetJobResult(ctx context.Context, jobId string) (io.ReadCloser, error) {
+	res, err := c.send(ctx, http.MethodGet, "/"+url.PathEscape(jobId)+"/result", nil, "application/json")
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, decodeResponse(res, nil)
+	}
+
+	return res.Body, nil
+}
+
+// do sends the request, retrying as described on Client, and decodes the
+// "message" of a successful response into out, unless out is nil.
+func (c *client) do(ctx context.Context, method, path string, body interface{}, out interface{}) (int, error) {
+	var payload []byte
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("could not marshal request: %v", err.Error())
+		}
+		payload = data
+	}
+
+	wait := c.retryWait
+	for attempt := 0; ; attempt++ {
+		res, err := c.send(ctx, method, path, payload, "application/json")
+		if err == nil {
+			err = decodeResponse(res, out)
+			if err == nil {
+				return res.StatusCode, nil
+			}
+			if res.StatusCode < http.StatusInternalServerError {
+				return res.StatusCode, err
+			}
+		}
+
+		if attempt >= c.maxRetries || ctx.Err() != nil {
+			return 0, err
+		}
+
+		if retryAfter := retryAfter(err); retryAfter > 0 {
+			wait = retryAfter
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return 0, err
+		}
+		if wait *= 2; wait > MaxRetryWait {
+			wait = MaxRetryWait
+		}
+	}
+}
+
+func (c *client) send(ctx context.Context, method, path string, payload []byte, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseUrl+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-API-Key", c.apiKey)
+
+	return c.httpClient.Do(req)
+}
+
+func decodeResponse(res *http.Response, out interface{}) error {
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return newError(res, data)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	envelope := struct {
+		Message json.RawMessage `json:"message"`
+	}{}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("could not decode response: %v", err.Error())
+	}
+
+	if err := json.Unmarshal(envelope.Message, out); err != nil {
+		return fmt.Errorf("could not decode response message: %v", err.Error())
+	}
+
+	return nil
+}
+
+func sleep(ctx context.Context, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
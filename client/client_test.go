@@ -0,0 +1,194 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/interfaces"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+)
+
+type adminKeyService struct {
+	app.ApiKeyService
+}
+
+func (adminKeyService) Authenticate(key string) (*domain.ApiKey, error) {
+	if key != "test-key" {
+		return nil, app.NewError(app.ErrUnauthorized, "invalid api key")
+	}
+	return &domain.ApiKey{KeyId: "key-1", TenantId: domain.DefaultTenantId, Scopes: []string{domain.ScopeAdmin}}, nil
+}
+
+type unlimitedTenantService struct {
+	app.TenantService
+}
+
+func (unlimitedTenantService) CheckQuota(tenantId string, newJobs int64) error {
+	return nil
+}
+
+type discardPublisher struct{}
+
+func (discardPublisher) PublishData(jobEvent *contracts.JobEvent) error {
+	return nil
+}
+
+// newApiServer serves the api server's real router, backed by memory.
+func newApiServer(t *testing.T) *httptest.Server {
+	repo := repository.NewMemoryRepository()
+	service := app.NewApiService(repo, unlimitedTenantService{}, app.RerunPolicies{
+		domain.DefaultJobType: app.NewFixedCooldownPolicy(app.DefaultCooldown),
+	})
+
+	breaker := repository.NewCircuitBreaker(3, 10*time.Second)
+	router := interfaces.NewRouter(interfaces.Handlers{
+		Api:        interfaces.NewApiHandler(service, discardPublisher{}, discardPublisher{}),
+		Result:     interfaces.NewResultHandler(app.NewResultService(repository.NewMemoryResultRepository(), repo)),
+		Schedule:   interfaces.NewScheduleHandler(nil),
+		Workflow:   interfaces.NewWorkflowHandler(nil),
+		Batch:      interfaces.NewBatchHandler(nil),
+		ApiKey:     interfaces.NewApiKeyHandler(nil),
+		Tenant:     interfaces.NewTenantHandler(nil),
+		Worker:     interfaces.NewWorkerHandler(nil),
+		Quarantine: interfaces.NewQuarantineHandler(nil),
+		Retention:  interfaces.NewRetentionHandler(nil),
+		Health:     interfaces.NewHealthHandler(nil, breaker),
+		Docs:       interfaces.NewDocsHandler(),
+	}, adminKeyService{}, breaker)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestClientJobLifecycle(t *testing.T) {
+	server := newApiServer(t)
+	c := NewClient(server.URL, "test-key", WithRetries(0, 0))
+	ctx := context.Background()
+
+	created, isNew, err := c.CreateJob(ctx, domain.JobRequest{ObjectId: "object-1", Type: "thumbnail"})
+	if err != nil || !isNew {
+		t.Fatalf("expected a new job, got %v, %v", isNew, err)
+	}
+
+	_, _, err = c.CreateJob(ctx, domain.JobRequest{ObjectId: "object-1", Type: "thumbnail"})
+	if !IsCode(err, app.ErrJobInProgress.Error()) {
+		t.Fatalf("expected the running job to reject the rerun, got %v", err)
+	}
+	if err.(*Error).RetryAfter() != 0 {
+		t.Errorf("expected no Retry-After, got %v", err.(*Error).RetryAfter())
+	}
+
+	job, err := c.GetJob(ctx, created.JobId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ObjectId != "object-1" || job.Type != "thumbnail" || job.Status != domain.JobProcessing {
+		t.Errorf("unexpected job %+v", job)
+	}
+
+	if _, err := c.GetJob(ctx, "missing"); !IsCode(err, app.ErrJobNotFound.Error()) {
+		t.Errorf("expected job_not_found, got %v", err)
+	}
+
+	if _, err := c.GetJobResult(ctx, created.JobId); !IsCode(err, app.ErrResultNotFound.Error()) {
+		t.Errorf("expected result_not_found for a processing job, got %v", err)
+	}
+
+	jobs, err := c.ListJobs(ctx, domain.JobFilter{Status: domain.JobProcessing, Limit: 10})
+	if err != nil || len(jobs) != 1 || jobs[0].JobId != created.JobId {
+		t.Fatalf("expected the created job, got %v, %v", jobs, err)
+	}
+
+	waited := make(chan *domain.Job, 1)
+	go func() {
+		job, err := c.Wait(ctx, created.JobId)
+		if err != nil {
+			t.Error(err)
+		}
+		waited <- job
+	}()
+
+	cancelled, err := c.CancelJob(ctx, created.JobId)
+	if err != nil || cancelled.Status != domain.JobCancelled {
+		t.Fatalf("expected a cancelled job, got %+v, %v", cancelled, err)
+	}
+
+	select {
+	case job := <-waited:
+		if job == nil || job.Status != domain.JobCancelled {
+			t.Errorf("expected Wait to return the cancelled job, got %+v", job)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait did not return after the job was cancelled")
+	}
+
+	if _, err := c.CancelJob(ctx, created.JobId); !IsCode(err, app.ErrJobFinished.Error()) {
+		t.Errorf("expected job_finished, got %v", err)
+	}
+
+	_, _, err = c.CreateJob(ctx, domain.JobRequest{ObjectId: "object-1", Type: "thumbnail"})
+	if !IsCode(err, app.ErrCooldownActive.Error()) {
+		t.Fatalf("expected a cooldown, got %v", err)
+	}
+	if err.(*Error).RetryAfter() <= 0 {
+		t.Errorf("expected a Retry-After, got %v", err.(*Error).RetryAfter())
+	}
+}
+
+func TestClientRetriesServerErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "test-key" {
+			t.Errorf("expected the api key, got %q", r.Header.Get("X-API-Key"))
+		}
+
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status": 503, "code": "database_unavailable", "detail": "the database is unavailable"}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"message": {"job_id": "job-1", "status": "finished"}}`))
+	}))
+	defer server.Close()
+
+	job, err := NewClient(server.URL, "test-key", WithRetries(3, time.Millisecond)).GetJob(context.Background(), "job-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.JobId != "job-1" || calls != 3 {
+		t.Errorf("expected job-1 after 3 calls, got %v after %v", job.JobId, calls)
+	}
+}
+
+func TestClientDoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"status": 403, "code": "forbidden", "detail": "api key is missing the jobs:read scope"}`))
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL, "test-key", WithRetries(3, time.Millisecond)).GetJob(context.Background(), "job-1")
+
+	apiErr, ok := err.(*Error)
+	if !ok || apiErr.StatusCode != http.StatusForbidden || apiErr.Code() != "forbidden" || apiErr.Message != "api key is missing the jobs:read scope" {
+		t.Fatalf("expected a 403 error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %v", calls)
+	}
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+)
+
+// Error is a response the server answered with a 4xx or 5xx. The api
+// answers with a Problem, whose Code is stable. Responses that are not one,
+// e.g. from a proxy in front of it, only set Message to their body.
+type Error struct {
+	StatusCode int
+	Header     http.Header
+	Problem    *domain.Problem
+	Message    string
+}
+
+func newError(res *http.Response, data []byte) *Error {
+	apiErr := &Error{StatusCode: res.StatusCode, Header: res.Header}
+
+	if strings.HasPrefix(res.Header.Get("Content-Type"), "application/problem+json") {
+		problem := domain.Problem{}
+		if err := json.Unmarshal(data, &problem); err == nil {
+			apiErr.Problem = &problem
+			apiErr.Message = problem.Detail
+			return apiErr
+		}
+	}
+
+	apiErr.Message = strings.TrimSpace(string(data))
+
+	return apiErr
+}
+
+func (e *Error) Error() string {
+	if e.Problem != nil {
+		return fmt.Sprintf("%v %v: %v", e.StatusCode, e.Problem.Code, e.Message)
+	}
+	return fmt.Sprintf("%v: %v", e.StatusCode, e.Message)
+}
+
+// Code is the problem's error code, or "" for responses without one.
+func (e *Error) Code() string {
+	if e.Problem == nil {
+		return ""
+	}
+	return e.Problem.Code
+}
+
+// RetryAfter is how long the server asked to wait, or 0.
+func (e *Error) RetryAfter() time.Duration {
+	seconds, err := strconv.Atoi(e.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// IsCode reports whether err is an *Error with the given problem code, e.g.
+// "cooldown_active".
+func IsCode(err error, code string) bool {
+	apiErr := &Error{}
+	return errors.As(err, &apiErr) && apiErr.Code() == code
+}
+
+func retryAfter(err error) time.Duration {
+	apiErr := &Error{}
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter()
+	}
+	return 0
+}
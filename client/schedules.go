@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+)
+
+func (c *client) CreateSchedule(ctx context.Context, scheduleRequest domain.ScheduleRequest) (*domain.Schedule, error) {
+	return c.schedule(ctx, http.MethodPost, "/schedules", scheduleRequest)
+}
+
+func (c *client) GetSchedule(ctx context.Context, scheduleId string) (*domain.Schedule, error) {
+	return c.schedule(ctx, http.MethodGet, "/schedules/"+url.PathEscape(scheduleId), nil)
+}
+
+func (c *client) ListSchedules(ctx context.Context) ([]*domain.Schedule, error) {
+	schedules := []*domain.Schedule{}
+
+	if _, err := c.do(ctx, http.MethodGet, "/schedules", nil, &schedules); err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+func (c *client) PauseSchedule(ctx context.Context, scheduleId string) (*domain.Schedule, error) {
+	return c.schedule(ctx, http.MethodPost, "/schedules/"+url.PathEscape(scheduleId)+"/pause", nil)
+}
+
+func (c *client) ResumeSchedule(ctx context.Context, scheduleId string) (*domain.Schedule, error) {
+	return c.schedule(ctx, http.MethodPost, "/schedules/"+url.PathEscape(scheduleId)+"/resume", nil)
+}
+
+func (c *client) DeleteSchedule(ctx context.Context, scheduleId string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/schedules/"+url.PathEscape(scheduleId), nil, nil)
+	return err
+}
+
+func (c *client) schedule(ctx context.Context, method, path string, body interface{}) (*domain.Schedule, error) {
+	schedule := domain.Schedule{}
+
+	if _, err := c.do(ctx, method, path, body, &schedule); err != nil {
+		return nil, err
+	}
+
+	return &schedule, nil
+}
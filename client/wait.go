@@ -0,0 +1,94 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+)
+
+func (c *client) Wait(ctx context.Context, jobId string) (*domain.Job, error) {
+	return c.Watch(ctx, jobId, nil)
+}
+
+func (c *client) Watch(ctx context.Context, jobId string, onChange func(job *domain.Job)) (*domain.Job, error) {
+	wait := c.retryWait
+	for {
+		job, err := c.watch(ctx, jobId, onChange)
+		if job != nil && job.IsTerminal() {
+			return job, nil
+		}
+
+		apiErr := &Error{}
+		if errors.As(err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError {
+			return nil, err
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		// A stream that delivered the job was healthy, start over
+		if job != nil {
+			wait = c.retryWait
+		}
+		if retryAfter := retryAfter(err); retryAfter > 0 {
+			wait = retryAfter
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+		if wait *= 2; wait > MaxRetryWait {
+			wait = MaxRetryWait
+		}
+	}
+}
+
+// watch reads the job's event stream until it ends and returns the last
+// job it carried.
+func (c *client) watch(ctx context.Context, jobId string, onChange func(job *domain.Job)) (*domain.Job, error) {
+	res, err := c.send(ctx, http.MethodGet, "/"+url.PathEscape(jobId)+"/events", nil, "text/event-stream")
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, decodeResponse(res, nil)
+	}
+	defer res.Body.Close()
+
+	var job *domain.Job
+	event, data := "", ""
+
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case line == "":
+			if event == "job" {
+				received := domain.Job{}
+				if err := json.Unmarshal([]byte(data), &received); err != nil {
+					return job, fmt.Errorf("could not decode job event: %v", err.Error())
+				}
+				job = &received
+				if onChange != nil {
+					onChange(job)
+				}
+			}
+			event, data = "", ""
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data += strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+
+	return job, scanner.Err()
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/natsconn"
+	"github.com/bogdan-copocean/hasty-server/retention"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	apilisteners "github.com/bogdan-copocean/hasty-server/services/api-server/events/listeners"
+	apipublishers "github.com/bogdan-copocean/hasty-server/services/api-server/events/publishers"
+	apiinterfaces "github.com/bogdan-copocean/hasty-server/services/api-server/interfaces"
+	apirepository "github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/scheduler"
+	joblisteners "github.com/bogdan-copocean/hasty-server/services/job-server/events/listeners"
+	jobpublishers "github.com/bogdan-copocean/hasty-server/services/job-server/events/publishers"
+	jobinterfaces "github.com/bogdan-copocean/hasty-server/services/job-server/interfaces"
+	jobrepository "github.com/bogdan-copocean/hasty-server/services/job-server/repository"
+	"github.com/bogdan-copocean/hasty-server/services/job-server/worker"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	stand "github.com/nats-io/nats-streaming-server/server"
+	"github.com/nats-io/nats-streaming-server/stores"
+	"google.golang.org/grpc"
+)
+
+// defaultAdminKey is the admin api key of a dev system started without one.
+const defaultAdminKey = "hasty-dev-admin-key"
+
+// devClusterId is the cluster id both services connect to.
+const devClusterId = "test-cluster"
+
+const (
+	devApiClientId = "dev-api-server"
+	devJobClientId = "dev-job-server"
+)
+
+type devConfig struct {
+	ApiPort  int
+	GrpcPort int
+	JobPort  int
+	NatsPort int
+	PoolSize int
+	AdminKey string
+}
+
+// runDev serves until ctx is done. Everything, events included, is kept in
+// memory and lost when it returns.
+func runDev(ctx context.Context, config devConfig) error {
+	natsServer, err := runNatsStreaming(config.NatsPort)
+	if err != nil {
+		return fmt.Errorf("could not start nats streaming: %v", err)
+	}
+	defer natsServer.Shutdown()
+
+	natsUrl := fmt.Sprintf("nats://127.0.0.1:%v", config.NatsPort)
+
+	apiServer, grpcServer, err := newDevApiServer(natsUrl, config)
+	if err != nil {
+		return err
+	}
+	jobServer := newDevJobServer(natsUrl, config)
+
+	grpcListener, err := net.Listen("tcp", fmt.Sprintf(":%v", config.GrpcPort))
+	if err != nil {
+		return fmt.Errorf("could not listen for grpc: %v", err)
+	}
+
+	errs := make(chan error, 3)
+	go func() { errs <- grpcServer.Serve(grpcListener) }()
+	go func() { errs <- apiServer.ListenAndServe() }()
+	go func() { errs <- jobServer.ListenAndServe() }()
+
+	log.Printf("api server on :%v, grpc on :%v, job server on :%v, admin api key %v\n", config.ApiPort, config.GrpcPort, config.JobPort, config.AdminKey)
+
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	apiServer.Shutdown(shutdownCtx)
+	jobServer.Shutdown(shutdownCtx)
+	grpcServer.Stop()
+
+	return err
+}
+
+// runNatsStreaming starts a NATS Streaming server with a memory store,
+// listening on localhost only.
+func runNatsStreaming(port int) (*stand.StanServer, error) {
+	opts := stand.GetDefaultOptions()
+	opts.ID = devClusterId
+	opts.StoreType = stores.TypeMemory
+
+	natsOpts := stand.DefaultNatsServerOptions
+	natsOpts.Host = "127.0.0.1"
+	natsOpts.Port = port
+
+	return stand.RunServerWithOpts(opts, &natsOpts)
+}
+
+// newDevApiServer wires the api server like services/api-server, with the
+// memory repositories, no limits and no retention policy.
+func newDevApiServer(natsUrl string, config devConfig) (*http.Server, *grpc.Server, error) {
+	breaker := apirepository.NewCircuitBreaker(3, 10*time.Second)
+
+	repo := apirepository.NewMemoryRepository()
+	scheduleRepo := apirepository.NewMemoryScheduleRepository()
+	workflowRepo := apirepository.NewMemoryWorkflowRepository()
+	batchRepo := apirepository.NewMemoryBatchRepository()
+	apiKeyRepo := apirepository.NewMemoryApiKeyRepository()
+	tenantRepo := apirepository.NewMemoryTenantRepository()
+	workerRepo := apirepository.NewMemoryWorkerRepository()
+	quarantineRepo := apirepository.NewMemoryQuarantineRepository()
+	resultRepo := apirepository.NewMemoryResultRepository()
+	retentionRepo := apirepository.NewMemoryRetentionRepository(repo, resultRepo)
+
+	tenantService := app.NewTenantService(tenantRepo, repo, domain.Tenant{})
+	service := app.NewApiService(repo, tenantService, app.RerunPolicies{})
+	scheduleService := app.NewScheduleService(scheduleRepo)
+	apiKeyService := app.NewApiKeyService(apiKeyRepo)
+	workerService := app.NewWorkerService(workerRepo)
+	quarantineService := app.NewQuarantineService(quarantineRepo)
+	resultService := app.NewResultService(resultRepo, repo)
+	retentionService := app.NewRetentionService(retentionRepo, retention.Policy{}, retention.NewNoArchiver())
+
+	if err := apiKeyService.BootstrapAdminKey(config.AdminKey); err != nil {
+		return nil, nil, fmt.Errorf("could not bootstrap admin api key: %v", err)
+	}
+
+	conn := natsconn.ConnectToNats(natsUrl, devApiClientId, 1000)
+
+	// Events are JSON in structured CloudEvents, readable when debugging
+	eventSource := contracts.CloudEventSource("api-server", devApiClientId)
+	publisher := apipublishers.NewJobEventPublisher(conn, contracts.SubjectJobCreated, eventSource, contracts.EncodingJson, contracts.CloudEventsStructured)
+	cancelledPublisher := apipublishers.NewJobEventPublisher(conn, contracts.SubjectJobCancelled, eventSource, contracts.EncodingJson, contracts.CloudEventsStructured)
+
+	workflowService := app.NewWorkflowService(repo, workflowRepo, tenantService, publisher)
+	batchService := app.NewBatchService(service, repo, batchRepo, publisher, cancelledPublisher)
+
+	apilisteners.NewJobEventListener(conn, contracts.SubjectJobFinished, "job-finished-group", "job-finished-durable-name", service, workflowService, quarantineService, resultService).Listen()
+	apilisteners.NewJobEventListener(conn, contracts.SubjectJobCancelled, "job-cancelled-group", "job-cancelled-durable-name", service, workflowService, quarantineService, resultService).Listen()
+	apilisteners.NewHeartbeatListener(conn, contracts.SubjectWorkerHeartbeat, "worker-heartbeat-group", workerService).Listen()
+
+	scheduler.NewScheduler(scheduleService, service, publisher).Start()
+
+	r := apiinterfaces.NewRouter(apiinterfaces.Handlers{
+		Api:        apiinterfaces.NewApiHandler(service, publisher, cancelledPublisher),
+		Result:     apiinterfaces.NewResultHandler(resultService),
+		Schedule:   apiinterfaces.NewScheduleHandler(scheduleService),
+		Workflow:   apiinterfaces.NewWorkflowHandler(workflowService),
+		Batch:      apiinterfaces.NewBatchHandler(batchService),
+		ApiKey:     apiinterfaces.NewApiKeyHandler(apiKeyService),
+		Tenant:     apiinterfaces.NewTenantHandler(tenantService),
+		Worker:     apiinterfaces.NewWorkerHandler(workerService),
+		Quarantine: apiinterfaces.NewQuarantineHandler(quarantineService),
+		Retention:  apiinterfaces.NewRetentionHandler(retentionService),
+		Health:     apiinterfaces.NewHealthHandler(conn, breaker),
+		Docs:       apiinterfaces.NewDocsHandler(),
+	}, apiKeyService, breaker)
+
+	grpcServer := apiinterfaces.NewGrpcServer(apiinterfaces.NewJobsServer(service, publisher, cancelledPublisher), apiKeyService, breaker)
+
+	return &http.Server{Addr: fmt.Sprintf(":%v", config.ApiPort), Handler: r}, grpcServer, nil
+}
+
+// newDevJobServer wires a job server like services/job-server, with the
+// memory repositories. Its job events are not purged, so it has no
+// /retention/purge.
+func newDevJobServer(natsUrl string, config devConfig) *http.Server {
+	r := chi.NewRouter()
+	r.Use(middleware.Logger)
+
+	repo := jobrepository.NewMemoryRepository()
+	quarantineRepo := jobrepository.NewMemoryQuarantineRepository()
+
+	conn := natsconn.ConnectToNats(natsUrl, devJobClientId, 0)
+
+	eventSource := contracts.CloudEventSource("job-server", devJobClientId)
+	finishedPublisher := jobpublishers.NewJobEventPublisher(conn, contracts.SubjectJobFinished, eventSource, contracts.EncodingJson, contracts.CloudEventsStructured)
+	cancelledPublisher := jobpublishers.NewJobEventPublisher(conn, contracts.SubjectJobCancelled, eventSource, contracts.EncodingJson, contracts.CloudEventsStructured)
+
+	pool := worker.NewPool(config.PoolSize)
+
+	executors := worker.Executors{
+		worker.AnyJobType: worker.NewSleepExecutor(joblisteners.MinSleepTime, joblisteners.MaxSleepTime),
+	}
+	joblisteners.NewJobCreatedListener(conn, devJobClientId, executors, contracts.SubjectJobCreated, "job-created-group", finishedPublisher, cancelledPublisher, repo, quarantineRepo, jobrepository.NewInlineResultRepository(), pool).ListenAndPublish()
+
+	heartbeatPublisher := jobpublishers.NewHeartbeatPublisher(conn, contracts.SubjectWorkerHeartbeat, eventSource, contracts.CloudEventsStructured)
+	worker.NewHeartbeater(devJobClientId, pool, []string{worker.AnyJobType}, heartbeatPublisher).Start()
+
+	r.Get("/status", jobinterfaces.NewStatusHandler(devJobClientId, pool).GetHandler)
+	r.Get("/healthz", jobinterfaces.NewHealthHandler(conn).GetHandler)
+
+	quarantineHandler := jobinterfaces.NewQuarantineHandler(quarantineRepo)
+	r.Get("/quarantine", quarantineHandler.GetAllHandler)
+	r.Get("/quarantine/{messageId}", quarantineHandler.GetHandler)
+	r.Delete("/quarantine/{messageId}", quarantineHandler.DeleteHandler)
+
+	return &http.Server{Addr: fmt.Sprintf(":%v", config.JobPort), Handler: r}
+}
@@ -0,0 +1,70 @@
+// Command hasty-server runs the whole system in one process. "hasty-server
+// dev" starts the api server and a job server against an embedded NATS
+// Streaming server and in-memory stores, for local development.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stderr))
+}
+
+// run returns the exit code: 1 when the system failed, 2 for bad usage.
+func run(ctx context.Context, args []string, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "dev" {
+		usage(stderr)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("hasty-server dev", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		usage(stderr)
+		fmt.Fprintln(stderr)
+		flags.PrintDefaults()
+	}
+
+	config := devConfig{}
+	flags.IntVar(&config.ApiPort, "api-port", 9090, "http port of the api server")
+	flags.IntVar(&config.GrpcPort, "grpc-port", 9092, "grpc port of the api server")
+	flags.IntVar(&config.JobPort, "job-port", 9091, "http port of the job server")
+	flags.IntVar(&config.NatsPort, "nats-port", 4222, "port of the embedded nats streaming server")
+	flags.IntVar(&config.PoolSize, "pool-size", 4, "jobs run at once by the job server")
+	flags.StringVar(&config.AdminKey, "admin-key", getEnv("HASTY_ADMIN_KEY", defaultAdminKey), "admin api key, or $HASTY_ADMIN_KEY")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+
+	if err := runDev(ctx, config); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: hasty-server dev [-api-port port] [-grpc-port port] [-job-port port] [-nats-port port] [-pool-size n] [-admin-key key]")
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
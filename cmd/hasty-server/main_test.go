@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRunUsage(t *testing.T) {
+	for _, args := range [][]string{{}, {"serve"}, {"dev", "-pool-size"}, {"dev", "extra"}} {
+		stderr := bytes.Buffer{}
+		if code := run(context.Background(), args, &stderr); code != 2 {
+			t.Errorf("%v: expected exit code 2, got %v", args, code)
+		}
+		if !bytes.Contains(stderr.Bytes(), []byte("usage: hasty-server dev")) {
+			t.Errorf("%v: expected the usage, got %q", args, stderr.String())
+		}
+	}
+}
+
+func freePort(t *testing.T) int {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+
+	return lis.Addr().(*net.TCPAddr).Port
+}
+
+func waitForStatus(t *testing.T, url, apiKey string, expected int) {
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("X-API-Key", apiKey)
+
+		res, err := http.DefaultClient.Do(req)
+		if err == nil {
+			res.Body.Close()
+			if res.StatusCode == expected {
+				return
+			}
+			err = fmt.Errorf("status %v", res.StatusCode)
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("%v: expected status %v: %v", url, expected, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestRunDev(t *testing.T) {
+	config := devConfig{
+		ApiPort:  freePort(t),
+		GrpcPort: freePort(t),
+		JobPort:  freePort(t),
+		NatsPort: freePort(t),
+		PoolSize: 1,
+		AdminKey: defaultAdminKey,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- runDev(ctx, config) }()
+
+	apiUrl := fmt.Sprintf("http://127.0.0.1:%v", config.ApiPort)
+	waitForStatus(t, apiUrl+"/healthz", "", http.StatusOK)
+	waitForStatus(t, fmt.Sprintf("http://127.0.0.1:%v/status", config.JobPort), "", http.StatusOK)
+
+	// The admin key is bootstrapped into the memory store
+	waitForStatus(t, apiUrl+"/admin/keys", config.AdminKey, http.StatusOK)
+	waitForStatus(t, apiUrl+"/admin/keys", "not-the-admin-key", http.StatusUnauthorized)
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected a clean shutdown, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("runDev did not return after ctx was done")
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+)
+
+// Dead letters are the messages the api server quarantined because they
+// could not be decoded.
+var deadLetterCommands = []command{
+	{"list", "", listDeadLetters},
+	{"get", "<message_id>", getDeadLetter},
+	{"delete", "<message_id>", deleteDeadLetter},
+}
+
+var keyCommands = []command{
+	{"issue", "-tenant tenant_id -scope scope... [-name name]", issueKey},
+	{"list", "", listKeys},
+	{"rotate", "<key_id>", rotateKey},
+	{"revoke", "<key_id>", revokeKey},
+}
+
+var deadLetterHeader = []string{"MESSAGE ID", "SUBJECT", "SEQUENCE", "QUARANTINED", "ERROR"}
+
+func deadLetterRow(message *domain.QuarantinedMessage) []string {
+	return []string{message.MessageId, message.Subject, strconv.FormatUint(message.Sequence, 10), formatTime(message.Timestamp), message.Error}
+}
+
+func listDeadLetters(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	messages, err := cli.client.ListQuarantine(ctx)
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{}
+	for _, message := range messages {
+		rows = append(rows, deadLetterRow(message))
+	}
+
+	return cli.out.print(messages, deadLetterHeader, rows)
+}
+
+// getDeadLetter also prints the raw message in table output, which is what
+// an operator needs to work out why it could not be decoded.
+func getDeadLetter(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	message, err := cli.client.GetQuarantinedMessage(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	header := append(deadLetterHeader, "DATA")
+	row := append(deadLetterRow(message), strconv.Quote(string(message.Data)))
+
+	return cli.out.print(message, header, [][]string{row})
+}
+
+func deleteDeadLetter(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	return cli.client.DeleteQuarantinedMessage(ctx, args[0])
+}
+
+var keyHeader = []string{"KEY ID", "TENANT", "NAME", "PREFIX", "SCOPES", "REVOKED", "CREATED"}
+
+func keyRow(apiKey *domain.ApiKey) []string {
+	return []string{apiKey.KeyId, apiKey.TenantId, orDash(apiKey.Name), apiKey.Prefix, strings.Join(apiKey.Scopes, ","),
+		strconv.FormatBool(apiKey.Revoked), formatTime(apiKey.Timestamp)}
+}
+
+// printIssuedKey adds the plain key, which the server only returns once.
+func printIssuedKey(cli *cli, issued *domain.IssuedApiKey) error {
+	header := append(keyHeader, "KEY")
+	row := append(keyRow(issued.ApiKey), issued.Key)
+
+	return cli.out.print(issued, header, [][]string{row})
+}
+
+func issueKey(ctx context.Context, cli *cli, args []string) error {
+	flags := newFlags("issue")
+	apiKeyRequest := domain.ApiKeyRequest{}
+	flags.StringVar(&apiKeyRequest.TenantId, "tenant", "", "tenant the key belongs to")
+	flags.StringVar(&apiKeyRequest.Name, "name", "", "name of the key")
+	scopes := listFlag{}
+	flags.Var(&scopes, "scope", "jobs:read, jobs:write or admin, repeatable")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if apiKeyRequest.TenantId == "" || len(scopes) == 0 || flags.NArg() > 0 {
+		return errUsage
+	}
+	apiKeyRequest.Scopes = scopes
+
+	issued, err := cli.client.IssueApiKey(ctx, apiKeyRequest)
+	if err != nil {
+		return err
+	}
+
+	return printIssuedKey(cli, issued)
+}
+
+func listKeys(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	apiKeys, err := cli.client.ListApiKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{}
+	for _, apiKey := range apiKeys {
+		rows = append(rows, keyRow(apiKey))
+	}
+
+	return cli.out.print(apiKeys, keyHeader, rows)
+}
+
+func rotateKey(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	issued, err := cli.client.RotateApiKey(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	return printIssuedKey(cli, issued)
+}
+
+func revokeKey(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	apiKey, err := cli.client.RevokeApiKey(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	return cli.out.print(apiKey, keyHeader, [][]string{keyRow(apiKey)})
+}
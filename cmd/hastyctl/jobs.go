@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+)
+
+var jobCommands = []command{
+	{"submit", "[-type type] [-param key=value]... [-file path] [object_id...]", submitJobs},
+	{"get", "<job_id>", getJob},
+	{"list", "[-status status] [-object-id object_id] [-limit n]", listJobs},
+	{"cancel", "<job_id>...", cancelJobs},
+	{"watch", "<job_id>", watchJob},
+	{"result", "<job_id>", getJobResult},
+}
+
+var jobHeader = []string{"JOB ID", "OBJECT ID", "TYPE", "STATUS", "CREATED", "UPDATED", "WORKER"}
+
+func jobRow(job *domain.Job) []string {
+	return []string{job.JobId, job.ObjectId, job.Type, job.Status, formatTime(job.CreatedAt), formatTime(job.Timestamp), orDash(job.WorkerId)}
+}
+
+// submitJobs creates a single job for a single object id, and batches of
+// up to domain.MaxBatchSize otherwise. -file reads one object id per line,
+// skipping blank lines and lines starting with #.
+func submitJobs(ctx context.Context, cli *cli, args []string) error {
+	flags := newFlags("submit")
+	jobType := flags.String("type", "", "job type")
+	params := paramsFlag{}
+	flags.Var(params, "param", "job parameter as key=value, repeatable")
+	file := flags.String("file", "", "file with one object id per line, - for stdin")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	objectIds := flags.Args()
+	if *file != "" {
+		fromFile, err := readObjectIds(*file)
+		if err != nil {
+			return err
+		}
+		objectIds = append(objectIds, fromFile...)
+	}
+
+	if len(objectIds) == 0 {
+		return errUsage
+	}
+
+	if len(objectIds) == 1 && *file == "" {
+		created, isNew, err := cli.client.CreateJob(ctx, domain.JobRequest{ObjectId: objectIds[0], Type: *jobType, Params: params})
+		if err != nil {
+			return err
+		}
+
+		return cli.out.print(map[string]interface{}{"job_id": created.JobId, "created": isNew},
+			[]string{"JOB ID", "CREATED"}, [][]string{{created.JobId, strconv.FormatBool(isNew)}})
+	}
+
+	batches := []*domain.Batch{}
+	rows := [][]string{}
+	for start := 0; start < len(objectIds); start += domain.MaxBatchSize {
+		end := start + domain.MaxBatchSize
+		if end > len(objectIds) {
+			end = len(objectIds)
+		}
+
+		batch, err := cli.client.CreateBatch(ctx, domain.BatchRequest{ObjectIds: objectIds[start:end], Type: *jobType, Params: params})
+		if err != nil {
+			return err
+		}
+
+		batches = append(batches, batch)
+		rows = append(rows, []string{batch.BatchId, strconv.Itoa(batch.Total), strconv.Itoa(batch.Rejected)})
+		for _, itemErr := range batch.Errors {
+			fmt.Fprintf(cli.stderr, "%v: %v: %v\n", itemErr.ObjectId, itemErr.Code, itemErr.Message)
+		}
+	}
+
+	return cli.out.print(batches, []string{"BATCH ID", "SUBMITTED", "REJECTED"}, rows)
+}
+
+func readObjectIds(path string) ([]string, error) {
+	file := os.Stdin
+	if path != "-" {
+		opened, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer opened.Close()
+		file = opened
+	}
+
+	objectIds := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		objectIds = append(objectIds, line)
+	}
+
+	return objectIds, scanner.Err()
+}
+
+func getJob(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	job, err := cli.client.GetJob(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	return cli.out.print(job, jobHeader, [][]string{jobRow(job)})
+}
+
+// getJobResult copies the result document as it is, in either output
+// format.
+func getJobResult(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	result, err := cli.client.GetJobResult(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	defer result.Close()
+
+	if _, err := io.Copy(cli.out.w, result); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(cli.out.w)
+	return err
+}
+
+func listJobs(ctx context.Context, cli *cli, args []string) error {
+	flags := newFlags("list")
+	filter := domain.JobFilter{}
+	flags.StringVar(&filter.Status, "status", "", "only jobs with this status")
+	flags.StringVar(&filter.ObjectId, "object-id", "", "only jobs of this object")
+	flags.Int64Var(&filter.Limit, "limit", 0, "at most this many jobs, newest first")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	jobs, err := cli.client.ListJobs(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{}
+	for _, job := range jobs {
+		rows = append(rows, jobRow(job))
+	}
+
+	return cli.out.print(jobs, jobHeader, rows)
+}
+
+// cancelJobs cancels every job it can and fails if any of them failed.
+func cancelJobs(ctx context.Context, cli *cli, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	jobs := []*domain.Job{}
+	rows := [][]string{}
+	failed := 0
+	for _, jobId := range args {
+		job, err := cli.client.CancelJob(ctx, jobId)
+		if err != nil {
+			fmt.Fprintf(cli.stderr, "%v: %v\n", jobId, err)
+			failed++
+			continue
+		}
+
+		jobs = append(jobs, job)
+		rows = append(rows, jobRow(job))
+	}
+
+	if err := cli.out.print(jobs, jobHeader, rows); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("could not cancel %v of %v jobs", failed, len(args))
+	}
+	return nil
+}
+
+// watchJob prints every change of the job until it is terminal, and fails
+// unless it finished.
+func watchJob(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	job, err := cli.client.Watch(ctx, args[0], func(job *domain.Job) {
+		cli.out.printLine(job, []string{formatTime(job.Timestamp), job.JobId, job.Status, orDash(job.WorkerId)})
+	})
+	if err != nil {
+		return err
+	}
+
+	if job.Status != domain.JobFinished {
+		return fmt.Errorf("job %v ended %v", job.JobId, job.Status)
+	}
+	return nil
+}
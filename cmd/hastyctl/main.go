@@ -0,0 +1,200 @@
+// Command hastyctl manages jobs, schedules, dead letters and api keys of a
+// hasty api server.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/bogdan-copocean/hasty-server/client"
+)
+
+type cli struct {
+	client client.Client
+	out    *printer
+	stderr io.Writer
+}
+
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, cli *cli, args []string) error
+}
+
+type group struct {
+	name     string
+	commands []command
+}
+
+var groups = []group{
+	{"jobs", jobCommands},
+	{"schedules", scheduleCommands},
+	{"deadletters", deadLetterCommands},
+	{"keys", keyCommands},
+}
+
+// errUsage makes run print the usage of the command that failed.
+var errUsage = errors.New("usage")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run returns the exit code: 1 when the command failed, 2 for bad usage.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("hastyctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { usage(stderr) }
+
+	url := flags.String("url", getEnv("HASTY_URL", "http://localhost"), "api server url, or $HASTY_URL")
+	apiKey := flags.String("key", os.Getenv("HASTY_API_KEY"), "api key, or $HASTY_API_KEY")
+	format := flags.String("o", formatTable, "output format: table or json")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *format != formatTable && *format != formatJson {
+		fmt.Fprintf(stderr, "unknown output format %q\n", *format)
+		return 2
+	}
+
+	args = flags.Args()
+	if len(args) < 2 {
+		usage(stderr)
+		return 2
+	}
+
+	cmd, ok := findCommand(args[0], args[1])
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", strings.Join(args[:2], " "))
+		usage(stderr)
+		return 2
+	}
+
+	c := &cli{
+		client: client.NewClient(*url, *apiKey),
+		out:    &printer{w: stdout, format: *format},
+		stderr: stderr,
+	}
+
+	if err := cmd.run(ctx, c, args[2:]); err != nil {
+		if errors.Is(err, errUsage) {
+			if err != errUsage {
+				fmt.Fprintln(stderr, err)
+			}
+			fmt.Fprintln(stderr, strings.TrimSpace(fmt.Sprintf("usage: hastyctl %v %v %v", args[0], cmd.name, cmd.usage)))
+			return 2
+		}
+
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+func findCommand(groupName, name string) (command, bool) {
+	for _, g := range groups {
+		if g.name != groupName {
+			continue
+		}
+		for _, cmd := range g.commands {
+			if cmd.name == name {
+				return cmd, true
+			}
+		}
+	}
+	return command{}, false
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: hastyctl [-url url] [-key api-key] [-o table|json] <command> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, g := range groups {
+		for _, cmd := range g.commands {
+			fmt.Fprintln(w, strings.TrimRight(fmt.Sprintf("  %v %v %v", g.name, cmd.name, cmd.usage), " "))
+		}
+	}
+}
+
+// newFlags returns the flag set of a command. It prints nothing, parseFlags
+// reports what was wrong as errUsage.
+func newFlags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return flags
+}
+
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	err := flags.Parse(args)
+	if err == flag.ErrHelp {
+		return errUsage
+	}
+	if err != nil {
+		return usageError{err}
+	}
+	return nil
+}
+
+// usageError is a flag error, which is printed before the usage.
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e usageError) Is(target error) bool {
+	return target == errUsage
+}
+
+// paramsFlag collects repeated -param key=value flags.
+type paramsFlag map[string]string
+
+func (p paramsFlag) String() string {
+	pairs := []string{}
+	for key, value := range p {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (p paramsFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	p[parts[0]] = parts[1]
+	return nil
+}
+
+// listFlag collects a repeated flag.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
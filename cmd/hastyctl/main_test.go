@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/interfaces"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+)
+
+type adminKeyService struct {
+	app.ApiKeyService
+}
+
+func (adminKeyService) Authenticate(key string) (*domain.ApiKey, error) {
+	return &domain.ApiKey{KeyId: "key-1", TenantId: domain.DefaultTenantId, Scopes: []string{domain.ScopeAdmin}}, nil
+}
+
+type unlimitedTenantService struct {
+	app.TenantService
+}
+
+func (unlimitedTenantService) CheckQuota(tenantId string, newJobs int64) error {
+	return nil
+}
+
+type discardPublisher struct{}
+
+func (discardPublisher) PublishData(jobEvent *contracts.JobEvent) error {
+	return nil
+}
+
+// newApiServer serves the api server's real job endpoints, backed by memory.
+func newApiServer(t *testing.T) string {
+	repo := repository.NewMemoryRepository()
+	service := app.NewApiService(repo, unlimitedTenantService{}, app.RerunPolicies{
+		domain.DefaultJobType: app.NewFixedCooldownPolicy(app.DefaultCooldown),
+	})
+
+	breaker := repository.NewCircuitBreaker(3, 10*time.Second)
+	router := interfaces.NewRouter(interfaces.Handlers{
+		Api:        interfaces.NewApiHandler(service, discardPublisher{}, discardPublisher{}),
+		Result:     interfaces.NewResultHandler(app.NewResultService(repository.NewMemoryResultRepository(), repo)),
+		Schedule:   interfaces.NewScheduleHandler(nil),
+		Workflow:   interfaces.NewWorkflowHandler(nil),
+		Batch:      interfaces.NewBatchHandler(nil),
+		ApiKey:     interfaces.NewApiKeyHandler(nil),
+		Tenant:     interfaces.NewTenantHandler(nil),
+		Worker:     interfaces.NewWorkerHandler(nil),
+		Quarantine: interfaces.NewQuarantineHandler(nil),
+		Retention:  interfaces.NewRetentionHandler(nil),
+		Health:     interfaces.NewHealthHandler(nil, breaker),
+		Docs:       interfaces.NewDocsHandler(),
+	}, adminKeyService{}, breaker)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+func runCli(t *testing.T, url string, args ...string) (int, string, string) {
+	t.Helper()
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run(context.Background(), append([]string{"-url", url, "-key", "test-key"}, args...), stdout, stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+func TestJobCommands(t *testing.T) {
+	url := newApiServer(t)
+
+	code, stdout, stderr := runCli(t, url, "-o", "json", "jobs", "submit", "-type", "thumbnail", "-param", "size=small", "object-1")
+	if code != 0 {
+		t.Fatalf("submit failed with %v: %v", code, stderr)
+	}
+
+	submitted := struct {
+		JobId   string `json:"job_id"`
+		Created bool   `json:"created"`
+	}{}
+	if err := json.Unmarshal([]byte(stdout), &submitted); err != nil || !submitted.Created {
+		t.Fatalf("expected a created job, got %v: %v", stdout, err)
+	}
+
+	code, stdout, _ = runCli(t, url, "-o", "json", "jobs", "get", submitted.JobId)
+	job := domain.Job{}
+	if err := json.Unmarshal([]byte(stdout), &job); code != 0 || err != nil {
+		t.Fatalf("get failed with %v: %v", code, stdout)
+	}
+	if job.Type != "thumbnail" || job.Params["size"] != "small" {
+		t.Errorf("unexpected job %+v", job)
+	}
+
+	code, stdout, _ = runCli(t, url, "jobs", "list", "-status", "processing")
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if code != 0 || len(lines) != 2 || !strings.HasPrefix(lines[0], "JOB ID") || !strings.HasPrefix(lines[1], submitted.JobId) {
+		t.Fatalf("expected a header and the job, got %v: %q", code, stdout)
+	}
+
+	if code, _, stderr = runCli(t, url, "jobs", "result", submitted.JobId); code != 1 || !strings.Contains(stderr, "404 result_not_found") {
+		t.Errorf("expected the processing job to have no result, got %v: %v", code, stderr)
+	}
+
+	if code, _, stderr = runCli(t, url, "jobs", "cancel", submitted.JobId, "missing"); code != 1 || !strings.Contains(stderr, "missing: 404 job_not_found") {
+		t.Errorf("expected the missing job to fail the cancel, got %v: %v", code, stderr)
+	}
+
+	code, stdout, stderr = runCli(t, url, "jobs", "watch", submitted.JobId)
+	if code != 1 || !strings.Contains(stdout, "cancelled") || !strings.Contains(stderr, "ended cancelled") {
+		t.Errorf("expected watch to report the cancelled job, got %v: %v %v", code, stdout, stderr)
+	}
+}
+
+func TestSubmitFromFileCreatesBatches(t *testing.T) {
+	var requested domain.BatchRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/batches" {
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&requested)
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": domain.Batch{BatchId: "batch-1", Total: 2, Rejected: 1, Errors: []domain.BatchItemError{{ObjectId: "object-3", Code: "cooldown_active", Message: "ran recently"}}},
+		})
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "objects.txt")
+	os.WriteFile(file, []byte("object-1\n\n# skipped\nobject-2\nobject-3\n"), 0644)
+
+	code, stdout, stderr := runCli(t, server.URL, "jobs", "submit", "-type", "report", "-file", file)
+	if code != 0 {
+		t.Fatalf("submit failed with %v: %v", code, stderr)
+	}
+
+	if strings.Join(requested.ObjectIds, ",") != "object-1,object-2,object-3" || requested.Type != "report" {
+		t.Errorf("unexpected batch request %+v", requested)
+	}
+	if !strings.Contains(stdout, "batch-1") || !strings.Contains(stderr, "object-3: cooldown_active: ran recently") {
+		t.Errorf("expected the batch and its errors, got %q and %q", stdout, stderr)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	if code, _, stderr := runCli(t, "http://localhost", "jobs"); code != 2 || !strings.Contains(stderr, "jobs submit") {
+		t.Errorf("expected the usage, got %v: %v", code, stderr)
+	}
+
+	if code, _, stderr := runCli(t, "http://localhost", "jobs", "get"); code != 2 || !strings.Contains(stderr, "usage: hastyctl jobs get <job_id>") {
+		t.Errorf("expected the usage of get, got %v: %v", code, stderr)
+	}
+}
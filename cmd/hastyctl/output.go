@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	formatTable = "table"
+	formatJson  = "json"
+)
+
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+// print writes v as indented JSON, or as a table of header and rows.
+func (p *printer) print(v interface{}, header []string, rows [][]string) error {
+	if p.format == formatJson {
+		encoder := json.NewEncoder(p.w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// printLine writes v as one line of JSON, or the row as it is, for output
+// that streams.
+func (p *printer) printLine(v interface{}, row []string) error {
+	if p.format == formatJson {
+		return json.NewEncoder(p.w).Encode(v)
+	}
+
+	_, err := fmt.Fprintln(p.w, strings.Join(row, "  "))
+	return err
+}
+
+func formatTime(unix int64) string {
+	if unix == 0 {
+		return "-"
+	}
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+)
+
+var scheduleCommands = []command{
+	{"create", "-object-id object_id -cron expr [-type type] [-param key=value]... [-misfire skip|run_once]", createSchedule},
+	{"list", "", listSchedules},
+	{"get", "<schedule_id>", scheduleAction(func(ctx context.Context, cli *cli, scheduleId string) (*domain.Schedule, error) {
+		return cli.client.GetSchedule(ctx, scheduleId)
+	})},
+	{"pause", "<schedule_id>", scheduleAction(func(ctx context.Context, cli *cli, scheduleId string) (*domain.Schedule, error) {
+		return cli.client.PauseSchedule(ctx, scheduleId)
+	})},
+	{"resume", "<schedule_id>", scheduleAction(func(ctx context.Context, cli *cli, scheduleId string) (*domain.Schedule, error) {
+		return cli.client.ResumeSchedule(ctx, scheduleId)
+	})},
+	{"delete", "<schedule_id>", deleteSchedule},
+}
+
+var scheduleHeader = []string{"SCHEDULE ID", "OBJECT ID", "TYPE", "CRON", "MISFIRE", "PAUSED", "LAST RUN", "NEXT RUN"}
+
+func scheduleRow(schedule *domain.Schedule) []string {
+	return []string{schedule.ScheduleId, schedule.ObjectId, schedule.Type, schedule.CronExpr, schedule.MisfirePolicy,
+		strconv.FormatBool(schedule.Paused), formatTime(schedule.LastRun), formatTime(schedule.NextRun)}
+}
+
+func createSchedule(ctx context.Context, cli *cli, args []string) error {
+	flags := newFlags("create")
+	scheduleRequest := domain.ScheduleRequest{Params: paramsFlag{}}
+	flags.StringVar(&scheduleRequest.ObjectId, "object-id", "", "object id of every job")
+	flags.StringVar(&scheduleRequest.CronExpr, "cron", "", "cron expression, e.g. \"*/10 * * * *\"")
+	flags.StringVar(&scheduleRequest.Type, "type", "", "job type")
+	flags.StringVar(&scheduleRequest.MisfirePolicy, "misfire", "", "skip (default) or run_once")
+	flags.Var(paramsFlag(scheduleRequest.Params), "param", "job parameter as key=value, repeatable")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if scheduleRequest.ObjectId == "" || scheduleRequest.CronExpr == "" || flags.NArg() > 0 {
+		return errUsage
+	}
+
+	schedule, err := cli.client.CreateSchedule(ctx, scheduleRequest)
+	if err != nil {
+		return err
+	}
+
+	return cli.out.print(schedule, scheduleHeader, [][]string{scheduleRow(schedule)})
+}
+
+func listSchedules(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	schedules, err := cli.client.ListSchedules(ctx)
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{}
+	for _, schedule := range schedules {
+		rows = append(rows, scheduleRow(schedule))
+	}
+
+	return cli.out.print(schedules, scheduleHeader, rows)
+}
+
+// scheduleAction runs action for the schedule id argument and prints the
+// schedule it returns.
+func scheduleAction(action func(ctx context.Context, cli *cli, scheduleId string) (*domain.Schedule, error)) func(ctx context.Context, cli *cli, args []string) error {
+	return func(ctx context.Context, cli *cli, args []string) error {
+		if len(args) != 1 {
+			return errUsage
+		}
+
+		schedule, err := action(ctx, cli, args[0])
+		if err != nil {
+			return err
+		}
+
+		return cli.out.print(schedule, scheduleHeader, [][]string{scheduleRow(schedule)})
+	}
+}
+
+func deleteSchedule(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	return cli.client.DeleteSchedule(ctx, args[0])
+}
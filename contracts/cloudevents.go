@@ -0,0 +1,316 @@
+package contracts
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+)
+
+// CloudEventsSpecVersion is the only CloudEvents version read and written.
+const CloudEventsSpecVersion = "1.0"
+
+const (
+	ContentTypeJson            = "application/json"
+	ContentTypeProtobuf        = "application/protobuf"
+	ContentTypeCloudEventsJson = "application/cloudevents+json"
+)
+
+// cloudEventTypePrefix makes job:finished the type com.hasty.job.finished.
+const cloudEventTypePrefix = "com.hasty."
+
+// CloudEventsMode is how the envelope is put on the wire. Structured mode
+// sends the whole event as JSON, with the data inside it. Binary mode sends
+// the attributes as ce-* headers and the data as the body.
+type CloudEventsMode string
+
+const (
+	CloudEventsStructured CloudEventsMode = "structured"
+	CloudEventsBinary     CloudEventsMode = "binary"
+)
+
+// natsHeaderLine starts a binary mode event on NATS Streaming, which has no
+// headers: its payload is the header block of a NATS message, followed by
+// the data.
+const natsHeaderLine = "NATS/1.0\r\n"
+
+// ParseCloudEventsMode defaults to structured.
+func ParseCloudEventsMode(name string) (CloudEventsMode, error) {
+	switch CloudEventsMode(name) {
+	case "", CloudEventsStructured:
+		return CloudEventsStructured, nil
+	case CloudEventsBinary:
+		return CloudEventsBinary, nil
+	}
+
+	return "", fmt.Errorf("unknown cloudevents mode %q, expected structured or binary", name)
+}
+
+// CloudEvent is a CloudEvents 1.0 envelope. Data holds JSON data and
+// DataBase64 any other content type.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	Id              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      []byte          `json:"data_base64,omitempty"`
+}
+
+// CloudEventSource identifies the instance of service running on hostname.
+func CloudEventSource(service, hostname string) string {
+	return "/hasty/" + service + "/" + hostname
+}
+
+// CloudEventType is the type of events published on a NATS subject.
+func CloudEventType(subject string) string {
+	return cloudEventTypePrefix + strings.ReplaceAll(subject, ":", ".")
+}
+
+func newCloudEvent(source, natsSubject, subject, contentType string, data []byte) *CloudEvent {
+	cloudEvent := &CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		Id:              uuid.NewString(),
+		Source:          source,
+		Type:            CloudEventType(natsSubject),
+		Subject:         subject,
+		Time:            time.Now().UTC(),
+		DataContentType: contentType,
+	}
+
+	if contentType == ContentTypeJson {
+		cloudEvent.Data = data
+	} else {
+		cloudEvent.DataBase64 = data
+	}
+
+	return cloudEvent
+}
+
+// NewJobCloudEvent wraps jobEvent, with its job id as the subject and its
+// data in encoding.
+func NewJobCloudEvent(source string, jobEvent *JobEvent, encoding Encoding) (*CloudEvent, error) {
+	contentType, data, err := marshalJobEvent(jobEvent, encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCloudEvent(source, jobEvent.Subject, jobEvent.Job.JobId, contentType, data), nil
+}
+
+// NewWorkerHeartbeatCloudEvent wraps heartbeat, with its worker id as the
+// subject. Heartbeat data is always JSON.
+func NewWorkerHeartbeatCloudEvent(source string, heartbeat *WorkerHeartbeat) (*CloudEvent, error) {
+	data, err := EncodeWorkerHeartbeat(heartbeat)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCloudEvent(source, SubjectWorkerHeartbeat, heartbeat.WorkerId, ContentTypeJson, data), nil
+}
+
+func (ce *CloudEvent) data() []byte {
+	if ce.DataBase64 != nil {
+		return ce.DataBase64
+	}
+	return ce.Data
+}
+
+// Structured is the event in structured mode.
+func (ce *CloudEvent) Structured() ([]byte, error) {
+	return json.Marshal(ce)
+}
+
+// Binary is the event in binary mode, for a NATS message with headers.
+func (ce *CloudEvent) Binary() (nats.Header, []byte) {
+	header := nats.Header{}
+	header.Set("ce-specversion", ce.SpecVersion)
+	header.Set("ce-id", ce.Id)
+	header.Set("ce-source", ce.Source)
+	header.Set("ce-type", ce.Type)
+	if ce.Subject != "" {
+		header.Set("ce-subject", ce.Subject)
+	}
+	if !ce.Time.IsZero() {
+		header.Set("ce-time", ce.Time.Format(time.RFC3339Nano))
+	}
+	if ce.DataContentType != "" {
+		header.Set("content-type", ce.DataContentType)
+	}
+
+	return header, ce.data()
+}
+
+// EncodeCloudEvent is the payload of the event on NATS Streaming. In binary
+// mode it is the header block a NATS message would carry, then the data.
+func EncodeCloudEvent(ce *CloudEvent, mode CloudEventsMode) ([]byte, error) {
+	if mode != CloudEventsBinary {
+		return ce.Structured()
+	}
+
+	header, data := ce.Binary()
+
+	payload := bytes.NewBufferString(natsHeaderLine)
+	if err := http.Header(header).Write(payload); err != nil {
+		return nil, err
+	}
+	payload.WriteString("\r\n")
+	payload.Write(data)
+
+	return payload.Bytes(), nil
+}
+
+// ReadCloudEvent reads an event in either mode, from the headers of a NATS
+// message or from its payload. It returns nil without an error when the
+// message is not a CloudEvent, e.g. a payload from before the envelope.
+func ReadCloudEvent(header nats.Header, data []byte) (*CloudEvent, error) {
+	var cloudEvent *CloudEvent
+	var err error
+
+	switch {
+	case hasHeader(header, "ce-specversion"):
+		cloudEvent, err = cloudEventFromBinary(header, data)
+	case bytes.HasPrefix(data, []byte(natsHeaderLine)):
+		cloudEvent, err = cloudEventFromPayload(data)
+	case len(data) > 0 && data[0] == '{':
+		cloudEvent, err = cloudEventFromStructured(data)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err.Error())
+	}
+	if cloudEvent == nil {
+		return nil, nil
+	}
+
+	if cloudEvent.SpecVersion != CloudEventsSpecVersion {
+		return nil, fmt.Errorf("%w: cloudevents specversion %v, only %v is known", ErrUnsupportedVersion, cloudEvent.SpecVersion, CloudEventsSpecVersion)
+	}
+	if cloudEvent.Id == "" || cloudEvent.Source == "" || cloudEvent.Type == "" {
+		return nil, fmt.Errorf("%w: cloudevent needs an id, source and type", ErrInvalidPayload)
+	}
+
+	return cloudEvent, nil
+}
+
+func cloudEventFromStructured(data []byte) (*CloudEvent, error) {
+	probe := struct {
+		SpecVersion string `json:"specversion"`
+	}{}
+	if err := json.Unmarshal(data, &probe); err != nil || probe.SpecVersion == "" {
+		return nil, nil
+	}
+
+	cloudEvent := CloudEvent{}
+	if err := json.Unmarshal(data, &cloudEvent); err != nil {
+		return nil, err
+	}
+	if cloudEvent.DataContentType == "" && cloudEvent.Data != nil {
+		cloudEvent.DataContentType = ContentTypeJson
+	}
+
+	return &cloudEvent, nil
+}
+
+func cloudEventFromPayload(data []byte) (*CloudEvent, error) {
+	end := bytes.Index(data, []byte("\r\n\r\n"))
+	if end < 0 {
+		return nil, fmt.Errorf("header block is not terminated")
+	}
+
+	reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(data[len(natsHeaderLine) : end+4])))
+	header, err := reader.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	return cloudEventFromBinary(nats.Header(header), data[end+4:])
+}
+
+func cloudEventFromBinary(header nats.Header, data []byte) (*CloudEvent, error) {
+	cloudEvent := CloudEvent{
+		SpecVersion:     getHeader(header, "ce-specversion"),
+		Id:              getHeader(header, "ce-id"),
+		Source:          getHeader(header, "ce-source"),
+		Type:            getHeader(header, "ce-type"),
+		Subject:         getHeader(header, "ce-subject"),
+		DataContentType: getHeader(header, "content-type"),
+	}
+
+	if cloudEvent.DataContentType == ContentTypeJson {
+		cloudEvent.Data = data
+	} else {
+		cloudEvent.DataBase64 = data
+	}
+
+	if value := getHeader(header, "ce-time"); value != "" {
+		eventTime, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, err
+		}
+		cloudEvent.Time = eventTime
+	}
+
+	return &cloudEvent, nil
+}
+
+// getHeader ignores the case of key, since header names are case-insensitive
+// and nats.Header is not.
+func getHeader(header nats.Header, key string) string {
+	for name, values := range header {
+		if strings.EqualFold(name, key) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+func hasHeader(header nats.Header, key string) bool {
+	return getHeader(header, key) != ""
+}
+
+// DecodeJobEventMessage reads a job event in a CloudEvent of either mode, or
+// a bare payload from before the envelope.
+func DecodeJobEventMessage(header nats.Header, data []byte) (*JobEvent, error) {
+	cloudEvent, err := ReadCloudEvent(header, data)
+	if err != nil {
+		return nil, err
+	}
+	if cloudEvent == nil {
+		return DecodeJobEvent(data)
+	}
+
+	if !strings.HasPrefix(cloudEvent.Type, cloudEventTypePrefix+"job.") {
+		return nil, fmt.Errorf("%w: cloudevent type %v is not a job event", ErrInvalidPayload, cloudEvent.Type)
+	}
+
+	return unmarshalJobEvent(cloudEvent.DataContentType, cloudEvent.data())
+}
+
+// DecodeWorkerHeartbeatMessage reads a heartbeat in a CloudEvent of either
+// mode, or a bare payload from before the envelope.
+func DecodeWorkerHeartbeatMessage(header nats.Header, data []byte) (*WorkerHeartbeat, error) {
+	cloudEvent, err := ReadCloudEvent(header, data)
+	if err != nil {
+		return nil, err
+	}
+	if cloudEvent == nil {
+		return DecodeWorkerHeartbeat(data)
+	}
+
+	if cloudEvent.Type != CloudEventType(SubjectWorkerHeartbeat) {
+		return nil, fmt.Errorf("%w: cloudevent type %v is not a heartbeat", ErrInvalidPayload, cloudEvent.Type)
+	}
+
+	return DecodeWorkerHeartbeat(cloudEvent.data())
+}
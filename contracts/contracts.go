@@ -0,0 +1,38 @@
+// Package contracts defines the messages the api server and the job servers
+// exchange over NATS: their subjects, payloads and schema versions. Both
+// services encode and decode them only through this package.
+package contracts
+
+import "errors"
+
+// Subjects, job events go through NATS Streaming and heartbeats through
+// plain NATS.
+const (
+	SubjectJobCreated      = "job:created"
+	SubjectJobFinished     = "job:finished"
+	SubjectJobCancelled    = "job:cancelled"
+	SubjectWorkerHeartbeat = "worker:heartbeat"
+)
+
+// SchemaVersion is written into every payload. Version 0 is a payload from
+// before versions existed, which decoders upcast. Consumers must be
+// upgraded before producers start sending a new version, since a newer
+// version than a consumer knows is rejected.
+const SchemaVersion = 1
+
+// DefaultTenantId owns events published before tenants existed.
+const DefaultTenantId = "default"
+
+// DefaultJobType is the type of jobs created before job types existed.
+const DefaultJobType = "default"
+
+// ResultsBucket is the GridFS bucket of the api server's database holding
+// result documents, the ones too large for a job:finished event are
+// written there by the job server.
+const ResultsBucket = "job_result_data"
+
+var (
+	// ErrUnsupportedVersion is returned for payloads newer than SchemaVersion.
+	ErrUnsupportedVersion = errors.New("unsupported schema version")
+	ErrInvalidPayload     = errors.New("invalid payload")
+)
@@ -0,0 +1,224 @@
+package contracts
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func newJobEvent() *JobEvent {
+	return &JobEvent{
+		Subject:  SubjectJobFinished,
+		TenantId: "tenant-1",
+		Job: Job{
+			JobId:         "5f0c1d5e-3b8a-4f7e-9c2d-1a6b7e8f9a0b",
+			TenantId:      "tenant-1",
+			ObjectId:      "object-1",
+			Type:          "thumbnail",
+			Params:        map[string]string{"size": "small", "format": "png"},
+			Status:        "finished",
+			Timestamp:     1760000045,
+			CreatedAt:     1760000000,
+			SleepTimeUsed: 45,
+			BatchId:       "0a9f8e7b-6a1d-4c2e-8f7b-3e5a8b1c0d5f",
+			ApiKeyId:      "key-1",
+			WorkerId:      "job-server-1",
+		},
+	}
+}
+
+func TestJobEventRoundTrip(t *testing.T) {
+	for _, encoding := range []Encoding{EncodingJson, EncodingProtobuf} {
+		data, err := EncodeJobEvent(newJobEvent(), encoding)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		jobEvent, err := DecodeJobEvent(data)
+		if err != nil {
+			t.Fatalf("%v: %v", encoding, err)
+		}
+
+		want := newJobEvent()
+		want.SchemaVersion = SchemaVersion
+		if !reflect.DeepEqual(jobEvent, want) {
+			t.Errorf("%v: expected %+v, got %+v", encoding, want, jobEvent)
+		}
+	}
+}
+
+func TestJobResultRoundTrip(t *testing.T) {
+	results := []*JobResult{
+		{Data: json.RawMessage(`{"thumbnail":"object-1-small.png","bytes":2048}`)},
+		{Error: &JobError{Code: "unsupported_format", Message: "cannot read tiff", Details: map[string]string{"format": "tiff"}}},
+		{File: "64b7f0c2a1e4d5f6a7b8c9d0", Size: 4 << 20},
+	}
+
+	for _, encoding := range []Encoding{EncodingJson, EncodingProtobuf} {
+		for _, result := range results {
+			jobEvent := newJobEvent()
+			jobEvent.Job.Result = result
+
+			data, err := EncodeJobEvent(jobEvent, encoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := DecodeJobEvent(data)
+			if err != nil {
+				t.Fatalf("%v: %v", encoding, err)
+			}
+			if !reflect.DeepEqual(decoded.Job.Result, result) {
+				t.Errorf("%v: expected %+v, got %+v", encoding, result, decoded.Job.Result)
+			}
+		}
+	}
+}
+
+func TestParseEncoding(t *testing.T) {
+	if encoding, err := ParseEncoding(""); err != nil || encoding != EncodingProtobuf {
+		t.Errorf("expected Protobuf by default, got %v: %v", encoding, err)
+	}
+	if _, err := ParseEncoding("xml"); err == nil {
+		t.Error("expected an unknown encoding to be rejected")
+	}
+}
+
+func TestDecodeJobEventUpcastsUnversionedEvents(t *testing.T) {
+	jobEvent, err := DecodeJobEvent([]byte(`{"subject":"job:created","job":{"job_id":"job-1","object_id":"object-1","status":"processing"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jobEvent.SchemaVersion != SchemaVersion || jobEvent.TenantId != DefaultTenantId || jobEvent.Job.TenantId != DefaultTenantId || jobEvent.Job.Type != DefaultJobType {
+		t.Errorf("expected the event to be upcast, got %+v", jobEvent)
+	}
+}
+
+func TestDecodeRejectsNewerAndInvalidPayloads(t *testing.T) {
+	if _, err := DecodeJobEvent([]byte(`{"schema_version":99,"job":{"job_id":"job-1"}}`)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected a newer job event to be rejected, got %v", err)
+	}
+	if _, err := DecodeWorkerHeartbeat([]byte(`{"schema_version":99,"worker_id":"worker-1"}`)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected a newer heartbeat to be rejected, got %v", err)
+	}
+
+	for _, data := range []string{`not json`, `{"schema_version":1,"job":{}}`, "\x00\xff"} {
+		if _, err := DecodeJobEvent([]byte(data)); !errors.Is(err, ErrInvalidPayload) {
+			t.Errorf("expected %q to be invalid, got %v", data, err)
+		}
+	}
+}
+
+func BenchmarkEncodeJobEvent(b *testing.B) {
+	for _, encoding := range []Encoding{EncodingJson, EncodingProtobuf} {
+		b.Run(string(encoding), func(b *testing.B) {
+			jobEvent := newJobEvent()
+			data, _ := EncodeJobEvent(jobEvent, encoding)
+			b.ReportMetric(float64(len(data)), "bytes/event")
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := EncodeJobEvent(jobEvent, encoding); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecodeJobEvent(b *testing.B) {
+	for _, encoding := range []Encoding{EncodingJson, EncodingProtobuf} {
+		b.Run(string(encoding), func(b *testing.B) {
+			data, _ := EncodeJobEvent(newJobEvent(), encoding)
+			b.ReportMetric(float64(len(data)), "bytes/event")
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := DecodeJobEvent(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestJobCloudEventRoundTrip(t *testing.T) {
+	want := newJobEvent()
+	want.SchemaVersion = SchemaVersion
+
+	for _, encoding := range []Encoding{EncodingJson, EncodingProtobuf} {
+		cloudEvent, err := NewJobCloudEvent(CloudEventSource("job-server", "host-1"), newJobEvent(), encoding)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if cloudEvent.Type != "com.hasty.job.finished" || cloudEvent.Subject != want.Job.JobId || cloudEvent.Source != "/hasty/job-server/host-1" || cloudEvent.Id == "" || cloudEvent.Time.IsZero() {
+			t.Errorf("%v: unexpected attributes %+v", encoding, cloudEvent)
+		}
+
+		for _, mode := range []CloudEventsMode{CloudEventsStructured, CloudEventsBinary} {
+			data, err := EncodeCloudEvent(cloudEvent, mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			jobEvent, err := DecodeJobEventMessage(nil, data)
+			if err != nil {
+				t.Fatalf("%v %v: %v", encoding, mode, err)
+			}
+			if !reflect.DeepEqual(jobEvent, want) {
+				t.Errorf("%v %v: expected %+v, got %+v", encoding, mode, want, jobEvent)
+			}
+		}
+
+		header, data := cloudEvent.Binary()
+		if jobEvent, err := DecodeJobEventMessage(header, data); err != nil || !reflect.DeepEqual(jobEvent, want) {
+			t.Errorf("%v: expected the event from the headers, got %+v: %v", encoding, jobEvent, err)
+		}
+	}
+}
+
+func TestReadCloudEvent(t *testing.T) {
+	structured := `{"specversion":"1.0","id":"event-1","source":"/hasty/api-server/host-1","type":"com.hasty.job.created","subject":"job-1","time":"2026-10-19T10:00:00Z","data":{"job":{"job_id":"job-1"}}}`
+
+	cloudEvent, err := ReadCloudEvent(nil, []byte(structured))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cloudEvent.Id != "event-1" || cloudEvent.DataContentType != ContentTypeJson || cloudEvent.Time.Unix() != 1792404000 {
+		t.Errorf("unexpected event %+v", cloudEvent)
+	}
+
+	if cloudEvent, err := ReadCloudEvent(nil, []byte(`{"subject":"job:created","job":{"job_id":"job-1"}}`)); cloudEvent != nil || err != nil {
+		t.Errorf("expected a bare payload not to be a cloudevent, got %+v: %v", cloudEvent, err)
+	}
+
+	header, _ := cloudEvent.Binary()
+	header.Set("ce-specversion", "2.0")
+	if _, err := ReadCloudEvent(header, nil); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected an unknown specversion to be rejected, got %v", err)
+	}
+
+	if _, err := DecodeJobEventMessage(nil, []byte(`{"specversion":"1.0","id":"event-1","source":"/hasty","type":"com.example.other","data":{}}`)); !errors.Is(err, ErrInvalidPayload) {
+		t.Errorf("expected another type to be rejected, got %v", err)
+	}
+}
+
+func TestWorkerHeartbeatCloudEvent(t *testing.T) {
+	cloudEvent, err := NewWorkerHeartbeatCloudEvent(CloudEventSource("job-server", "host-1"), &WorkerHeartbeat{WorkerId: "worker-1", Capacity: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, data := cloudEvent.Binary()
+	heartbeat, err := DecodeWorkerHeartbeatMessage(header, data)
+	if err != nil || heartbeat.WorkerId != "worker-1" || heartbeat.Capacity != 4 {
+		t.Errorf("unexpected heartbeat %+v: %v", heartbeat, err)
+	}
+
+	if _, err := DecodeWorkerHeartbeatMessage(nil, []byte(`{"worker_id":"worker-1"}`)); err != nil {
+		t.Errorf("expected a bare heartbeat to be read, got %v", err)
+	}
+}
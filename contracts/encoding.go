@@ -0,0 +1,28 @@
+package contracts
+
+import "fmt"
+
+// Encoding is how job events are put on the wire. Consumers decode both, so
+// producers can switch while consumers of either kind are running.
+type Encoding string
+
+const (
+	EncodingJson     Encoding = "json"
+	EncodingProtobuf Encoding = "protobuf"
+)
+
+// protobufMarker starts every Protobuf payload. A JSON payload starts with
+// "{", and NATS Streaming messages have no headers to say which one it is.
+const protobufMarker = 0x00
+
+// ParseEncoding defaults to Protobuf.
+func ParseEncoding(name string) (Encoding, error) {
+	switch Encoding(name) {
+	case "", EncodingProtobuf:
+		return EncodingProtobuf, nil
+	case EncodingJson:
+		return EncodingJson, nil
+	}
+
+	return "", fmt.Errorf("unknown encoding %q, expected json or protobuf", name)
+}
@@ -0,0 +1,5 @@
+// Package eventspb is the Protobuf encoding of the job events, generated
+// from events.proto.
+package eventspb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative events.proto
@@ -0,0 +1,227 @@
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/bogdan-copocean/hasty-server/contracts/eventspb"
+	"google.golang.org/protobuf/proto"
+)
+
+// Job is a job as it travels between the services. On SubjectJobFinished
+// its Status is "finished", or "failed" when its Result carries the
+// executor's error.
+type Job struct {
+	JobId         string            `json:"job_id"`
+	TenantId      string            `json:"tenant_id"`
+	ObjectId      string            `json:"object_id"`
+	Type          string            `json:"type"`
+	Params        map[string]string `json:"params,omitempty"`
+	Status        string            `json:"status"`
+	Timestamp     int64             `json:"timestamp"`
+	CreatedAt     int64             `json:"created_at"`
+	SleepTimeUsed int               `json:"sleep_time_used"`
+	ScheduleId    string            `json:"schedule_id,omitempty"`
+	WorkflowId    string            `json:"workflow_id,omitempty"`
+	BatchId       string            `json:"batch_id,omitempty"`
+	ApiKeyId      string            `json:"api_key_id,omitempty"`
+	WorkerId      string            `json:"worker_id,omitempty"`
+	Result        *JobResult        `json:"result,omitempty"`
+}
+
+// JobResult is what the executor of a job returned: a JSON document in Data
+// when the job finished, or the Error of a failed job. A document too large
+// for the event is in ResultsBucket instead, File is its GridFS id and Size
+// its length.
+type JobResult struct {
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error *JobError       `json:"error,omitempty"`
+	File  string          `json:"file,omitempty"`
+	Size  int64           `json:"size,omitempty"`
+}
+
+// JobError is an executor's error. Code is for machines, Details carries
+// whatever else the executor knows about the failure.
+type JobError struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+func (e *JobError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// JobEvent is the payload of SubjectJobCreated, SubjectJobFinished and
+// SubjectJobCancelled.
+type JobEvent struct {
+	SchemaVersion int    `json:"schema_version"`
+	Subject       string `json:"subject"`
+	TenantId      string `json:"tenant_id"`
+	Job           Job    `json:"job"`
+}
+
+// EncodeJobEvent is the bare payload of jobEvent, without an envelope.
+func EncodeJobEvent(jobEvent *JobEvent, encoding Encoding) ([]byte, error) {
+	_, data, err := marshalJobEvent(jobEvent, encoding)
+	if err != nil || encoding == EncodingJson {
+		return data, err
+	}
+
+	return append([]byte{protobufMarker}, data...), nil
+}
+
+// DecodeJobEvent reads a bare payload in either encoding. It upcasts
+// payloads from before schema versions and rejects newer ones with
+// ErrUnsupportedVersion.
+func DecodeJobEvent(data []byte) (*JobEvent, error) {
+	if len(data) > 0 && data[0] == protobufMarker {
+		return unmarshalJobEvent(ContentTypeProtobuf, data[1:])
+	}
+
+	return unmarshalJobEvent(ContentTypeJson, data)
+}
+
+func marshalJobEvent(jobEvent *JobEvent, encoding Encoding) (string, []byte, error) {
+	jobEvent.SchemaVersion = SchemaVersion
+
+	if encoding == EncodingJson {
+		data, err := json.Marshal(jobEvent)
+		return ContentTypeJson, data, err
+	}
+
+	data, err := proto.Marshal(jobEventToProto(jobEvent))
+	return ContentTypeProtobuf, data, err
+}
+
+func unmarshalJobEvent(contentType string, data []byte) (*JobEvent, error) {
+	jobEvent := JobEvent{}
+
+	switch contentType {
+	case ContentTypeProtobuf:
+		pb := eventspb.JobEvent{}
+		if err := proto.Unmarshal(data, &pb); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err.Error())
+		}
+		jobEvent = jobEventFromProto(&pb)
+	case ContentTypeJson:
+		if err := json.Unmarshal(data, &jobEvent); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err.Error())
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown content type %q", ErrInvalidPayload, contentType)
+	}
+
+	if jobEvent.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("%w: job event version %v, at most %v is known", ErrUnsupportedVersion, jobEvent.SchemaVersion, SchemaVersion)
+	}
+
+	if jobEvent.SchemaVersion == 0 {
+		upcastJobEventV0(&jobEvent)
+	}
+
+	if jobEvent.Job.JobId == "" {
+		return nil, fmt.Errorf("%w: event has no job_id", ErrInvalidPayload)
+	}
+
+	return &jobEvent, nil
+}
+
+// upcastJobEventV0 fills in what unversioned events could leave out: they
+// may predate tenants and job types, and carried the tenant only next to
+// the job.
+func upcastJobEventV0(jobEvent *JobEvent) {
+	if jobEvent.TenantId == "" {
+		jobEvent.TenantId = DefaultTenantId
+	}
+	if jobEvent.Job.TenantId == "" {
+		jobEvent.Job.TenantId = jobEvent.TenantId
+	}
+	if jobEvent.Job.Type == "" {
+		jobEvent.Job.Type = DefaultJobType
+	}
+	jobEvent.SchemaVersion = SchemaVersion
+}
+
+func jobEventToProto(jobEvent *JobEvent) *eventspb.JobEvent {
+	job := jobEvent.Job
+
+	return &eventspb.JobEvent{
+		SchemaVersion: int32(jobEvent.SchemaVersion),
+		Subject:       jobEvent.Subject,
+		TenantId:      jobEvent.TenantId,
+		Job: &eventspb.Job{
+			JobId:         job.JobId,
+			TenantId:      job.TenantId,
+			ObjectId:      job.ObjectId,
+			Type:          job.Type,
+			Params:        job.Params,
+			Status:        job.Status,
+			Timestamp:     job.Timestamp,
+			CreatedAt:     job.CreatedAt,
+			SleepTimeUsed: int32(job.SleepTimeUsed),
+			ScheduleId:    job.ScheduleId,
+			WorkflowId:    job.WorkflowId,
+			BatchId:       job.BatchId,
+			ApiKeyId:      job.ApiKeyId,
+			WorkerId:      job.WorkerId,
+			Result:        jobResultToProto(job.Result),
+		},
+	}
+}
+
+func jobEventFromProto(pb *eventspb.JobEvent) JobEvent {
+	job := pb.GetJob()
+
+	return JobEvent{
+		SchemaVersion: int(pb.SchemaVersion),
+		Subject:       pb.Subject,
+		TenantId:      pb.TenantId,
+		Job: Job{
+			JobId:         job.GetJobId(),
+			TenantId:      job.GetTenantId(),
+			ObjectId:      job.GetObjectId(),
+			Type:          job.GetType(),
+			Params:        job.GetParams(),
+			Status:        job.GetStatus(),
+			Timestamp:     job.GetTimestamp(),
+			CreatedAt:     job.GetCreatedAt(),
+			SleepTimeUsed: int(job.GetSleepTimeUsed()),
+			ScheduleId:    job.GetScheduleId(),
+			WorkflowId:    job.GetWorkflowId(),
+			BatchId:       job.GetBatchId(),
+			ApiKeyId:      job.GetApiKeyId(),
+			WorkerId:      job.GetWorkerId(),
+			Result:        jobResultFromProto(job.GetResult()),
+		},
+	}
+}
+
+func jobResultToProto(result *JobResult) *eventspb.JobResult {
+	if result == nil {
+		return nil
+	}
+
+	pb := &eventspb.JobResult{Data: result.Data, File: result.File, Size: result.Size}
+	if result.Error != nil {
+		pb.Error = &eventspb.JobError{Code: result.Error.Code, Message: result.Error.Message, Details: result.Error.Details}
+	}
+
+	return pb
+}
+
+func jobResultFromProto(pb *eventspb.JobResult) *JobResult {
+	if pb == nil {
+		return nil
+	}
+
+	result := &JobResult{Data: pb.GetData(), File: pb.GetFile(), Size: pb.GetSize()}
+	if pb.GetError() != nil {
+		result.Error = &JobError{Code: pb.Error.GetCode(), Message: pb.Error.GetMessage(), Details: pb.Error.GetDetails()}
+	}
+	if len(result.Data) == 0 {
+		result.Data = nil
+	}
+
+	return result
+}
@@ -0,0 +1,44 @@
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// WorkerHeartbeat is the payload of SubjectWorkerHeartbeat.
+type WorkerHeartbeat struct {
+	SchemaVersion int      `json:"schema_version"`
+	WorkerId      string   `json:"worker_id"`
+	Capacity      int      `json:"capacity"`
+	Busy          int64    `json:"busy"`
+	JobTypes      []string `json:"job_types"`
+	Version       string   `json:"version"`
+	StartedAt     int64    `json:"started_at"`
+	Timestamp     int64    `json:"timestamp"`
+}
+
+func EncodeWorkerHeartbeat(heartbeat *WorkerHeartbeat) ([]byte, error) {
+	heartbeat.SchemaVersion = SchemaVersion
+	return json.Marshal(heartbeat)
+}
+
+// DecodeWorkerHeartbeat rejects heartbeats newer than SchemaVersion.
+// Unversioned ones have the same fields, they only need the version.
+func DecodeWorkerHeartbeat(data []byte) (*WorkerHeartbeat, error) {
+	heartbeat := WorkerHeartbeat{}
+
+	if err := json.Unmarshal(data, &heartbeat); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err.Error())
+	}
+
+	if heartbeat.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("%w: heartbeat version %v, at most %v is known", ErrUnsupportedVersion, heartbeat.SchemaVersion, SchemaVersion)
+	}
+	heartbeat.SchemaVersion = SchemaVersion
+
+	if heartbeat.WorkerId == "" {
+		return nil, fmt.Errorf("%w: heartbeat has no worker_id", ErrInvalidPayload)
+	}
+
+	return &heartbeat, nil
+}
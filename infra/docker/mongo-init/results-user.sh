@@ -0,0 +1,20 @@
+#!/bin/bash
+# Creates the user the job servers write large results with. It can only
+# read and write the job_result_data GridFS bucket of the api server's
+# database. Like every script in /docker-entrypoint-initdb.d it only runs
+# when the volume is empty.
+set -e
+
+mongosh --quiet -u "$MONGO_INITDB_ROOT_USERNAME" -p "$MONGO_INITDB_ROOT_PASSWORD" --authenticationDatabase admin <<JS
+const bucket = ["job_result_data.files", "job_result_data.chunks"].map((collection) => ({
+  resource: { db: "jobs_db", collection: collection },
+  actions: ["find", "insert", "listIndexes", "createIndex"],
+}));
+
+db.getSiblingDB("admin").createRole({ role: "resultsWriter", privileges: bucket, roles: [] });
+db.getSiblingDB("admin").createUser({
+  user: "$RESULTS_MONGO_USERNAME",
+  pwd: "$RESULTS_MONGO_PASSWORD",
+  roles: [{ role: "resultsWriter", db: "admin" }],
+});
+JS
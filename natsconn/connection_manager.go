@@ -0,0 +1,230 @@
+// Package natsconn keeps the NATS Streaming connection of a service up, for
+// the api server and the job servers alike.
+package natsconn
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/nats-io/stan.go"
+)
+
+const (
+	ConnectionConnected    = "connected"
+	ConnectionReconnecting = "reconnecting"
+
+	MinReconnectWait = time.Second
+	MaxReconnectWait = 30 * time.Second
+
+	// DefaultNatsUrl is the NATS Streaming server of docker-compose
+	DefaultNatsUrl = "nats://nats-streaming:4222"
+)
+
+var ErrNotConnected = errors.New("not connected to nats")
+
+// ConnectionManager owns the NATS Streaming connection. When the connection
+// is lost it reconnects with backoff and runs every registered subscribe
+// function again, so durable queue subscriptions resume where they were.
+type ConnectionManager interface {
+	// Conn returns the current connection, or ErrNotConnected.
+	Conn() (stan.Conn, error)
+	// Subscribe runs subscribe now and again after every reconnect.
+	Subscribe(subscribe func(conn stan.Conn) error) error
+	// Publish publishes synchronously. While disconnected, messages are
+	// buffered up to the buffer size and flushed in order once reconnected;
+	// beyond that it returns ErrNotConnected.
+	Publish(subject string, data []byte) error
+	Status() ConnectionStatus
+}
+
+type ConnectionStatus struct {
+	State             string `json:"state"`
+	ClientId          string `json:"client_id"`
+	Reconnects        uint64 `json:"reconnects"`
+	DisconnectedSince int64  `json:"disconnected_since,omitempty"`
+	LastError         string `json:"last_error,omitempty"`
+	Buffered          int    `json:"buffered"`
+	BufferSize        int    `json:"buffer_size"`
+}
+
+type pendingPublish struct {
+	subject string
+	data    []byte
+}
+
+type connectionManager struct {
+	clusterId  string
+	clientId   string
+	url        string
+	bufferSize int
+
+	mu                sync.Mutex
+	conn              stan.Conn
+	subscriptions     []func(conn stan.Conn) error
+	buffer            []pendingPublish
+	connectedOnce     bool
+	reconnects        uint64
+	disconnectedSince int64
+	lastError         string
+}
+
+// ConnectToNats blocks until the first connection is made, retrying with
+// backoff, and keeps the connection up from then on.
+func ConnectToNats(url, clientId string, bufferSize int) ConnectionManager {
+	cm := &connectionManager{
+		clusterId:         "test-cluster",
+		clientId:          clientId,
+		url:               url,
+		bufferSize:        bufferSize,
+		disconnectedSince: time.Now().Unix(),
+	}
+	cm.connectLoop()
+
+	log.Println("Connected to Nats")
+
+	return cm
+}
+
+func (cm *connectionManager) Conn() (stan.Conn, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if cm.conn == nil {
+		return nil, ErrNotConnected
+	}
+	return cm.conn, nil
+}
+
+func (cm *connectionManager) Subscribe(subscribe func(conn stan.Conn) error) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	cm.subscriptions = append(cm.subscriptions, subscribe)
+
+	if cm.conn == nil {
+		return nil
+	}
+	return subscribe(cm.conn)
+}
+
+func (cm *connectionManager) Publish(subject string, data []byte) error {
+	cm.mu.Lock()
+
+	if cm.conn == nil {
+		defer cm.mu.Unlock()
+
+		if len(cm.buffer) >= cm.bufferSize {
+			return ErrNotConnected
+		}
+		cm.buffer = append(cm.buffer, pendingPublish{subject: subject, data: data})
+		return nil
+	}
+
+	conn := cm.conn
+	cm.mu.Unlock()
+
+	return conn.Publish(subject, data)
+}
+
+func (cm *connectionManager) Status() ConnectionStatus {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	status := ConnectionStatus{
+		State:      ConnectionConnected,
+		ClientId:   cm.clientId,
+		Reconnects: cm.reconnects,
+		LastError:  cm.lastError,
+		Buffered:   len(cm.buffer),
+		BufferSize: cm.bufferSize,
+	}
+
+	if cm.conn == nil {
+		status.State = ConnectionReconnecting
+		status.DisconnectedSince = cm.disconnectedSince
+	}
+
+	return status
+}
+
+func (cm *connectionManager) connectionLost(lost stan.Conn, reason error) {
+	cm.mu.Lock()
+	if cm.conn != lost {
+		cm.mu.Unlock()
+		return
+	}
+	cm.conn = nil
+	cm.disconnectedSince = time.Now().Unix()
+	cm.lastError = reason.Error()
+	cm.mu.Unlock()
+
+	log.Printf("Connection lost, reconnecting, reason: %v\n", reason)
+
+	lost.Close()
+
+	go cm.connectLoop()
+}
+
+func (cm *connectionManager) connectLoop() {
+	wait := MinReconnectWait
+
+	for {
+		err := cm.connect()
+		if err == nil {
+			return
+		}
+
+		cm.mu.Lock()
+		cm.lastError = err.Error()
+		cm.mu.Unlock()
+
+		log.Printf("Can't connect: %v, retrying in %v. Make sure a NATS Streaming Server is running at: %s\n", err, wait, cm.url)
+
+		time.Sleep(wait)
+		if wait *= 2; wait > MaxReconnectWait {
+			wait = MaxReconnectWait
+		}
+	}
+}
+
+// connect dials, subscribes and flushes the buffer before the connection is
+// handed out, so buffered messages keep their order.
+func (cm *connectionManager) connect() error {
+	conn, err := stan.Connect(cm.clusterId, cm.clientId, stan.NatsURL(cm.url),
+		stan.Pings(1, 3),
+		stan.SetConnectionLostHandler(cm.connectionLost))
+	if err != nil {
+		return err
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	for _, subscribe := range cm.subscriptions {
+		if err := subscribe(conn); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+
+	for len(cm.buffer) > 0 {
+		if err := conn.Publish(cm.buffer[0].subject, cm.buffer[0].data); err != nil {
+			conn.Close()
+			return err
+		}
+		cm.buffer = cm.buffer[1:]
+	}
+
+	if cm.connectedOnce {
+		cm.reconnects++
+		log.Printf("Reconnected to Nats, resubscribed %v subscriptions\n", len(cm.subscriptions))
+	}
+
+	cm.conn = conn
+	cm.connectedOnce = true
+	cm.disconnectedSince = 0
+
+	return nil
+}
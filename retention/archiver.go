@@ -0,0 +1,133 @@
+package retention
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Archiver keeps expired documents before they are deleted.
+type Archiver interface {
+	Name() string
+	Archive(docs []bson.Raw) error
+}
+
+type collectionArchiver struct {
+	collection *mongo.Collection
+}
+
+// NewCollectionArchiver copies documents to collection with their _id, so
+// archiving the same document twice keeps one copy.
+func NewCollectionArchiver(collection *mongo.Collection) Archiver {
+	return &collectionArchiver{collection: collection}
+}
+
+func (a *collectionArchiver) Name() string {
+	return "collection:" + a.collection.Name()
+}
+
+func (a *collectionArchiver) Archive(docs []bson.Raw) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	models := []mongo.WriteModel{}
+	for _, doc := range docs {
+		models = append(models, mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": doc.Lookup("_id")}).SetReplacement(doc).SetUpsert(true))
+	}
+
+	_, err := a.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+type fileArchiver struct {
+	dir    string
+	prefix string
+}
+
+// NewFileArchiver writes every batch to its own gzipped NDJSON file in dir,
+// one document per line as relaxed extended JSON.
+func NewFileArchiver(dir, prefix string) Archiver {
+	return &fileArchiver{dir: dir, prefix: prefix}
+}
+
+func (a *fileArchiver) Name() string {
+	return "file:" + a.dir
+}
+
+func (a *fileArchiver) Archive(docs []bson.Raw) error {
+	if err := os.MkdirAll(a.dir, 0755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%v-%v.ndjson.gz", a.prefix, time.Now().UTC().Format("20060102T150405.000000000Z"))
+	path := filepath.Join(a.dir, name)
+
+	// Written under a temporary name, a crash never leaves a partial archive
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path + ".tmp")
+
+	gz := gzip.NewWriter(file)
+	for _, doc := range docs {
+		line, err := bson.MarshalExtJSON(doc, false, false)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		if _, err := gz.Write(append(line, '\n')); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	if err := gz.Close(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+type noArchiver struct{}
+
+// NewNoArchiver deletes without keeping anything.
+func NewNoArchiver() Archiver {
+	return noArchiver{}
+}
+
+func (noArchiver) Name() string {
+	return "none"
+}
+
+func (noArchiver) Archive(docs []bson.Raw) error {
+	return nil
+}
+
+// ParseArchiver reads an archive spec: "collection" (the default) keeps
+// expired documents in archive, "file:<dir>" in gzipped NDJSON files named
+// after prefix, and "none" deletes them.
+func ParseArchiver(spec string, archive *mongo.Collection, prefix string) (Archiver, error) {
+	switch {
+	case spec == "" || spec == "collection":
+		return NewCollectionArchiver(archive), nil
+	case spec == "none":
+		return NewNoArchiver(), nil
+	case strings.HasPrefix(spec, "file:") && len(spec) > len("file:"):
+		return NewFileArchiver(strings.TrimPrefix(spec, "file:"), prefix), nil
+	}
+
+	return nil, fmt.Errorf("unknown archive %q, expected collection, file:<dir> or none", spec)
+}
@@ -0,0 +1,49 @@
+package retention
+
+import (
+	"bufio"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestFileArchiverWritesGzippedNdjson(t *testing.T) {
+	dir := t.TempDir()
+	archiver := NewFileArchiver(dir, "jobs")
+
+	docs := []bson.Raw{}
+	for _, jobId := range []string{"job-1", "job-2"} {
+		doc, _ := bson.Marshal(bson.M{"jobId": jobId, "status": "finished"})
+		docs = append(docs, doc)
+	}
+
+	if err := archiver.Archive(docs); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 1 || !strings.HasPrefix(filepath.Base(files[0]), "jobs-") || !strings.HasSuffix(files[0], ".ndjson.gz") {
+		t.Fatalf("expected a single archive, got %v", files)
+	}
+
+	file, _ := os.Open(files[0])
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := []string{}
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	if len(lines) != 2 || !strings.Contains(lines[0], `"jobId":"job-1"`) || !strings.Contains(lines[1], `"jobId":"job-2"`) {
+		t.Errorf("expected one job per line, got %q", lines)
+	}
+}
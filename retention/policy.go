@@ -0,0 +1,92 @@
+// Package retention purges expired jobs and job events, archiving them
+// first. The api server and the job servers share it, each with a Store
+// over its own collection.
+package retention
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPurgeInterval is how often expired documents are purged.
+const DefaultPurgeInterval = time.Hour
+
+// terminalStatuses are the statuses a job no longer leaves, the only ones
+// that can expire.
+var terminalStatuses = map[string]bool{"finished": true, "failed": true, "cancelled": true, "skipped": true}
+
+// Policy is how long jobs of a terminal status are kept. Statuses without
+// an entry are kept forever.
+type Policy map[string]time.Duration
+
+// Report is what a purge removed, or with DryRun what it would remove.
+type Report struct {
+	DryRun   bool           `json:"dry_run"`
+	Archive  string         `json:"archive"`
+	Statuses []StatusReport `json:"statuses"`
+	Total    int64          `json:"total"`
+}
+
+type StatusReport struct {
+	Status  string `json:"status"`
+	KeepFor string `json:"keep_for"`
+	// Documents older than Before are removed
+	Before int64 `json:"before"`
+	Count  int64 `json:"count"`
+}
+
+// ParsePolicy reads "status=age" entries separated by commas, e.g.
+// "finished=30d,cancelled=90d". Ages are Go durations or a number of days.
+// Only terminal statuses can expire.
+func ParsePolicy(spec string) (Policy, error) {
+	policy := Policy{}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid retention %q, expected status=age", entry)
+		}
+
+		status := parts[0]
+		if !terminalStatuses[status] {
+			return nil, fmt.Errorf("invalid retention for status %v: only finished, failed, cancelled and skipped jobs expire", status)
+		}
+
+		age, err := parseAge(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid retention for status %v: %v", status, err.Error())
+		}
+		policy[status] = age
+	}
+
+	return policy, nil
+}
+
+func parseAge(spec string) (time.Duration, error) {
+	var age time.Duration
+	if strings.HasSuffix(spec, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(spec, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid number of days %q", spec)
+		}
+		age = time.Duration(days) * 24 * time.Hour
+	} else {
+		parsed, err := time.ParseDuration(spec)
+		if err != nil {
+			return 0, err
+		}
+		age = parsed
+	}
+
+	if age <= 0 {
+		return 0, fmt.Errorf("the age must be positive, got %q", spec)
+	}
+	return age, nil
+}
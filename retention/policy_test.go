@@ -0,0 +1,22 @@
+package retention
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy("finished=30d, cancelled=2160h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy["finished"] != 30*24*time.Hour || policy["cancelled"] != 90*24*time.Hour || len(policy) != 2 {
+		t.Errorf("unexpected policy %v", policy)
+	}
+
+	for _, spec := range []string{"processing=1d", "pending=1d", "finished", "finished=0d", "finished=soon"} {
+		if _, err := ParsePolicy(spec); err == nil {
+			t.Errorf("expected %q to be rejected", spec)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PurgeBatchSize is how many expired documents are archived and deleted at
+// once.
+const PurgeBatchSize = 1000
+
+// Store holds the documents a policy expires.
+type Store interface {
+	CountExpired(status string, before int64) (int64, error)
+	// PurgeExpired archives and then deletes the documents in batches. A
+	// batch that cannot be archived is not deleted.
+	PurgeExpired(status string, before int64, archiver Archiver) (int64, error)
+}
+
+type Purger interface {
+	// Purge archives and deletes the documents the policy no longer keeps,
+	// or with dryRun only counts them.
+	Purge(dryRun bool) (*Report, error)
+	// Start purges every interval, if the policy keeps anything for less
+	// than forever.
+	Start(interval time.Duration)
+}
+
+type purger struct {
+	store    Store
+	policy   Policy
+	archiver Archiver
+	noun     string
+}
+
+// NewPurger purges store by policy. noun names its documents in errors and
+// logs, e.g. "jobs".
+func NewPurger(store Store, policy Policy, archiver Archiver, noun string) Purger {
+	return &purger{store: store, policy: policy, archiver: archiver, noun: noun}
+}
+
+func (p *purger) Purge(dryRun bool) (*Report, error) {
+	now := time.Now()
+
+	report := Report{DryRun: dryRun, Archive: p.archiver.Name(), Statuses: []StatusReport{}}
+
+	statuses := []string{}
+	for status := range p.policy {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+
+	for _, status := range statuses {
+		keepFor := p.policy[status]
+		statusReport := StatusReport{
+			Status:  status,
+			KeepFor: keepFor.String(),
+			Before:  now.Add(-keepFor).Unix(),
+		}
+
+		var err error
+		if dryRun {
+			statusReport.Count, err = p.store.CountExpired(status, statusReport.Before)
+		} else {
+			statusReport.Count, err = p.store.PurgeExpired(status, statusReport.Before, p.archiver)
+		}
+
+		report.Total += statusReport.Count
+		report.Statuses = append(report.Statuses, statusReport)
+
+		if err != nil {
+			return &report, fmt.Errorf("could not purge %v %v: %w", status, p.noun, err)
+		}
+	}
+
+	return &report, nil
+}
+
+func (p *purger) Start(interval time.Duration) {
+	if len(p.policy) == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			report, err := p.Purge(false)
+			if err != nil {
+				log.Printf("could not purge expired %v: %v\n", p.noun, err.Error())
+			}
+			if report != nil && report.Total > 0 {
+				log.Printf("purged %v expired %v to %v\n", report.Total, p.noun, report.Archive)
+			}
+		}
+	}()
+}
+
+// PurgeCollection archives and then deletes the documents of collection
+// matching filter, PurgeBatchSize at a time. beforeDelete, unless nil, runs
+// on every archived batch, which is not deleted when it fails.
+func PurgeCollection(collection *mongo.Collection, filter bson.M, archiver Archiver, beforeDelete func(ctx context.Context, docs []bson.Raw) error) (int64, error) {
+	var total int64
+	for {
+		deleted, err := purgeBatch(collection, filter, archiver, beforeDelete)
+		total += deleted
+		if err != nil || deleted == 0 {
+			return total, err
+		}
+	}
+}
+
+func purgeBatch(collection *mongo.Collection, filter bson.M, archiver Archiver, beforeDelete func(ctx context.Context, docs []bson.Raw) error) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetLimit(PurgeBatchSize))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	docs := []bson.Raw{}
+	ids := bson.A{}
+	for cursor.Next(ctx) {
+		doc := append(bson.Raw(nil), cursor.Current...)
+		docs = append(docs, doc)
+		ids = append(ids, doc.Lookup("_id"))
+	}
+	if err := cursor.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(docs) == 0 {
+		return 0, nil
+	}
+
+	if err := archiver.Archive(docs); err != nil {
+		return 0, fmt.Errorf("could not archive: %w", err)
+	}
+
+	if beforeDelete != nil {
+		if err := beforeDelete(ctx, docs); err != nil {
+			return 0, err
+		}
+	}
+
+	res, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, err
+	}
+
+	return res.DeletedCount, nil
+}
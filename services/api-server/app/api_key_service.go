@@ -0,0 +1,232 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	apiKeyPrefix       = "hsk_"
+	apiKeyPrefixLength = 12
+)
+
+type ApiKeyService interface {
+	Authenticate(key string) (*domain.ApiKey, error)
+	IssueKey(tenantId string, apiKeyRequest *domain.ApiKeyRequest) (*domain.IssuedApiKey, error)
+	RotateKey(tenantId, keyId string) (*domain.IssuedApiKey, error)
+	RevokeKey(tenantId, keyId string) (*domain.ApiKey, error)
+	GetKeys(tenantId string) ([]*domain.ApiKey, error)
+	BootstrapAdminKey(key string) error
+}
+
+type apiKeyService struct {
+	apiKeyRepo repository.ApiKeyRepository
+}
+
+func NewApiKeyService(apiKeyRepo repository.ApiKeyRepository) ApiKeyService {
+	return &apiKeyService{apiKeyRepo: apiKeyRepo}
+}
+
+func (aks *apiKeyService) Authenticate(key string) (*domain.ApiKey, error) {
+	if key == "" {
+		return nil, NewError(ErrUnauthorized, "missing api key")
+	}
+
+	apiKey, err := aks.apiKeyRepo.GetApiKeyByHash(hashApiKey(key))
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, NewError(ErrUnauthorized, "invalid api key")
+		}
+		return nil, NewError(ErrInternal, "could not get api key from mongo %v", err.Error())
+	}
+
+	if apiKey.Revoked {
+		return nil, NewError(ErrUnauthorized, "api key has been revoked")
+	}
+
+	return apiKey, nil
+}
+
+// IssueKey issues a key for the tenant of the request, which the caller's
+// tenantId must administer.
+func (aks *apiKeyService) IssueKey(tenantId string, apiKeyRequest *domain.ApiKeyRequest) (*domain.IssuedApiKey, error) {
+	if apiKeyRequest.TenantId == "" {
+		return nil, NewError(ErrInvalidRequest, "you must provide a tenant_id")
+	}
+
+	if err := CheckTenantAdmin(tenantId, apiKeyRequest.TenantId); err != nil {
+		return nil, err
+	}
+
+	if len(apiKeyRequest.Scopes) == 0 {
+		return nil, NewError(ErrInvalidRequest, "you must provide at least one scope")
+	}
+
+	for _, scope := range apiKeyRequest.Scopes {
+		if !isKnownScope(scope) {
+			return nil, NewError(ErrInvalidRequest, "unknown scope: %v", scope)
+		}
+	}
+
+	key, err := generateApiKey()
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey := domain.ApiKey{
+		KeyId:     uuid.New().String(),
+		TenantId:  apiKeyRequest.TenantId,
+		Name:      apiKeyRequest.Name,
+		Prefix:    key[:apiKeyPrefixLength],
+		Hash:      hashApiKey(key),
+		Scopes:    apiKeyRequest.Scopes,
+		Timestamp: time.Now().Unix(),
+	}
+
+	if err := aks.apiKeyRepo.SetApiKey(&apiKey); err != nil {
+		return nil, NewError(ErrInternal, "could not set api key to mongo %v", err.Error())
+	}
+
+	return &domain.IssuedApiKey{ApiKey: &apiKey, Key: key}, nil
+}
+
+// RotateKey replaces the secret of a key while keeping its id and scopes.
+// The previous secret stops working immediately.
+func (aks *apiKeyService) RotateKey(tenantId, keyId string) (*domain.IssuedApiKey, error) {
+	apiKey, err := aks.getKey(tenantId, keyId)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKey.Revoked {
+		return nil, NewError(ErrApiKeyRevoked, "api key %v has been revoked", keyId)
+	}
+
+	key, err := generateApiKey()
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey.Prefix = key[:apiKeyPrefixLength]
+	apiKey.Hash = hashApiKey(key)
+	apiKey.RotatedAt = time.Now().Unix()
+
+	if err := aks.apiKeyRepo.UpdateApiKeyHash(apiKey); err != nil {
+		return nil, NewError(ErrInternal, "could not update api key to mongo %v", err.Error())
+	}
+
+	return &domain.IssuedApiKey{ApiKey: apiKey, Key: key}, nil
+}
+
+func (aks *apiKeyService) RevokeKey(tenantId, keyId string) (*domain.ApiKey, error) {
+	apiKey, err := aks.getKey(tenantId, keyId)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey.Revoked = true
+
+	if err := aks.apiKeyRepo.UpdateApiKeyRevoked(apiKey); err != nil {
+		return nil, NewError(ErrInternal, "could not update api key to mongo %v", err.Error())
+	}
+
+	return apiKey, nil
+}
+
+// GetKeys returns the keys of every tenant tenantId administers.
+func (aks *apiKeyService) GetKeys(tenantId string) ([]*domain.ApiKey, error) {
+	apiKeys, err := aks.apiKeyRepo.GetApiKeys()
+	if err != nil {
+		return nil, NewError(ErrInternal, "could not get api keys from mongo %v", err.Error())
+	}
+
+	administered := []*domain.ApiKey{}
+	for _, apiKey := range apiKeys {
+		if CheckTenantAdmin(tenantId, apiKey.TenantId) == nil {
+			administered = append(administered, apiKey)
+		}
+	}
+
+	return administered, nil
+}
+
+// BootstrapAdminKey stores key as an admin key when no key exists yet, so a
+// fresh deployment has a way to issue the first real keys.
+func (aks *apiKeyService) BootstrapAdminKey(key string) error {
+	count, err := aks.apiKeyRepo.CountApiKeys()
+	if err != nil {
+		return NewError(ErrInternal, "could not count api keys in mongo %v", err.Error())
+	}
+
+	if count > 0 {
+		return nil
+	}
+
+	if len(key) < apiKeyPrefixLength {
+		return NewError(ErrInvalidRequest, "the bootstrap api key must have at least %v characters", apiKeyPrefixLength)
+	}
+
+	apiKey := domain.ApiKey{
+		KeyId:     uuid.New().String(),
+		TenantId:  domain.DefaultTenantId,
+		Name:      "bootstrap",
+		Prefix:    key[:apiKeyPrefixLength],
+		Hash:      hashApiKey(key),
+		Scopes:    []string{domain.ScopeAdmin},
+		Timestamp: time.Now().Unix(),
+	}
+
+	if err := aks.apiKeyRepo.SetApiKey(&apiKey); err != nil {
+		return NewError(ErrInternal, "could not set api key to mongo %v", err.Error())
+	}
+
+	return nil
+}
+
+// getKey returns the key keyId, as not found when tenantId does not
+// administer its tenant.
+func (aks *apiKeyService) getKey(tenantId, keyId string) (*domain.ApiKey, error) {
+	apiKey, err := aks.apiKeyRepo.GetApiKeyByKeyId(keyId)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, NewError(ErrApiKeyNotFound, "no api key with id: %v", keyId)
+		}
+		return nil, NewError(ErrInternal, "could not get api key from mongo %v", err.Error())
+	}
+
+	if CheckTenantAdmin(tenantId, apiKey.TenantId) != nil {
+		return nil, NewError(ErrApiKeyNotFound, "no api key with id: %v", keyId)
+	}
+
+	return apiKey, nil
+}
+
+func generateApiKey() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", NewError(ErrInternal, "could not generate api key: %v", err.Error())
+	}
+
+	return apiKeyPrefix + hex.EncodeToString(secret), nil
+}
+
+func hashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func isKnownScope(scope string) bool {
+	for _, s := range domain.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package app
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+)
+
+func TestIssueKeyValidatesTheRequest(t *testing.T) {
+	service := NewApiKeyService(repository.NewMemoryApiKeyRepository())
+
+	tests := []struct {
+		name    string
+		request domain.ApiKeyRequest
+		valid   bool
+	}{
+		{name: "read key", request: domain.ApiKeyRequest{TenantId: "tenant-a", Scopes: []string{domain.ScopeJobsRead}}, valid: true},
+		{name: "every scope", request: domain.ApiKeyRequest{TenantId: "tenant-a", Scopes: domain.Scopes}, valid: true},
+		{name: "missing tenant", request: domain.ApiKeyRequest{Scopes: []string{domain.ScopeJobsRead}}},
+		{name: "no scopes", request: domain.ApiKeyRequest{TenantId: "tenant-a"}},
+		{name: "unknown scope", request: domain.ApiKeyRequest{TenantId: "tenant-a", Scopes: []string{domain.ScopeJobsRead, "jobs:delete"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := test.request
+			issued, err := service.IssueKey(domain.OperatorTenantId, &request)
+
+			if !test.valid {
+				if !errors.Is(err, ErrInvalidRequest) {
+					t.Fatalf("expected an invalid request, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(issued.Key, apiKeyPrefix) || issued.ApiKey.Prefix != issued.Key[:apiKeyPrefixLength] {
+				t.Errorf("expected a %v key starting with its prefix, got %v and %v", apiKeyPrefix, issued.Key, issued.ApiKey.Prefix)
+			}
+			if issued.ApiKey.Hash == issued.Key || issued.ApiKey.Hash != hashApiKey(issued.Key) {
+				t.Errorf("expected only the hash of the key to be stored, got %v", issued.ApiKey.Hash)
+			}
+		})
+	}
+}
+
+func TestApiKeyLifecycle(t *testing.T) {
+	service := NewApiKeyService(repository.NewMemoryApiKeyRepository())
+
+	issued, err := service.IssueKey("tenant-a", &domain.ApiKeyRequest{TenantId: "tenant-a", Name: "ci", Scopes: []string{domain.ScopeJobsRead, domain.ScopeJobsWrite}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apiKey, err := service.Authenticate(issued.Key)
+	if err != nil || apiKey.KeyId != issued.ApiKey.KeyId || apiKey.TenantId != "tenant-a" {
+		t.Fatalf("expected the issued key to authenticate, got %+v: %v", apiKey, err)
+	}
+	if !apiKey.HasScope(domain.ScopeJobsWrite) || apiKey.HasScope(domain.ScopeAdmin) {
+		t.Errorf("expected the key to grant exactly its scopes, got %v", apiKey.Scopes)
+	}
+
+	for _, key := range []string{"", "hsk_unknown", strings.ToUpper(issued.Key)} {
+		if _, err := service.Authenticate(key); !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("expected %q to be unauthorized, got %v", key, err)
+		}
+	}
+
+	rotated, err := service.RotateKey("tenant-a", issued.ApiKey.KeyId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.Key == issued.Key || rotated.ApiKey.KeyId != issued.ApiKey.KeyId || rotated.ApiKey.RotatedAt == 0 {
+		t.Errorf("expected a new secret for the same key, got %+v", rotated.ApiKey)
+	}
+	if _, err := service.Authenticate(issued.Key); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected the previous secret to stop working, got %v", err)
+	}
+	if apiKey, err := service.Authenticate(rotated.Key); err != nil || !apiKey.HasScope(domain.ScopeJobsWrite) {
+		t.Errorf("expected the rotated key to keep its scopes, got %+v: %v", apiKey, err)
+	}
+
+	if _, err := service.RevokeKey("tenant-a", issued.ApiKey.KeyId); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Authenticate(rotated.Key); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected the revoked key to be unauthorized, got %v", err)
+	}
+	if _, err := service.RotateKey("tenant-a", issued.ApiKey.KeyId); !errors.Is(err, ErrApiKeyRevoked) {
+		t.Errorf("expected a revoked key not to rotate, got %v", err)
+	}
+
+	if _, err := service.RevokeKey("tenant-a", "missing"); !errors.Is(err, ErrApiKeyNotFound) {
+		t.Errorf("expected an unknown key not to be found, got %v", err)
+	}
+}
+
+func TestApiKeysAreAdministeredByTheirTenant(t *testing.T) {
+	service := NewApiKeyService(repository.NewMemoryApiKeyRepository())
+
+	issued := map[string]*domain.IssuedApiKey{}
+	for _, tenantId := range []string{"tenant-a", "tenant-b"} {
+		key, err := service.IssueKey(domain.OperatorTenantId, &domain.ApiKeyRequest{TenantId: tenantId, Scopes: []string{domain.ScopeAdmin}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		issued[tenantId] = key
+	}
+	keyOfB := issued["tenant-b"].ApiKey.KeyId
+
+	if _, err := service.IssueKey("tenant-a", &domain.ApiKeyRequest{TenantId: "tenant-b", Scopes: []string{domain.ScopeAdmin}}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected tenant-a not to issue keys of tenant-b, got %v", err)
+	}
+	if _, err := service.RotateKey("tenant-a", keyOfB); !errors.Is(err, ErrApiKeyNotFound) {
+		t.Errorf("expected tenant-a not to find the key of tenant-b to rotate, got %v", err)
+	}
+	if _, err := service.RevokeKey("tenant-a", keyOfB); !errors.Is(err, ErrApiKeyNotFound) {
+		t.Errorf("expected tenant-a not to find the key of tenant-b to revoke, got %v", err)
+	}
+	if _, err := service.Authenticate(issued["tenant-b"].Key); err != nil {
+		t.Errorf("expected the key of tenant-b to still work, got %v", err)
+	}
+
+	keys, err := service.GetKeys("tenant-a")
+	if err != nil || len(keys) != 1 || keys[0].TenantId != "tenant-a" {
+		t.Errorf("expected only the key of tenant-a, got %v: %v", keys, err)
+	}
+	if keys, _ := service.GetKeys(domain.OperatorTenantId); len(keys) != 2 {
+		t.Errorf("expected the operator to see every key, got %v", len(keys))
+	}
+	if _, err := service.RevokeKey(domain.OperatorTenantId, keyOfB); err != nil {
+		t.Errorf("expected the operator to revoke the key of tenant-b, got %v", err)
+	}
+}
+
+func TestBootstrapAdminKey(t *testing.T) {
+	service := NewApiKeyService(repository.NewMemoryApiKeyRepository())
+
+	if err := service.BootstrapAdminKey("short"); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("expected a short bootstrap key to be rejected, got %v", err)
+	}
+
+	if err := service.BootstrapAdminKey("bootstrap-secret"); err != nil {
+		t.Fatal(err)
+	}
+	apiKey, err := service.Authenticate("bootstrap-secret")
+	if err != nil || !apiKey.HasScope(domain.ScopeJobsWrite) || !apiKey.HasScope(domain.ScopeAdmin) {
+		t.Fatalf("expected an admin key granting every scope, got %+v: %v", apiKey, err)
+	}
+
+	// Once keys exist the bootstrap key is left alone
+	if err := service.BootstrapAdminKey("another-secret"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Authenticate("another-secret"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected a second bootstrap key to be ignored, got %v", err)
+	}
+}
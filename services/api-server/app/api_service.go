@@ -1,87 +1,244 @@
 package app
 
 import (
-	"fmt"
 	"time"
 
 	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
 	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
 	"github.com/google/uuid"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type ApiService interface {
-	ProcessJob(objectId string) (*domain.Job, error)
-	UpdateJob(job *domain.Job) error
-	GetJob(objectId string) (*domain.Job, error)
+	ProcessJob(jobRequest *domain.JobRequest) (*domain.Job, bool, error)
+	UpdateJob(job *domain.Job) (bool, error)
+	GetJob(tenantId, jobId string) (*domain.Job, error)
+	ListJobs(filter domain.JobFilter) ([]*domain.Job, error)
+	CancelJob(tenantId, jobId string) (*domain.Job, error)
+	DiscardJob(job *domain.Job) error
 }
 
 type apiService struct {
-	mongoRepo repository.MongoRepository
+	jobRepo       repository.JobRepository
+	tenantService TenantService
+	rerunPolicies RerunPolicies
 }
 
-func NewApiService(mongoRepo repository.MongoRepository) ApiService {
-	return &apiService{mongoRepo: mongoRepo}
+func NewApiService(jobRepo repository.JobRepository, tenantService TenantService, rerunPolicies RerunPolicies) ApiService {
+	return &apiService{jobRepo: jobRepo, tenantService: tenantService, rerunPolicies: rerunPolicies}
 }
 
-func (as *apiService) ProcessJob(objectId string) (*domain.Job, error) {
-	now := time.Now().Unix()
+// maxClaimAttempts bounds how often ProcessJob re-evaluates the rerun
+// policy after losing the race for an object to a concurrent request.
+const maxClaimAttempts = 5
+
+// claimSettleDelay is how long ProcessJob waits, per attempt, for the job of
+// a claim that is not stored yet.
+const claimSettleDelay = 20 * time.Millisecond
+
+// ProcessJob creates a job for the request unless the rerun policy of its
+// type says otherwise. The returned bool is false when the policy handed
+// back an existing job, which must not be published again.
+//
+// Creation is atomic per object: the new job first has to win the object's
+// claim, which only moves from the job the policy was checked against, and
+// is stored afterwards. A request that loses the claim to a concurrent one
+// checks the policy again against the winner, one whose job cannot be stored
+// gives the claim back.
+func (as *apiService) ProcessJob(jobRequest *domain.JobRequest) (*domain.Job, bool, error) {
+	if jobRequest.ObjectId == "" {
+		return nil, false, NewError(ErrInvalidRequest, "you must provide an object_id")
+	}
 
-	foundJob, err := as.mongoRepo.GetJobByObjectId(objectId)
-	if err != nil && err != mongo.ErrNoDocuments {
-		return nil, fmt.Errorf("error while getting document: %v", err.Error())
+	jobType := jobRequest.Type
+	if jobType == "" {
+		jobType = domain.DefaultJobType
 	}
 
-	if foundJob != nil {
-		timePassed := now - foundJob.Timestamp
+	for attempt := 0; attempt < maxClaimAttempts; attempt++ {
+		now := time.Now().Unix()
+
+		claimedJobId, foundJob, err := as.getLatestJob(jobRequest.TenantId, jobRequest.ObjectId)
+		if err != nil {
+			return nil, false, err
+		}
+
+		// The winner of the claim may not have stored its job yet. A claim
+		// whose job stays missing belongs to a purged job and is checked as
+		// if the object had none.
+		if claimedJobId != "" && foundJob == nil && attempt < maxClaimAttempts-1 {
+			time.Sleep(claimSettleDelay * time.Duration(attempt+1))
+			continue
+		}
+
+		existingJob, err := as.rerunPolicies.For(jobType).Check(foundJob, now)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if existingJob != nil {
+			return existingJob, false, nil
+		}
 
-		if timePassed < 300 {
-			return nil, fmt.Errorf("you need to wait 5 minutes before rerunning the same job")
+		if err := as.tenantService.CheckQuota(jobRequest.TenantId, 1); err != nil {
+			return nil, false, err
 		}
 
-		foundJob.JobId = uuid.New().String()
-		foundJob.Status = "processing"
-		foundJob.Timestamp = now
-		foundJob.SleepTimeUsed = 0
+		newJob := domain.Job{}
+
+		newJob.JobId = uuid.New().String()
+		newJob.TenantId = jobRequest.TenantId
+		newJob.Status = domain.JobProcessing
+		newJob.Timestamp = now
+		newJob.CreatedAt = now
+		newJob.ObjectId = jobRequest.ObjectId
+		newJob.Type = jobType
+		newJob.Params = jobRequest.Params
+		newJob.ScheduleId = jobRequest.ScheduleId
+		newJob.BatchId = jobRequest.BatchId
+		newJob.ApiKeyId = jobRequest.ApiKeyId
+		newJob.SleepTimeUsed = 0
+		newJob.PreviousJobId = claimedJobId
+
+		claimed, err := as.jobRepo.ClaimObject(jobRequest.TenantId, jobRequest.ObjectId, claimedJobId, newJob.JobId)
+		if err != nil {
+			return nil, false, NewError(ErrInternal, "could not claim object in the database: %v", err.Error())
+		}
+		if !claimed {
+			continue
+		}
 
-		if err = as.mongoRepo.SetJob(foundJob); err != nil {
-			return nil, fmt.Errorf("could not set found job to mongo %v", err.Error())
+		if err = as.jobRepo.SetJob(&newJob); err != nil {
+			if _, releaseErr := as.jobRepo.ClaimObject(jobRequest.TenantId, jobRequest.ObjectId, newJob.JobId, claimedJobId); releaseErr != nil {
+				return nil, false, NewError(ErrInternal, "could not set new job to the database: %v, nor give back the claim: %v", err.Error(), releaseErr.Error())
+			}
+			return nil, false, NewError(ErrInternal, "could not set new job to the database: %v", err.Error())
 		}
 
-		return foundJob, nil
+		return &newJob, true, nil
 	}
 
-	newJob := domain.Job{}
+	return nil, false, NewError(ErrObjectBusy, "too many concurrent jobs for object %v, try again", jobRequest.ObjectId)
+}
 
-	newJob.JobId = uuid.New().String()
-	newJob.Status = "processing"
-	newJob.Timestamp = now
-	newJob.ObjectId = objectId
-	newJob.SleepTimeUsed = 0
+// getLatestJob returns the object's claim and the job it points to. Objects
+// that were created before claims existed fall back to their newest job.
+func (as *apiService) getLatestJob(tenantId, objectId string) (string, *domain.Job, error) {
+	claimedJobId, err := as.jobRepo.GetObjectClaim(tenantId, objectId)
+	if err != nil {
+		return "", nil, NewError(ErrInternal, "could not get object claim from the database: %v", err.Error())
+	}
 
-	if err = as.mongoRepo.SetJob(&newJob); err != nil {
-		return nil, fmt.Errorf("could not set new job to mongo %v", err.Error())
+	var foundJob *domain.Job
+	if claimedJobId != "" {
+		foundJob, err = as.jobRepo.GetJobByJobId(tenantId, claimedJobId)
+	} else {
+		foundJob, err = as.jobRepo.GetJobByObjectId(tenantId, objectId)
 	}
 
-	return &newJob, nil
+	if err != nil && err != repository.ErrNotFound {
+		return "", nil, NewError(ErrInternal, "error while getting document: %v", err.Error())
+	}
+
+	return claimedJobId, foundJob, nil
 }
 
-func (as *apiService) UpdateJob(job *domain.Job) error {
-	if err := as.mongoRepo.UpdateJobStatusAndTimeSlept(job); err != nil {
-		return fmt.Errorf("could not update job to mongo %v", err.Error())
+// UpdateJob stores the status a job server reported for a processing job.
+// It reports whether the job has that status now: false once the job is no
+// longer processing, e.g. cancelled through the api meanwhile, while a
+// redelivered event finds the status it stored the first time.
+func (as *apiService) UpdateJob(job *domain.Job) (bool, error) {
+	if err := as.jobRepo.UpdateJobStatusAndTimeSlept(job); err != nil {
+		return false, NewError(ErrInternal, "could not update job to the database: %v", err.Error())
 	}
-	return nil
+
+	stored, err := as.jobRepo.GetJobByJobId(job.TenantId, job.JobId)
+	if err == repository.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, NewError(ErrInternal, "could not get job from the database: %v", err.Error())
+	}
+
+	return stored.Status == job.Status, nil
 }
 
-func (as *apiService) GetJob(jobId string) (*domain.Job, error) {
-	job, err := as.mongoRepo.GetJobByJobId(jobId)
+func (as *apiService) GetJob(tenantId, jobId string) (*domain.Job, error) {
+	job, err := as.jobRepo.GetJobByJobId(tenantId, jobId)
 
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("no job with id: %v", jobId)
+		if err == repository.ErrNotFound {
+			return nil, NewError(ErrJobNotFound, "no job with id: %v", jobId)
 		}
-		return nil, fmt.Errorf("could not get job from mongo %v", err.Error())
+		return nil, NewError(ErrInternal, "could not get job from the database: %v", err.Error())
 	}
 
 	return job, nil
 }
+
+// ListJobs returns the tenant's newest jobs first, DefaultJobListLimit of
+// them unless the filter asks for another limit up to MaxJobListLimit.
+func (as *apiService) ListJobs(filter domain.JobFilter) ([]*domain.Job, error) {
+	switch filter.Status {
+	case "", domain.JobPending, domain.JobProcessing, domain.JobFinished, domain.JobFailed, domain.JobCancelled, domain.JobSkipped:
+	default:
+		return nil, NewError(ErrInvalidRequest, "unknown status: %v", filter.Status)
+	}
+
+	if filter.Limit < 0 || filter.Limit > domain.MaxJobListLimit {
+		return nil, NewError(ErrInvalidRequest, "limit must be between 1 and %v", domain.MaxJobListLimit)
+	}
+	if filter.Limit == 0 {
+		filter.Limit = domain.DefaultJobListLimit
+	}
+
+	jobs, err := as.jobRepo.GetJobs(filter)
+	if err != nil {
+		return nil, NewError(ErrInternal, "could not get jobs from the database: %v", err.Error())
+	}
+
+	return jobs, nil
+}
+
+// CancelJob moves a pending or processing job to cancelled. A job server
+// that is running it is not interrupted, its result is ignored.
+func (as *apiService) CancelJob(tenantId, jobId string) (*domain.Job, error) {
+	job, err := as.GetJob(tenantId, jobId)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.IsTerminal() {
+		return nil, NewError(ErrJobFinished, "job %v is already %v", jobId, job.Status)
+	}
+
+	fromStatus := job.Status
+	job.Status = domain.JobCancelled
+	job.Timestamp = time.Now().Unix()
+
+	moved, err := as.jobRepo.TransitionJobStatus(job, fromStatus)
+	if err != nil {
+		return nil, NewError(ErrInternal, "could not cancel job in the database: %v", err.Error())
+	}
+
+	if !moved {
+		// The job moved on meanwhile, report where it ended up
+		return as.CancelJob(tenantId, jobId)
+	}
+
+	return job, nil
+}
+
+// DiscardJob undoes the creation of a job that was never queued: it is
+// deleted and its object's claim goes back to the previous job, so a retry
+// is checked against that one.
+func (as *apiService) DiscardJob(job *domain.Job) error {
+	if err := as.jobRepo.DeleteJob(job); err != nil {
+		return NewError(ErrInternal, "could not delete unqueued job from the database: %v", err.Error())
+	}
+
+	if _, err := as.jobRepo.ClaimObject(job.TenantId, job.ObjectId, job.JobId, job.PreviousJobId); err != nil {
+		return NewError(ErrInternal, "could not release the claim of unqueued job %v: %v", job.JobId, err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+package app
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+)
+
+const parallelSubmissions = 50
+
+type unlimitedTenantService struct{}
+
+func (unlimitedTenantService) GetTenant(tenantId string) (*domain.Tenant, error) {
+	return &domain.Tenant{TenantId: tenantId}, nil
+}
+
+func (unlimitedTenantService) SetTenant(tenant *domain.Tenant) (*domain.Tenant, error) {
+	return tenant, nil
+}
+
+func (unlimitedTenantService) CheckQuota(tenantId string, newJobs int64) error {
+	return nil
+}
+
+type submission struct {
+	job     *domain.Job
+	created bool
+	err     error
+}
+
+func submitInParallel(service ApiService, jobRequest domain.JobRequest) []submission {
+	results := make([]submission, parallelSubmissions)
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < parallelSubmissions; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			request := jobRequest
+			job, created, err := service.ProcessJob(&request)
+			results[i] = submission{job: job, created: created, err: err}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	return results
+}
+
+func TestProcessJobCreatesOneJobUnderParallelSubmissions(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewApiService(repo, unlimitedTenantService{}, RerunPolicies{domain.DefaultJobType: NewFixedCooldownPolicy(DefaultCooldown)})
+
+	jobRequest := domain.JobRequest{ObjectId: "object-1", TenantId: domain.DefaultTenantId}
+
+	created := 0
+	for _, result := range submitInParallel(service, jobRequest) {
+		if result.err == nil && result.created {
+			created++
+			continue
+		}
+
+		var rejected *RerunRejectedError
+		if !errors.As(result.err, &rejected) {
+			t.Fatalf("expected a cooldown rejection, got created=%v err=%v", result.created, result.err)
+		}
+	}
+
+	if created != 1 {
+		t.Fatalf("expected exactly 1 created job, got %v", created)
+	}
+
+	count, _ := repo.CountJobsByStatus(domain.DefaultTenantId, domain.JobProcessing)
+	if count != 1 {
+		t.Fatalf("expected exactly 1 stored job, got %v", count)
+	}
+}
+
+func TestProcessJobReturnsWinnerUnderParallelSubmissions(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewApiService(repo, unlimitedTenantService{}, RerunPolicies{domain.DefaultJobType: NewReturnExistingPolicy(DefaultCooldown)})
+
+	jobRequest := domain.JobRequest{ObjectId: "object-1", TenantId: domain.DefaultTenantId}
+
+	var winner string
+	for _, result := range submitInParallel(service, jobRequest) {
+		if result.err != nil {
+			t.Fatalf("expected no error, got %v", result.err)
+		}
+		if result.created {
+			if winner != "" {
+				t.Fatalf("expected exactly 1 created job, got %v and %v", winner, result.job.JobId)
+			}
+			winner = result.job.JobId
+		}
+	}
+
+	if winner == "" {
+		t.Fatalf("expected 1 created job, got none")
+	}
+
+	count, _ := repo.CountJobsByStatus(domain.DefaultTenantId, domain.JobProcessing)
+	if count != 1 {
+		t.Fatalf("expected exactly 1 stored job, got %v", count)
+	}
+}
+
+func TestProcessJobClaimsPerTenantAndObject(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewApiService(repo, unlimitedTenantService{}, RerunPolicies{})
+
+	requests := []domain.JobRequest{
+		{ObjectId: "object-1", TenantId: "tenant-a"},
+		{ObjectId: "object-2", TenantId: "tenant-a"},
+		{ObjectId: "object-1", TenantId: "tenant-b"},
+	}
+
+	for _, jobRequest := range requests {
+		created := 0
+		for _, result := range submitInParallel(service, jobRequest) {
+			if result.created {
+				created++
+			}
+		}
+		if created != 1 {
+			t.Fatalf("expected exactly 1 created job for %v/%v, got %v", jobRequest.TenantId, jobRequest.ObjectId, created)
+		}
+	}
+}
+
+// storeFinishedJob stores a job for objectId created at createdAt that has
+// finished, and points the object's claim at it.
+func storeFinishedJob(t *testing.T, repo repository.JobRepository, objectId string, createdAt time.Time) *domain.Job {
+	job := &domain.Job{
+		JobId:     objectId + "-finished",
+		TenantId:  domain.DefaultTenantId,
+		ObjectId:  objectId,
+		Type:      domain.DefaultJobType,
+		Status:    domain.JobFinished,
+		CreatedAt: createdAt.Unix(),
+		Timestamp: time.Now().Unix(),
+	}
+	if err := repo.SetJob(job); err != nil {
+		t.Fatal(err)
+	}
+	if claimed, err := repo.ClaimObject(domain.DefaultTenantId, objectId, "", job.JobId); err != nil || !claimed {
+		t.Fatalf("could not claim %v: %v", objectId, err)
+	}
+	return job
+}
+
+func TestProcessJobAllowsRerunAfterCooldown(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewApiService(repo, unlimitedTenantService{}, RerunPolicies{})
+
+	storeFinishedJob(t, repo, "object-1", time.Now().Add(-DefaultCooldown-time.Second))
+
+	second, created, err := service.ProcessJob(&domain.JobRequest{ObjectId: "object-1", TenantId: domain.DefaultTenantId})
+	if err != nil || !created {
+		t.Fatalf("expected a rerun to be created, got created=%v err=%v", created, err)
+	}
+
+	if claim, _ := repo.GetObjectClaim(domain.DefaultTenantId, "object-1"); claim != second.JobId {
+		t.Fatalf("expected the claim to move to %v, got %v", second.JobId, claim)
+	}
+}
+
+type failingSetJobRepository struct {
+	repository.JobRepository
+}
+
+func (failingSetJobRepository) SetJob(job *domain.Job) error {
+	return errors.New("the database is down")
+}
+
+func TestProcessJobGivesTheClaimBackWhenTheJobCannotBeStored(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	first := storeFinishedJob(t, repo, "object-1", time.Now().Add(-DefaultCooldown-time.Second))
+
+	failing := NewApiService(failingSetJobRepository{repo}, unlimitedTenantService{}, RerunPolicies{})
+	if _, _, err := failing.ProcessJob(&domain.JobRequest{ObjectId: "object-1", TenantId: domain.DefaultTenantId}); !errors.Is(err, ErrInternal) {
+		t.Fatalf("expected an internal error, got %v", err)
+	}
+
+	if claim, _ := repo.GetObjectClaim(domain.DefaultTenantId, "object-1"); claim != first.JobId {
+		t.Fatalf("expected the claim to go back to %v, got %v", first.JobId, claim)
+	}
+
+	if _, _, err := failing.ProcessJob(&domain.JobRequest{ObjectId: "object-2", TenantId: domain.DefaultTenantId}); !errors.Is(err, ErrInternal) {
+		t.Fatalf("expected an internal error, got %v", err)
+	}
+	if claim, _ := repo.GetObjectClaim(domain.DefaultTenantId, "object-2"); claim != "" {
+		t.Fatalf("expected a new object to stay unclaimed, got %v", claim)
+	}
+}
+
+func TestUpdateJobReportsWhetherTheJobTookTheStatus(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewApiService(repo, unlimitedTenantService{}, RerunPolicies{})
+
+	for _, job := range []*domain.Job{
+		{JobId: "job-1", ObjectId: "object-1", TenantId: domain.DefaultTenantId, Status: domain.JobProcessing},
+		{JobId: "job-2", ObjectId: "object-2", TenantId: domain.DefaultTenantId, Status: domain.JobCancelled},
+	} {
+		if err := repo.SetJob(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		jobId   string
+		updated bool
+	}{
+		{name: "processing", jobId: "job-1", updated: true},
+		{name: "redelivered", jobId: "job-1", updated: true},
+		{name: "cancelled meanwhile", jobId: "job-2"},
+		{name: "missing", jobId: "missing"},
+	}
+
+	for _, test := range tests {
+		updated, err := service.UpdateJob(&domain.Job{JobId: test.jobId, TenantId: domain.DefaultTenantId, Status: domain.JobFinished})
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		if updated != test.updated {
+			t.Errorf("%v: expected updated %v, got %v", test.name, test.updated, updated)
+		}
+	}
+
+	if job, _ := repo.GetJobByJobId(domain.DefaultTenantId, "job-2"); job.Status != domain.JobCancelled {
+		t.Errorf("expected the cancelled job to stay cancelled, got %v", job.Status)
+	}
+}
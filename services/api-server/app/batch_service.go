@@ -0,0 +1,220 @@
+package app
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/events"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/events/publishers"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// batchWorkers bounds how many items of a batch are created and published
+// at the same time.
+const batchWorkers = 16
+
+type BatchService interface {
+	SubmitBatch(batchRequest *domain.BatchRequest) (*domain.Batch, error)
+	GetBatch(tenantId, batchId string) (*domain.Batch, error)
+	CancelBatch(tenantId, batchId string) (*domain.Batch, error)
+}
+
+type batchService struct {
+	apiService         ApiService
+	jobRepo            repository.JobRepository
+	batchRepo          repository.BatchRepository
+	jobEventPublisher  publishers.JobEventPublisher
+	cancelledPublisher publishers.JobEventPublisher
+}
+
+// NewBatchService publishes the jobs it creates with jobEventPublisher and
+// the jobs it cancels with cancelledPublisher.
+func NewBatchService(apiService ApiService, jobRepo repository.JobRepository, batchRepo repository.BatchRepository, jobEventPublisher, cancelledPublisher publishers.JobEventPublisher) BatchService {
+	return &batchService{
+		apiService:         apiService,
+		jobRepo:            jobRepo,
+		batchRepo:          batchRepo,
+		jobEventPublisher:  jobEventPublisher,
+		cancelledPublisher: cancelledPublisher,
+	}
+}
+
+// SubmitBatch creates one job per object id. Items that cannot be created
+// (e.g. the object ran in the last 5 minutes) are reported back instead of
+// failing the whole batch. The batch is stored first, as submitting, so
+// every job created points to an existing batch, and becomes active with
+// its totals once every item is done.
+func (bs *batchService) SubmitBatch(batchRequest *domain.BatchRequest) (*domain.Batch, error) {
+	if len(batchRequest.ObjectIds) == 0 {
+		return nil, NewError(ErrInvalidRequest, "you must provide at least one object_id")
+	}
+
+	if len(batchRequest.ObjectIds) > domain.MaxBatchSize {
+		return nil, NewError(ErrInvalidRequest, "a batch can contain at most %v object_ids", domain.MaxBatchSize)
+	}
+
+	batch := domain.Batch{
+		BatchId:   uuid.New().String(),
+		TenantId:  batchRequest.TenantId,
+		Status:    domain.BatchSubmitting,
+		Timestamp: time.Now().Unix(),
+	}
+
+	if err := bs.batchRepo.SetBatch(&batch); err != nil {
+		return nil, NewError(ErrInternal, "could not set batch to mongo %v", err.Error())
+	}
+
+	itemErrors := make([]error, len(batchRequest.ObjectIds))
+	seen := map[string]bool{}
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < batchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				itemErrors[index] = bs.submitItem(batch.BatchId, batchRequest.ObjectIds[index], batchRequest)
+			}
+		}()
+	}
+
+	for index, objectId := range batchRequest.ObjectIds {
+		switch {
+		case objectId == "":
+			itemErrors[index] = NewError(ErrInvalidRequest, "you must provide an object_id")
+		case seen[objectId]:
+			itemErrors[index] = NewError(ErrInvalidRequest, "duplicate object_id in batch")
+		default:
+			seen[objectId] = true
+			indexes <- index
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	for index, err := range itemErrors {
+		if err != nil {
+			batch.Errors = append(batch.Errors, domain.BatchItemError{ObjectId: batchRequest.ObjectIds[index], Code: errorCode(err), Message: err.Error()})
+			continue
+		}
+		batch.Total++
+	}
+	batch.Rejected = len(batch.Errors)
+	batch.Status = domain.BatchActive
+
+	if err := bs.batchRepo.UpdateBatchTotals(&batch); err != nil {
+		return nil, NewError(ErrInternal, "could not update batch %v to mongo %v", batch.BatchId, err.Error())
+	}
+
+	return &batch, nil
+}
+
+func (bs *batchService) submitItem(batchId, objectId string, batchRequest *domain.BatchRequest) error {
+	job, created, err := bs.apiService.ProcessJob(&domain.JobRequest{
+		ObjectId: objectId,
+		Type:     batchRequest.Type,
+		Params:   batchRequest.Params,
+		BatchId:  batchId,
+		ApiKeyId: batchRequest.ApiKeyId,
+		TenantId: batchRequest.TenantId,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The existing job is not part of this batch, so it is reported back
+	// rather than counted.
+	if !created {
+		return NewError(ErrInvalidRequest, "job %v for the same object is reused", job.JobId)
+	}
+
+	eventJob := events.NewJobEvent(contracts.SubjectJobCreated, job)
+
+	if err := bs.jobEventPublisher.PublishData(eventJob); err != nil {
+		// Like a single job, it is undone so the object can be submitted again
+		if err := bs.apiService.DiscardJob(job); err != nil {
+			log.Printf("could not discard unqueued job %v of batch %v: %v\n", job.JobId, batchId, err.Error())
+		}
+
+		return NewError(ErrPublishFailed, "the job could not be queued, try again later: %v", err.Error())
+	}
+
+	return nil
+}
+
+func (bs *batchService) GetBatch(tenantId, batchId string) (*domain.Batch, error) {
+	batch, err := bs.batchRepo.GetBatchByBatchId(tenantId, batchId)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, NewError(ErrBatchNotFound, "no batch with id: %v", batchId)
+		}
+		return nil, NewError(ErrInternal, "could not get batch from mongo %v", err.Error())
+	}
+
+	counts, err := bs.jobRepo.GetJobStatusCountsByBatchId(tenantId, batchId)
+	if err != nil {
+		return nil, NewError(ErrInternal, "could not get batch jobs from mongo %v", err.Error())
+	}
+
+	terminal := counts[domain.JobFinished] + counts[domain.JobFailed] + counts[domain.JobCancelled] + counts[domain.JobSkipped]
+
+	batch.Counts = counts
+	batch.Completion = 100
+	if batch.Status == domain.BatchSubmitting {
+		batch.Completion = 0
+	} else if batch.Total > 0 {
+		batch.Completion = float64(terminal) * 100 / float64(batch.Total)
+	}
+
+	if batch.Status == domain.BatchActive && terminal == batch.Total {
+		batch.Status = domain.BatchCompleted
+	}
+
+	return batch, nil
+}
+
+// CancelBatch marks every job of the batch that has not finished yet as
+// cancelled and publishes a job:cancelled event for each, like a single
+// cancellation. Results the job server reports for them afterwards are
+// ignored.
+func (bs *batchService) CancelBatch(tenantId, batchId string) (*domain.Batch, error) {
+	batch, err := bs.GetBatch(tenantId, batchId)
+	if err != nil {
+		return nil, err
+	}
+
+	switch batch.Status {
+	case domain.BatchActive:
+	case domain.BatchSubmitting:
+		return nil, NewError(ErrBatchFinished, "batch %v is still submitting, cancel it once it is active", batchId)
+	default:
+		return nil, NewError(ErrBatchFinished, "batch %v is already %v", batchId, batch.Status)
+	}
+
+	cancelled, err := bs.jobRepo.CancelJobsByBatchId(tenantId, batchId, time.Now().Unix())
+	if err != nil {
+		return nil, NewError(ErrInternal, "could not cancel batch jobs in mongo %v", err.Error())
+	}
+
+	// The jobs are cancelled either way, only their workflows wait for the
+	// events
+	for _, job := range cancelled {
+		if err := bs.cancelledPublisher.PublishData(events.NewJobEvent(contracts.SubjectJobCancelled, job)); err != nil {
+			log.Printf("could not publish cancellation of job %v of batch %v: %v\n", job.JobId, batchId, err.Error())
+		}
+	}
+
+	batch.Status = domain.BatchCancelled
+
+	if err := bs.batchRepo.UpdateBatchStatus(batch); err != nil {
+		return nil, NewError(ErrInternal, "could not update batch to mongo %v", err.Error())
+	}
+
+	return bs.GetBatch(tenantId, batchId)
+}
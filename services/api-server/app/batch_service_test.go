@@ -0,0 +1,169 @@
+package app
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/events/publishers"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+)
+
+type recordingPublisher struct {
+	mu     sync.Mutex
+	err    error
+	events []*contracts.JobEvent
+}
+
+func (p *recordingPublisher) PublishData(jobEvent *contracts.JobEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+	p.events = append(p.events, jobEvent)
+	return nil
+}
+
+func newTestBatchService(publisher, cancelledPublisher publishers.JobEventPublisher) (BatchService, repository.JobRepository) {
+	repo := repository.NewMemoryRepository()
+	service := NewApiService(repo, unlimitedTenantService{}, RerunPolicies{domain.DefaultJobType: NewFixedCooldownPolicy(DefaultCooldown)})
+
+	return NewBatchService(service, repo, repository.NewMemoryBatchRepository(), publisher, cancelledPublisher), repo
+}
+
+func TestSubmitBatchDiscardsItemsThatCannotBeQueued(t *testing.T) {
+	publisher := &recordingPublisher{err: errors.New("the publish buffer is full")}
+	service, repo := newTestBatchService(publisher, &recordingPublisher{})
+
+	batchRequest := &domain.BatchRequest{ObjectIds: []string{"object-1", "object-2"}, TenantId: domain.DefaultTenantId}
+
+	batch, err := service.SubmitBatch(batchRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Total != 0 || batch.Rejected != 2 {
+		t.Fatalf("expected every item to be rejected, got %v total and %v rejected", batch.Total, batch.Rejected)
+	}
+	for _, itemErr := range batch.Errors {
+		if itemErr.Code != ErrPublishFailed.Error() {
+			t.Errorf("expected %v to be rejected with publish_failed, got %+v", itemErr.ObjectId, itemErr)
+		}
+	}
+	if jobs, _ := repo.GetJobs(domain.JobFilter{TenantId: domain.DefaultTenantId, Limit: 10}); len(jobs) != 0 {
+		t.Errorf("expected the unqueued jobs to be deleted, got %v", len(jobs))
+	}
+
+	publisher.err = nil
+
+	batch, err = service.SubmitBatch(batchRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Total != 2 || batch.Rejected != 0 || len(publisher.events) != 2 {
+		t.Errorf("expected the resubmitted items to be queued, got %v total, %v rejected: %+v", batch.Total, batch.Rejected, batch.Errors)
+	}
+}
+
+// inspectingPublisher hands every event it publishes to inspect.
+type inspectingPublisher func(jobEvent *contracts.JobEvent)
+
+func (p inspectingPublisher) PublishData(jobEvent *contracts.JobEvent) error {
+	p(jobEvent)
+	return nil
+}
+
+func TestSubmitBatchStoresTheBatchBeforeItsJobs(t *testing.T) {
+	var service BatchService
+	mu := sync.Mutex{}
+	seen := []string{}
+
+	service, _ = newTestBatchService(inspectingPublisher(func(jobEvent *contracts.JobEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		batch, err := service.GetBatch(domain.DefaultTenantId, jobEvent.Job.BatchId)
+		if err != nil {
+			seen = append(seen, err.Error())
+			return
+		}
+		seen = append(seen, batch.Status)
+	}), &recordingPublisher{})
+
+	batch, err := service.SubmitBatch(&domain.BatchRequest{ObjectIds: []string{"object-1", "object-2", ""}, TenantId: domain.DefaultTenantId})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(seen) != 2 || seen[0] != domain.BatchSubmitting || seen[1] != domain.BatchSubmitting {
+		t.Errorf("expected the batch to be submitting while its jobs are published, got %v", seen)
+	}
+	if len(batch.Errors) != 1 || batch.Errors[0].Code != ErrInvalidRequest.Error() {
+		t.Errorf("expected the empty object id to be rejected with invalid_request, got %+v", batch.Errors)
+	}
+
+	stored, err := service.GetBatch(domain.DefaultTenantId, batch.BatchId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != domain.BatchActive || stored.Total != 2 || stored.Rejected != 1 {
+		t.Errorf("expected an active batch of 2 jobs and 1 rejected, got %v with %v and %v", stored.Status, stored.Total, stored.Rejected)
+	}
+}
+
+func TestCancelBatchPublishesEveryCancelledJob(t *testing.T) {
+	cancelledPublisher := &recordingPublisher{}
+	service, repo := newTestBatchService(&recordingPublisher{}, cancelledPublisher)
+
+	batch, err := service.SubmitBatch(&domain.BatchRequest{ObjectIds: []string{"object-1", "object-2", "object-3"}, TenantId: domain.DefaultTenantId})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	finished, _ := repo.GetJobByObjectId(domain.DefaultTenantId, "object-1")
+	finished.Status = domain.JobFinished
+	if ok, _ := repo.TransitionJobStatus(finished, domain.JobProcessing); !ok {
+		t.Fatalf("could not finish the job of object-1")
+	}
+
+	if _, err := service.CancelBatch(domain.DefaultTenantId, batch.BatchId); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cancelledPublisher.events) != 2 {
+		t.Fatalf("expected 2 cancelled jobs to be published, got %v", len(cancelledPublisher.events))
+	}
+	for _, event := range cancelledPublisher.events {
+		if event.Subject != contracts.SubjectJobCancelled || event.Job.Status != domain.JobCancelled || event.Job.ObjectId == "object-1" {
+			t.Errorf("expected a cancelled job of the batch, got %+v", event)
+		}
+	}
+}
+
+func TestGetBatchCompletesOnceEveryJobEnded(t *testing.T) {
+	service, repo := newTestBatchService(&recordingPublisher{}, &recordingPublisher{})
+
+	batch, err := service.SubmitBatch(&domain.BatchRequest{ObjectIds: []string{"object-1", "object-2"}, TenantId: domain.DefaultTenantId})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for objectId, status := range map[string]string{"object-1": domain.JobFinished, "object-2": domain.JobFailed} {
+		job, _ := repo.GetJobByObjectId(domain.DefaultTenantId, objectId)
+		job.Status = status
+		if ok, _ := repo.TransitionJobStatus(job, domain.JobProcessing); !ok {
+			t.Fatalf("could not end the job of %v", objectId)
+		}
+	}
+
+	batch, err = service.GetBatch(domain.DefaultTenantId, batch.BatchId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Status != domain.BatchCompleted || batch.Completion != 100 || batch.Counts[domain.JobFailed] != 1 {
+		t.Errorf("expected a completed batch counting the failed job, got %v at %v%%: %v", batch.Status, batch.Completion, batch.Counts)
+	}
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors returned (wrapped in *Error) by the services. Their text
+// is the stable error code reported to clients.
+var (
+	ErrInvalidRequest      = errors.New("invalid_request")
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrForbidden           = errors.New("forbidden")
+	ErrJobNotFound         = errors.New("job_not_found")
+	ErrCooldownActive      = errors.New("cooldown_active")
+	ErrJobInProgress       = errors.New("job_in_progress")
+	ErrJobFinished         = errors.New("job_finished")
+	ErrObjectBusy          = errors.New("object_busy")
+	ErrQuotaExceeded       = errors.New("quota_exceeded")
+	ErrPublishFailed       = errors.New("publish_failed")
+	ErrResultNotFound      = errors.New("result_not_found")
+	ErrJobFailed           = errors.New("job_failed")
+	ErrScheduleNotFound    = errors.New("schedule_not_found")
+	ErrWorkflowNotFound    = errors.New("workflow_not_found")
+	ErrBatchNotFound       = errors.New("batch_not_found")
+	ErrBatchFinished       = errors.New("batch_finished")
+	ErrApiKeyNotFound      = errors.New("api_key_not_found")
+	ErrApiKeyRevoked       = errors.New("api_key_revoked")
+	ErrWorkerNotFound      = errors.New("worker_not_found")
+	ErrMessageNotFound     = errors.New("message_not_found")
+	ErrDatabaseUnavailable = errors.New("database_unavailable")
+	ErrInternal            = errors.New("internal_error")
+)
+
+// Error carries a human readable message for one of the sentinel errors,
+// so callers can match it with errors.Is.
+type Error struct {
+	Kind    error
+	Message string
+}
+
+func NewError(kind error, format string, args ...interface{}) error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// errorCode is the stable code of err, internal_error unless it is an *Error.
+func errorCode(err error) string {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Kind.Error()
+	}
+	return ErrInternal.Error()
+}
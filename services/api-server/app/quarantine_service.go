@@ -0,0 +1,74 @@
+package app
+
+import (
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type QuarantineService interface {
+	Quarantine(subject string, sequence uint64, data []byte, reason error) (*domain.QuarantinedMessage, error)
+	GetMessages() ([]*domain.QuarantinedMessage, error)
+	GetMessage(messageId string) (*domain.QuarantinedMessage, error)
+	DeleteMessage(messageId string) error
+}
+
+type quarantineService struct {
+	quarantineRepo repository.QuarantineRepository
+}
+
+func NewQuarantineService(quarantineRepo repository.QuarantineRepository) QuarantineService {
+	return &quarantineService{quarantineRepo: quarantineRepo}
+}
+
+func (qs *quarantineService) Quarantine(subject string, sequence uint64, data []byte, reason error) (*domain.QuarantinedMessage, error) {
+	message := domain.QuarantinedMessage{
+		MessageId: uuid.New().String(),
+		Subject:   subject,
+		Sequence:  sequence,
+		Data:      data,
+		Error:     reason.Error(),
+		Timestamp: time.Now().Unix(),
+	}
+
+	if err := qs.quarantineRepo.SetMessage(&message); err != nil {
+		return nil, NewError(ErrInternal, "could not set quarantined message to mongo %v", err.Error())
+	}
+
+	return &message, nil
+}
+
+func (qs *quarantineService) GetMessages() ([]*domain.QuarantinedMessage, error) {
+	messages, err := qs.quarantineRepo.GetMessages()
+	if err != nil {
+		return nil, NewError(ErrInternal, "could not get quarantined messages from mongo %v", err.Error())
+	}
+
+	return messages, nil
+}
+
+func (qs *quarantineService) GetMessage(messageId string) (*domain.QuarantinedMessage, error) {
+	message, err := qs.quarantineRepo.GetMessageByMessageId(messageId)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, NewError(ErrMessageNotFound, "no quarantined message with id: %v", messageId)
+		}
+		return nil, NewError(ErrInternal, "could not get quarantined message from mongo %v", err.Error())
+	}
+
+	return message, nil
+}
+
+func (qs *quarantineService) DeleteMessage(messageId string) error {
+	if err := qs.quarantineRepo.DeleteMessage(messageId); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return NewError(ErrMessageNotFound, "no quarantined message with id: %v", messageId)
+		}
+		return NewError(ErrInternal, "could not delete quarantined message from mongo %v", err.Error())
+	}
+
+	return nil
+}
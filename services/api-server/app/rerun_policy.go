@@ -0,0 +1,234 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+)
+
+const DefaultCooldown = 5 * time.Minute
+
+// RerunPolicy decides whether a new job may be created for an object whose
+// most recent job is latest (nil when the object never ran). It either
+// allows the job, returns an existing job to hand back instead, or rejects
+// the request with a *RerunRejectedError.
+type RerunPolicy interface {
+	Name() string
+	Check(latest *domain.Job, now int64) (*domain.Job, error)
+}
+
+type RerunRejectedError struct {
+	Policy string
+	// RetryAfter is the unix time from which a rerun is allowed, or 0 when
+	// it depends on the running job finishing.
+	RetryAfter int64
+	Reason     string
+}
+
+func (e *RerunRejectedError) Error() string {
+	return e.Reason
+}
+
+// Unwrap reports a rejection that ends at a known time as ErrCooldownActive
+// and one that waits for the running job as ErrJobInProgress.
+func (e *RerunRejectedError) Unwrap() error {
+	if e.RetryAfter > 0 {
+		return ErrCooldownActive
+	}
+	return ErrJobInProgress
+}
+
+// RerunPolicies maps job types to their policy. Types without an entry use
+// the policy of domain.DefaultJobType.
+type RerunPolicies map[string]RerunPolicy
+
+func (rp RerunPolicies) For(jobType string) RerunPolicy {
+	if policy, ok := rp[jobType]; ok {
+		return policy
+	}
+	if policy, ok := rp[domain.DefaultJobType]; ok {
+		return policy
+	}
+	return NewFixedCooldownPolicy(DefaultCooldown)
+}
+
+// ParseRerunPolicies reads a comma separated list of type=policy pairs,
+// e.g. "default=cooldown:5m,thumbnail=in_progress,report=existing:10m,ping=always".
+func ParseRerunPolicies(spec string) (RerunPolicies, error) {
+	policies := RerunPolicies{}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid rerun policy %q, expected type=policy", entry)
+		}
+
+		policy, err := parseRerunPolicy(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rerun policy for type %v: %v", parts[0], err.Error())
+		}
+		policies[parts[0]] = policy
+	}
+
+	return policies, nil
+}
+
+func parseRerunPolicy(spec string) (RerunPolicy, error) {
+	name, arg := spec, ""
+	if i := strings.Index(spec, ":"); i >= 0 {
+		name, arg = spec[:i], spec[i+1:]
+	}
+
+	cooldown := DefaultCooldown
+	if arg != "" {
+		parsed, err := time.ParseDuration(arg)
+		if err != nil {
+			return nil, err
+		}
+		cooldown = parsed
+	}
+
+	switch name {
+	case "cooldown":
+		return NewFixedCooldownPolicy(cooldown), nil
+	case "in_progress":
+		return NewInProgressPolicy(), nil
+	case "existing":
+		return NewReturnExistingPolicy(cooldown), nil
+	case "always":
+		return NewAlwaysAllowPolicy(), nil
+	}
+
+	return nil, fmt.Errorf("unknown policy %q", name)
+}
+
+type fixedCooldownPolicy struct {
+	cooldown time.Duration
+}
+
+// NewFixedCooldownPolicy rejects reruns while the latest job is pending or
+// processing, and then until cooldown has passed since it was created.
+func NewFixedCooldownPolicy(cooldown time.Duration) RerunPolicy {
+	return &fixedCooldownPolicy{cooldown: cooldown}
+}
+
+func (p *fixedCooldownPolicy) Name() string {
+	return "cooldown"
+}
+
+func (p *fixedCooldownPolicy) Check(latest *domain.Job, now int64) (*domain.Job, error) {
+	if latest == nil {
+		return nil, nil
+	}
+
+	if isInProgress(latest) {
+		return nil, &RerunRejectedError{
+			Policy: p.Name(),
+			Reason: fmt.Sprintf("job %v for the same object is still %v", latest.JobId, latest.Status),
+		}
+	}
+
+	allowedAt := createdAt(latest) + int64(p.cooldown.Seconds())
+	if now < allowedAt {
+		return nil, &RerunRejectedError{
+			Policy:     p.Name(),
+			RetryAfter: allowedAt,
+			Reason:     fmt.Sprintf("you need to wait %v before rerunning the same job", formatCooldown(p.cooldown)),
+		}
+	}
+
+	return nil, nil
+}
+
+type inProgressPolicy struct{}
+
+// NewInProgressPolicy only rejects reruns while the latest job has not
+// reached a final status.
+func NewInProgressPolicy() RerunPolicy {
+	return &inProgressPolicy{}
+}
+
+func (p *inProgressPolicy) Name() string {
+	return "in_progress"
+}
+
+func (p *inProgressPolicy) Check(latest *domain.Job, now int64) (*domain.Job, error) {
+	if latest == nil || !isInProgress(latest) {
+		return nil, nil
+	}
+
+	return nil, &RerunRejectedError{
+		Policy: p.Name(),
+		Reason: fmt.Sprintf("job %v for the same object is still %v", latest.JobId, latest.Status),
+	}
+}
+
+type returnExistingPolicy struct {
+	cooldown time.Duration
+}
+
+// NewReturnExistingPolicy hands back the latest job instead of creating a
+// new one while it is still running or younger than cooldown.
+func NewReturnExistingPolicy(cooldown time.Duration) RerunPolicy {
+	return &returnExistingPolicy{cooldown: cooldown}
+}
+
+func (p *returnExistingPolicy) Name() string {
+	return "existing"
+}
+
+func (p *returnExistingPolicy) Check(latest *domain.Job, now int64) (*domain.Job, error) {
+	if latest == nil {
+		return nil, nil
+	}
+
+	if isInProgress(latest) || now < createdAt(latest)+int64(p.cooldown.Seconds()) {
+		return latest, nil
+	}
+
+	return nil, nil
+}
+
+type alwaysAllowPolicy struct{}
+
+func NewAlwaysAllowPolicy() RerunPolicy {
+	return &alwaysAllowPolicy{}
+}
+
+func (p *alwaysAllowPolicy) Name() string {
+	return "always"
+}
+
+func (p *alwaysAllowPolicy) Check(latest *domain.Job, now int64) (*domain.Job, error) {
+	return nil, nil
+}
+
+func isInProgress(job *domain.Job) bool {
+	return job.Status == domain.JobPending || job.Status == domain.JobProcessing
+}
+
+// createdAt is when job was created, jobs stored before CreatedAt existed
+// only have their last status change.
+func createdAt(job *domain.Job) int64 {
+	if job.CreatedAt == 0 {
+		return job.Timestamp
+	}
+	return job.CreatedAt
+}
+
+func formatCooldown(cooldown time.Duration) string {
+	if cooldown%time.Minute == 0 {
+		if cooldown == time.Minute {
+			return "1 minute"
+		}
+		return fmt.Sprintf("%d minutes", cooldown/time.Minute)
+	}
+	return cooldown.String()
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+)
+
+func TestRerunPolicies(t *testing.T) {
+	const now = int64(1_000_000)
+	cooldown := 5 * time.Minute
+	recent := now - 60
+	old := now - int64(cooldown.Seconds()) - 1
+
+	job := func(status string, createdAt int64) *domain.Job {
+		// The last status change is always recent, only the creation counts
+		return &domain.Job{JobId: "job-1", Status: status, CreatedAt: createdAt, Timestamp: now}
+	}
+
+	tests := []struct {
+		name       string
+		policy     RerunPolicy
+		latest     *domain.Job
+		existing   bool
+		err        error
+		retryAfter int64
+	}{
+		{name: "cooldown allows a new object", policy: NewFixedCooldownPolicy(cooldown)},
+		{name: "cooldown rejects a pending job", policy: NewFixedCooldownPolicy(cooldown), latest: job(domain.JobPending, old), err: ErrJobInProgress},
+		{name: "cooldown rejects a processing job past the cooldown", policy: NewFixedCooldownPolicy(cooldown), latest: job(domain.JobProcessing, old), err: ErrJobInProgress},
+		{name: "cooldown rejects a recent finished job", policy: NewFixedCooldownPolicy(cooldown), latest: job(domain.JobFinished, recent), err: ErrCooldownActive, retryAfter: recent + int64(cooldown.Seconds())},
+		{name: "cooldown rejects a recent cancelled job", policy: NewFixedCooldownPolicy(cooldown), latest: job(domain.JobCancelled, recent), err: ErrCooldownActive, retryAfter: recent + int64(cooldown.Seconds())},
+		{name: "cooldown rejects a recent skipped job", policy: NewFixedCooldownPolicy(cooldown), latest: job(domain.JobSkipped, recent), err: ErrCooldownActive, retryAfter: recent + int64(cooldown.Seconds())},
+		{name: "cooldown allows an old finished job", policy: NewFixedCooldownPolicy(cooldown), latest: job(domain.JobFinished, old)},
+		{name: "cooldown allows an old cancelled job", policy: NewFixedCooldownPolicy(cooldown), latest: job(domain.JobCancelled, old)},
+		{name: "cooldown falls back to the timestamp", policy: NewFixedCooldownPolicy(cooldown), latest: &domain.Job{Status: domain.JobFinished, Timestamp: old}},
+		{name: "in progress rejects a processing job", policy: NewInProgressPolicy(), latest: job(domain.JobProcessing, old), err: ErrJobInProgress},
+		{name: "in progress allows a recent finished job", policy: NewInProgressPolicy(), latest: job(domain.JobFinished, recent)},
+		{name: "existing returns a processing job", policy: NewReturnExistingPolicy(cooldown), latest: job(domain.JobProcessing, old), existing: true},
+		{name: "existing returns a recent finished job", policy: NewReturnExistingPolicy(cooldown), latest: job(domain.JobFinished, recent), existing: true},
+		{name: "existing allows an old finished job", policy: NewReturnExistingPolicy(cooldown), latest: job(domain.JobFinished, old)},
+		{name: "always allows a processing job", policy: NewAlwaysAllowPolicy(), latest: job(domain.JobProcessing, recent)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			existing, err := test.policy.Check(test.latest, now)
+
+			if test.err == nil && err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if test.err != nil {
+				var rejected *RerunRejectedError
+				if !errors.Is(err, test.err) || !errors.As(err, &rejected) {
+					t.Fatalf("expected %v, got %v", test.err, err)
+				}
+				if rejected.Policy != test.policy.Name() || rejected.RetryAfter != test.retryAfter {
+					t.Errorf("expected policy %v retrying after %v, got %v after %v", test.policy.Name(), test.retryAfter, rejected.Policy, rejected.RetryAfter)
+				}
+			}
+
+			if (existing != nil) != test.existing {
+				t.Errorf("expected an existing job %v, got %v", test.existing, existing)
+			}
+		})
+	}
+}
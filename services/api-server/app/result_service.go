@@ -0,0 +1,107 @@
+package app
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+)
+
+type ResultService interface {
+	SaveResult(result *domain.JobResult) error
+	DiscardResult(result *domain.JobResult) error
+	GetResult(tenantId, jobId string) (*domain.JobResult, io.ReadCloser, error)
+}
+
+// JobFailedError is the result of a job whose executor returned an error.
+type JobFailedError struct {
+	JobId    string
+	JobError domain.JobError
+}
+
+func (e *JobFailedError) Error() string {
+	return fmt.Sprintf("job %v failed with %v: %v", e.JobId, e.JobError.Code, e.JobError.Message)
+}
+
+func (e *JobFailedError) Unwrap() error {
+	return ErrJobFailed
+}
+
+type resultService struct {
+	resultRepo repository.ResultRepository
+	jobRepo    repository.JobRepository
+}
+
+func NewResultService(resultRepo repository.ResultRepository, jobRepo repository.JobRepository) ResultService {
+	return &resultService{resultRepo: resultRepo, jobRepo: jobRepo}
+}
+
+// SaveResult keeps the result of a finished or failed job. Results of jobs
+// that were cancelled meanwhile, or no longer exist, are dropped like their status,
+// along with data the job server wrote to GridFS.
+func (rs *resultService) SaveResult(result *domain.JobResult) error {
+	job, err := rs.jobRepo.GetJobByJobId(result.TenantId, result.JobId)
+	if err != nil && err != repository.ErrNotFound {
+		return NewError(ErrInternal, "could not get job from the database: %v", err.Error())
+	}
+
+	if err == repository.ErrNotFound || !hasResult(job) {
+		return rs.DiscardResult(result)
+	}
+
+	if err := rs.resultRepo.SetResult(result); err != nil {
+		return NewError(ErrInternal, "could not store the result of job %v: %v", result.JobId, err.Error())
+	}
+
+	return nil
+}
+
+// DiscardResult drops a result that is not kept, deleting the data the job
+// server wrote to GridFS for it.
+func (rs *resultService) DiscardResult(result *domain.JobResult) error {
+	if err := rs.resultRepo.DeleteResultData(result); err != nil {
+		return NewError(ErrInternal, "could not delete the result data of job %v: %v", result.JobId, err.Error())
+	}
+	return nil
+}
+
+// GetResult returns the result and its data, which the caller must close.
+// A failed job returns a *JobFailedError instead.
+func (rs *resultService) GetResult(tenantId, jobId string) (*domain.JobResult, io.ReadCloser, error) {
+	job, err := rs.jobRepo.GetJobByJobId(tenantId, jobId)
+	if err == repository.ErrNotFound {
+		return nil, nil, NewError(ErrJobNotFound, "no job with id: %v", jobId)
+	}
+	if err != nil {
+		return nil, nil, NewError(ErrInternal, "could not get job from the database: %v", err.Error())
+	}
+
+	result, err := rs.resultRepo.GetResult(tenantId, jobId)
+	if err == repository.ErrNotFound {
+		if !hasResult(job) {
+			return nil, nil, NewError(ErrResultNotFound, "job %v is %v, only finished and failed jobs have a result", jobId, job.Status)
+		}
+		return nil, nil, NewError(ErrResultNotFound, "job %v has no result", jobId)
+	}
+	if err != nil {
+		return nil, nil, NewError(ErrInternal, "could not get the result from the database: %v", err.Error())
+	}
+
+	if result.Error != nil {
+		return nil, nil, &JobFailedError{JobId: jobId, JobError: *result.Error}
+	}
+
+	data, err := rs.resultRepo.OpenResultData(result)
+	if err != nil {
+		return nil, nil, NewError(ErrInternal, "could not read the result of job %v: %v", jobId, err.Error())
+	}
+
+	return result, data, nil
+}
+
+// hasResult reports whether the executor of job ran to the end, leaving a
+// result or the error it failed with.
+func hasResult(job *domain.Job) bool {
+	return job.Status == domain.JobFinished || job.Status == domain.JobFailed
+}
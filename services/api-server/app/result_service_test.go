@@ -0,0 +1,71 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+)
+
+func TestSaveResultKeepsOnlyResultsOfFinishedJobs(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewResultService(repository.NewMemoryResultRepository(), repo)
+
+	for _, job := range []*domain.Job{
+		{JobId: "job-1", ObjectId: "object-1", TenantId: domain.DefaultTenantId, Status: domain.JobFinished},
+		{JobId: "job-2", ObjectId: "object-2", TenantId: domain.DefaultTenantId, Status: domain.JobCancelled},
+	} {
+		if err := repo.SetJob(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, jobId := range []string{"job-1", "job-2", "missing"} {
+		result := &domain.JobResult{JobId: jobId, TenantId: domain.DefaultTenantId, Data: []byte(`{"pages": 3}`)}
+		if err := service.SaveResult(result); err != nil {
+			t.Fatalf("%v: %v", jobId, err)
+		}
+	}
+
+	_, data, err := service.GetResult(domain.DefaultTenantId, "job-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+	if document, _ := io.ReadAll(data); string(document) != `{"pages": 3}` {
+		t.Errorf("unexpected result %q", document)
+	}
+
+	if _, _, err := service.GetResult(domain.DefaultTenantId, "job-2"); !errors.Is(err, ErrResultNotFound) {
+		t.Errorf("expected no result for the cancelled job, got %v", err)
+	}
+	if _, _, err := service.GetResult(domain.DefaultTenantId, "missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected job_not_found, got %v", err)
+	}
+}
+
+func TestGetResultOfFailedJob(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewResultService(repository.NewMemoryResultRepository(), repo)
+
+	if err := repo.SetJob(&domain.Job{JobId: "job-1", ObjectId: "object-1", TenantId: domain.DefaultTenantId, Status: domain.JobFailed}); err != nil {
+		t.Fatal(err)
+	}
+
+	jobError := &domain.JobError{Code: "corrupt", Message: "the object is corrupt", Details: map[string]string{"page": "2"}}
+	if err := service.SaveResult(&domain.JobResult{JobId: "job-1", TenantId: domain.DefaultTenantId, Error: jobError}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err := service.GetResult(domain.DefaultTenantId, "job-1")
+
+	failed := &JobFailedError{}
+	if !errors.As(err, &failed) || !errors.Is(err, ErrJobFailed) {
+		t.Fatalf("expected a JobFailedError, got %v", err)
+	}
+	if failed.JobError.Code != "corrupt" || failed.JobError.Details["page"] != "2" {
+		t.Errorf("unexpected job error %+v", failed.JobError)
+	}
+}
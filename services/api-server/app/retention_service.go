@@ -0,0 +1,36 @@
+package app
+
+import (
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/retention"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+)
+
+type RetentionService interface {
+	// Purge archives and deletes the jobs the policy no longer keeps, or
+	// with dryRun only counts them.
+	Purge(dryRun bool) (*retention.Report, error)
+	// Start purges every interval, if the policy keeps anything for less
+	// than forever.
+	Start(interval time.Duration)
+}
+
+type retentionService struct {
+	retention.Purger
+}
+
+// NewRetentionService expires jobs by policy, which retention.ParsePolicy
+// reads with their age taken from their last status change.
+func NewRetentionService(retentionRepo repository.RetentionRepository, policy retention.Policy, archiver retention.Archiver) RetentionService {
+	return &retentionService{Purger: retention.NewPurger(retentionRepo, policy, archiver, "jobs")}
+}
+
+func (rs *retentionService) Purge(dryRun bool) (*retention.Report, error) {
+	report, err := rs.Purger.Purge(dryRun)
+	if err != nil {
+		return report, NewError(ErrInternal, "%v", err.Error())
+	}
+
+	return report, nil
+}
@@ -0,0 +1,43 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/retention"
+)
+
+type fakeRetentionRepository struct {
+	expired map[string]int64
+	purged  map[string]int64
+}
+
+func (repo *fakeRetentionRepository) CountExpired(status string, before int64) (int64, error) {
+	return repo.expired[status], nil
+}
+
+func (repo *fakeRetentionRepository) PurgeExpired(status string, before int64, archiver retention.Archiver) (int64, error) {
+	repo.purged[status] = repo.expired[status]
+	return repo.expired[status], nil
+}
+
+func TestPurgeDryRunOnlyCounts(t *testing.T) {
+	repo := &fakeRetentionRepository{expired: map[string]int64{"finished": 3, "skipped": 1}, purged: map[string]int64{}}
+	policy, _ := retention.ParsePolicy("skipped=90d,finished=30d")
+	service := NewRetentionService(repo, policy, retention.NewNoArchiver())
+
+	report, err := service.Purge(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.DryRun || report.Total != 4 || len(repo.purged) != 0 {
+		t.Errorf("expected 4 jobs to be counted and none purged, got %+v and %v", report, repo.purged)
+	}
+	if report.Statuses[0].Status != "finished" || report.Statuses[0].Before > time.Now().Add(-30*24*time.Hour).Unix() {
+		t.Errorf("expected finished jobs older than 30 days first, got %+v", report.Statuses[0])
+	}
+
+	if report, err := service.Purge(false); err != nil || report.Total != 4 || repo.purged["finished"] != 3 {
+		t.Errorf("expected the jobs to be purged, got %+v and %v: %v", report, repo.purged, err)
+	}
+}
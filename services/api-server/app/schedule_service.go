@@ -0,0 +1,213 @@
+package app
+
+import (
+	"log"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MisfireThreshold is how late a tick may be picked up before it counts as
+// missed (e.g. every api-server instance was down when it was due).
+const MisfireThreshold = time.Minute
+
+type ScheduleService interface {
+	CreateSchedule(scheduleRequest *domain.ScheduleRequest) (*domain.Schedule, error)
+	GetSchedules(tenantId string) ([]*domain.Schedule, error)
+	GetSchedule(tenantId, scheduleId string) (*domain.Schedule, error)
+	PauseSchedule(tenantId, scheduleId string) (*domain.Schedule, error)
+	ResumeSchedule(tenantId, scheduleId string) (*domain.Schedule, error)
+	DeleteSchedule(tenantId, scheduleId string) error
+	ClaimDueSchedules(now time.Time) ([]*domain.Schedule, error)
+	ReleaseSchedule(schedule *domain.Schedule) error
+}
+
+type scheduleService struct {
+	scheduleRepo repository.ScheduleRepository
+}
+
+func NewScheduleService(scheduleRepo repository.ScheduleRepository) ScheduleService {
+	return &scheduleService{scheduleRepo: scheduleRepo}
+}
+
+func (ss *scheduleService) CreateSchedule(scheduleRequest *domain.ScheduleRequest) (*domain.Schedule, error) {
+	if scheduleRequest.ObjectId == "" {
+		return nil, NewError(ErrInvalidRequest, "you must provide an object_id")
+	}
+
+	cronSchedule, err := cron.ParseStandard(scheduleRequest.CronExpr)
+	if err != nil {
+		return nil, NewError(ErrInvalidRequest, "invalid cron_expr: %v", err.Error())
+	}
+
+	misfirePolicy := scheduleRequest.MisfirePolicy
+	switch misfirePolicy {
+	case "":
+		misfirePolicy = domain.MisfireSkip
+	case domain.MisfireSkip, domain.MisfireRunOnce:
+	default:
+		return nil, NewError(ErrInvalidRequest, "misfire_policy must be one of: %v, %v", domain.MisfireSkip, domain.MisfireRunOnce)
+	}
+
+	jobType := scheduleRequest.Type
+	if jobType == "" {
+		jobType = domain.DefaultJobType
+	}
+
+	now := time.Now()
+
+	schedule := domain.Schedule{
+		ScheduleId:    uuid.New().String(),
+		TenantId:      scheduleRequest.TenantId,
+		ObjectId:      scheduleRequest.ObjectId,
+		Type:          jobType,
+		Params:        scheduleRequest.Params,
+		CronExpr:      scheduleRequest.CronExpr,
+		MisfirePolicy: misfirePolicy,
+		NextRun:       cronSchedule.Next(now).Unix(),
+		ApiKeyId:      scheduleRequest.ApiKeyId,
+		Timestamp:     now.Unix(),
+	}
+
+	if err := ss.scheduleRepo.SetSchedule(&schedule); err != nil {
+		return nil, NewError(ErrInternal, "could not set schedule to mongo %v", err.Error())
+	}
+
+	return &schedule, nil
+}
+
+func (ss *scheduleService) GetSchedules(tenantId string) ([]*domain.Schedule, error) {
+	schedules, err := ss.scheduleRepo.GetSchedules(tenantId)
+	if err != nil {
+		return nil, NewError(ErrInternal, "could not get schedules from mongo %v", err.Error())
+	}
+
+	return schedules, nil
+}
+
+func (ss *scheduleService) GetSchedule(tenantId, scheduleId string) (*domain.Schedule, error) {
+	schedule, err := ss.scheduleRepo.GetScheduleByScheduleId(tenantId, scheduleId)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, NewError(ErrScheduleNotFound, "no schedule with id: %v", scheduleId)
+		}
+		return nil, NewError(ErrInternal, "could not get schedule from mongo %v", err.Error())
+	}
+
+	return schedule, nil
+}
+
+func (ss *scheduleService) PauseSchedule(tenantId, scheduleId string) (*domain.Schedule, error) {
+	schedule, err := ss.GetSchedule(tenantId, scheduleId)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.Paused = true
+
+	if err := ss.scheduleRepo.UpdateSchedulePaused(schedule); err != nil {
+		return nil, NewError(ErrInternal, "could not update schedule to mongo %v", err.Error())
+	}
+
+	return schedule, nil
+}
+
+func (ss *scheduleService) ResumeSchedule(tenantId, scheduleId string) (*domain.Schedule, error) {
+	schedule, err := ss.GetSchedule(tenantId, scheduleId)
+	if err != nil {
+		return nil, err
+	}
+
+	cronSchedule, err := cron.ParseStandard(schedule.CronExpr)
+	if err != nil {
+		return nil, NewError(ErrInvalidRequest, "invalid cron_expr: %v", err.Error())
+	}
+
+	// Ticks that fell inside the pause are not misfires, so the next run
+	// is always computed from now.
+	schedule.Paused = false
+	schedule.NextRun = cronSchedule.Next(time.Now()).Unix()
+
+	if err := ss.scheduleRepo.UpdateSchedulePaused(schedule); err != nil {
+		return nil, NewError(ErrInternal, "could not update schedule to mongo %v", err.Error())
+	}
+
+	return schedule, nil
+}
+
+func (ss *scheduleService) DeleteSchedule(tenantId, scheduleId string) error {
+	if err := ss.scheduleRepo.DeleteSchedule(tenantId, scheduleId); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return NewError(ErrScheduleNotFound, "no schedule with id: %v", scheduleId)
+		}
+		return NewError(ErrInternal, "could not delete schedule from mongo %v", err.Error())
+	}
+
+	return nil
+}
+
+// ClaimDueSchedules advances every schedule whose next run has passed and
+// returns the ones that should fire a job now. Missed ticks are resolved
+// according to the schedule's misfire policy.
+func (ss *scheduleService) ClaimDueSchedules(now time.Time) ([]*domain.Schedule, error) {
+	dueSchedules, err := ss.scheduleRepo.GetDueSchedules(now.Unix())
+	if err != nil {
+		return nil, NewError(ErrInternal, "could not get due schedules from mongo %v", err.Error())
+	}
+
+	claimed := []*domain.Schedule{}
+
+	for _, schedule := range dueSchedules {
+		cronSchedule, err := cron.ParseStandard(schedule.CronExpr)
+		if err != nil {
+			log.Printf("schedule %v has an invalid cron_expr: %v\n", schedule.ScheduleId, err.Error())
+			continue
+		}
+
+		expectedNextRun := schedule.NextRun
+		missed := now.Sub(time.Unix(expectedNextRun, 0)) > MisfireThreshold
+		fire := !missed || schedule.MisfirePolicy == domain.MisfireRunOnce
+
+		schedule.ClaimedRun, schedule.PreviousLastRun = schedule.NextRun, schedule.LastRun
+		if fire {
+			schedule.LastRun = now.Unix()
+		}
+		schedule.NextRun = cronSchedule.Next(now).Unix()
+
+		ok, err := ss.scheduleRepo.AdvanceSchedule(schedule, expectedNextRun)
+		if err != nil {
+			log.Printf("could not advance schedule %v: %v\n", schedule.ScheduleId, err.Error())
+			continue
+		}
+
+		// Another instance already claimed this tick
+		if !ok {
+			continue
+		}
+
+		if fire {
+			claimed = append(claimed, schedule)
+		}
+	}
+
+	return claimed, nil
+}
+
+// ReleaseSchedule hands back a tick claimed by ClaimDueSchedules that could
+// not fire, so the next tick claims it again. Once it is older than
+// MisfireThreshold its misfire policy decides whether it still runs.
+func (ss *scheduleService) ReleaseSchedule(schedule *domain.Schedule) error {
+	released := *schedule
+	released.NextRun, released.LastRun = schedule.ClaimedRun, schedule.PreviousLastRun
+
+	// A schedule paused or resumed meanwhile already has its own next run
+	if _, err := ss.scheduleRepo.AdvanceSchedule(&released, schedule.NextRun); err != nil {
+		return NewError(ErrInternal, "could not release schedule %v: %v", schedule.ScheduleId, err.Error())
+	}
+
+	return nil
+}
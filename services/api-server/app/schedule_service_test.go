@@ -0,0 +1,146 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+)
+
+func TestClaimDueSchedulesComputesTheNextRun(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		// Friday 2021-10-01, cron expressions are read in local time
+		return time.Date(2021, time.October, day, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name          string
+		cronExpr      string
+		misfirePolicy string
+		nextRun       time.Time
+		now           time.Time
+		fired         bool
+		expectedNext  time.Time
+	}{
+		{name: "every minute", cronExpr: "* * * * *", nextRun: at(1, 10, 7), now: at(1, 10, 7), fired: true, expectedNext: at(1, 10, 8)},
+		{name: "every 15 minutes", cronExpr: "*/15 * * * *", nextRun: at(1, 10, 0), now: at(1, 10, 0), fired: true, expectedNext: at(1, 10, 15)},
+		{name: "late within the threshold", cronExpr: "*/15 * * * *", nextRun: at(1, 10, 0), now: at(1, 10, 0).Add(MisfireThreshold - time.Second), fired: true, expectedNext: at(1, 10, 15)},
+		{name: "hourly past the end of the day", cronExpr: "0 * * * *", nextRun: at(1, 23, 0), now: at(1, 23, 0), fired: true, expectedNext: at(2, 0, 0)},
+		{name: "weekdays over the weekend", cronExpr: "0 9 * * 1-5", nextRun: at(1, 9, 0), now: at(1, 9, 0), fired: true, expectedNext: at(4, 9, 0)},
+		{name: "monthly", cronExpr: "30 6 1 * *", nextRun: at(1, 6, 30), now: at(1, 6, 30), fired: true, expectedNext: time.Date(2021, time.November, 1, 6, 30, 0, 0, time.Local)},
+		{name: "descriptor", cronExpr: "@daily", nextRun: at(1, 0, 0), now: at(1, 0, 0), fired: true, expectedNext: at(2, 0, 0)},
+		{name: "missed ticks are skipped", cronExpr: "*/15 * * * *", misfirePolicy: domain.MisfireSkip, nextRun: at(1, 10, 0), now: at(1, 13, 5), expectedNext: at(1, 13, 15)},
+		{name: "missed ticks run once", cronExpr: "*/15 * * * *", misfirePolicy: domain.MisfireRunOnce, nextRun: at(1, 10, 0), now: at(1, 13, 5), fired: true, expectedNext: at(1, 13, 15)},
+		{name: "a missed daily tick runs once", cronExpr: "0 9 * * *", misfirePolicy: domain.MisfireRunOnce, nextRun: at(1, 9, 0), now: at(3, 8, 0), fired: true, expectedNext: at(3, 9, 0)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := repository.NewMemoryScheduleRepository()
+			service := NewScheduleService(repo)
+
+			misfirePolicy := test.misfirePolicy
+			if misfirePolicy == "" {
+				misfirePolicy = domain.MisfireSkip
+			}
+
+			schedule := &domain.Schedule{
+				ScheduleId:    "schedule-1",
+				TenantId:      domain.DefaultTenantId,
+				ObjectId:      "object-1",
+				CronExpr:      test.cronExpr,
+				MisfirePolicy: misfirePolicy,
+				NextRun:       test.nextRun.Unix(),
+			}
+			if err := repo.SetSchedule(schedule); err != nil {
+				t.Fatal(err)
+			}
+
+			claimed, err := service.ClaimDueSchedules(test.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fired := len(claimed) == 1; fired != test.fired {
+				t.Fatalf("expected fired %v, got %v claimed", test.fired, len(claimed))
+			}
+
+			stored, _ := service.GetSchedule(domain.DefaultTenantId, "schedule-1")
+			if stored.NextRun != test.expectedNext.Unix() {
+				t.Errorf("expected the next run at %v, got %v", test.expectedNext, time.Unix(stored.NextRun, 0))
+			}
+
+			expectedLast := int64(0)
+			if test.fired {
+				expectedLast = test.now.Unix()
+			}
+			if stored.LastRun != expectedLast {
+				t.Errorf("expected the last run at %v, got %v", expectedLast, stored.LastRun)
+			}
+
+			// The same tick is not claimed twice
+			if again, _ := service.ClaimDueSchedules(test.now); len(again) != 0 {
+				t.Errorf("expected the tick to be claimed once, got it again")
+			}
+		})
+	}
+}
+
+func TestClaimDueSchedulesIgnoresSchedulesNotDue(t *testing.T) {
+	repo := repository.NewMemoryScheduleRepository()
+	service := NewScheduleService(repo)
+
+	now := time.Date(2021, time.October, 1, 10, 0, 0, 0, time.Local)
+	schedules := []*domain.Schedule{
+		{ScheduleId: "future", TenantId: domain.DefaultTenantId, CronExpr: "* * * * *", NextRun: now.Add(time.Minute).Unix()},
+		{ScheduleId: "paused", TenantId: domain.DefaultTenantId, CronExpr: "* * * * *", NextRun: now.Unix(), Paused: true},
+	}
+	for _, schedule := range schedules {
+		if err := repo.SetSchedule(schedule); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if claimed, err := service.ClaimDueSchedules(now); err != nil || len(claimed) != 0 {
+		t.Errorf("expected nothing to be claimed, got %v: %v", len(claimed), err)
+	}
+}
+
+func TestCreateScheduleValidatesTheCronExpression(t *testing.T) {
+	service := NewScheduleService(repository.NewMemoryScheduleRepository())
+
+	tests := []struct {
+		cronExpr      string
+		misfirePolicy string
+		valid         bool
+	}{
+		{cronExpr: "*/5 * * * *", valid: true},
+		{cronExpr: "@hourly", misfirePolicy: domain.MisfireRunOnce, valid: true},
+		{cronExpr: ""},
+		{cronExpr: "* * * *"},
+		{cronExpr: "* * * * * *"},
+		{cronExpr: "61 * * * *"},
+		{cronExpr: "* * * * *", misfirePolicy: "catch_up"},
+	}
+
+	for _, test := range tests {
+		before := time.Now()
+		schedule, err := service.CreateSchedule(&domain.ScheduleRequest{ObjectId: "object-1", CronExpr: test.cronExpr, MisfirePolicy: test.misfirePolicy, TenantId: domain.DefaultTenantId})
+
+		if !test.valid {
+			if !errors.Is(err, ErrInvalidRequest) {
+				t.Errorf("%q %q: expected an invalid request, got %v", test.cronExpr, test.misfirePolicy, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: expected a schedule, got %v", test.cronExpr, err)
+			continue
+		}
+		if schedule.NextRun <= before.Unix() || schedule.LastRun != 0 {
+			t.Errorf("%q: expected the next run after %v, got %v", test.cronExpr, before.Unix(), schedule.NextRun)
+		}
+	}
+}
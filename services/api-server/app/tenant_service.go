@@ -0,0 +1,101 @@
+package app
+
+import (
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type TenantService interface {
+	GetTenant(tenantId string) (*domain.Tenant, error)
+	SetTenant(tenant *domain.Tenant) (*domain.Tenant, error)
+	CheckQuota(tenantId string, newJobs int64) error
+}
+
+type tenantService struct {
+	tenantRepo repository.TenantRepository
+	jobRepo    repository.JobRepository
+	defaults   domain.Tenant
+}
+
+// NewTenantService uses defaults for every tenant that has no quotas of
+// its own stored.
+func NewTenantService(tenantRepo repository.TenantRepository, jobRepo repository.JobRepository, defaults domain.Tenant) TenantService {
+	return &tenantService{tenantRepo: tenantRepo, jobRepo: jobRepo, defaults: defaults}
+}
+
+func (ts *tenantService) GetTenant(tenantId string) (*domain.Tenant, error) {
+	tenant, err := ts.tenantRepo.GetTenantByTenantId(tenantId)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			tenant := ts.defaults
+			tenant.TenantId = tenantId
+			return &tenant, nil
+		}
+		return nil, NewError(ErrInternal, "could not get tenant from mongo %v", err.Error())
+	}
+
+	return tenant, nil
+}
+
+func (ts *tenantService) SetTenant(tenant *domain.Tenant) (*domain.Tenant, error) {
+	if tenant.TenantId == "" {
+		return nil, NewError(ErrInvalidRequest, "you must provide a tenant_id")
+	}
+
+	if tenant.MaxConcurrentJobs < 0 || tenant.MaxDailyJobs < 0 {
+		return nil, NewError(ErrInvalidRequest, "quotas must not be negative")
+	}
+
+	if err := ts.tenantRepo.UpsertTenant(tenant); err != nil {
+		return nil, NewError(ErrInternal, "could not set tenant to mongo %v", err.Error())
+	}
+
+	return ts.GetTenant(tenant.TenantId)
+}
+
+// CheckQuota reports whether the tenant may start newJobs more jobs right
+// now. The counts are not locked, so bursts can overshoot a quota slightly.
+func (ts *tenantService) CheckQuota(tenantId string, newJobs int64) error {
+	tenant, err := ts.GetTenant(tenantId)
+	if err != nil {
+		return err
+	}
+
+	if tenant.MaxConcurrentJobs > 0 {
+		running, err := ts.jobRepo.CountJobsByStatus(tenantId, domain.JobProcessing)
+		if err != nil {
+			return NewError(ErrInternal, "could not count jobs in mongo %v", err.Error())
+		}
+
+		if running+newJobs > tenant.MaxConcurrentJobs {
+			return NewError(ErrQuotaExceeded, "tenant %v reached its limit of %v concurrent jobs", tenantId, tenant.MaxConcurrentJobs)
+		}
+	}
+
+	if tenant.MaxDailyJobs > 0 {
+		since := time.Now().Add(-24 * time.Hour).Unix()
+
+		created, err := ts.jobRepo.CountJobsCreatedSince(tenantId, since)
+		if err != nil {
+			return NewError(ErrInternal, "could not count jobs in mongo %v", err.Error())
+		}
+
+		if created+newJobs > tenant.MaxDailyJobs {
+			return NewError(ErrQuotaExceeded, "tenant %v reached its limit of %v jobs per day", tenantId, tenant.MaxDailyJobs)
+		}
+	}
+
+	return nil
+}
+
+// CheckTenantAdmin allows the admin keys of tenantId to administer the
+// tenant target, only the operator tenant administers other tenants.
+func CheckTenantAdmin(tenantId, target string) error {
+	if tenantId != target && tenantId != domain.OperatorTenantId {
+		return NewError(ErrForbidden, "api keys of tenant %v cannot administer tenant %v", tenantId, target)
+	}
+	return nil
+}
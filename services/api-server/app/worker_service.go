@@ -0,0 +1,79 @@
+package app
+
+import (
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// WorkerDeadAfter is how long a worker may go without a heartbeat before it
+// is reported dead. Job servers send one every 10 seconds.
+const WorkerDeadAfter = 30 * time.Second
+
+type WorkerService interface {
+	RecordHeartbeat(worker *domain.Worker) error
+	GetWorkers() ([]*domain.Worker, error)
+	GetWorker(workerId string) (*domain.Worker, error)
+}
+
+type workerService struct {
+	workerRepo repository.WorkerRepository
+}
+
+func NewWorkerService(workerRepo repository.WorkerRepository) WorkerService {
+	return &workerService{workerRepo: workerRepo}
+}
+
+func (ws *workerService) RecordHeartbeat(worker *domain.Worker) error {
+	if worker.WorkerId == "" {
+		return NewError(ErrInvalidRequest, "heartbeat without a worker_id")
+	}
+
+	// The api server's clock decides liveness, so skew between hosts does
+	// not make workers look dead or alive.
+	worker.LastSeen = time.Now().Unix()
+
+	if err := ws.workerRepo.UpsertWorker(worker); err != nil {
+		return NewError(ErrInternal, "could not set worker to mongo %v", err.Error())
+	}
+
+	return nil
+}
+
+func (ws *workerService) GetWorkers() ([]*domain.Worker, error) {
+	workers, err := ws.workerRepo.GetWorkers()
+	if err != nil {
+		return nil, NewError(ErrInternal, "could not get workers from mongo %v", err.Error())
+	}
+
+	now := time.Now()
+	for _, worker := range workers {
+		setWorkerStatus(worker, now)
+	}
+
+	return workers, nil
+}
+
+func (ws *workerService) GetWorker(workerId string) (*domain.Worker, error) {
+	worker, err := ws.workerRepo.GetWorkerByWorkerId(workerId)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, NewError(ErrWorkerNotFound, "no worker with id: %v", workerId)
+		}
+		return nil, NewError(ErrInternal, "could not get worker from mongo %v", err.Error())
+	}
+
+	setWorkerStatus(worker, time.Now())
+
+	return worker, nil
+}
+
+func setWorkerStatus(worker *domain.Worker, now time.Time) {
+	if now.Sub(time.Unix(worker.LastSeen, 0)) > WorkerDeadAfter {
+		worker.Status = domain.WorkerDead
+		return
+	}
+	worker.Status = domain.WorkerAlive
+}
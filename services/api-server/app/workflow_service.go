@@ -0,0 +1,330 @@
+package app
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/events"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/events/publishers"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var errAlreadyTransitioned = errors.New("job status was changed concurrently")
+
+type WorkflowService interface {
+	SubmitWorkflow(workflowRequest *domain.WorkflowRequest) (*domain.Workflow, error)
+	GetWorkflow(tenantId, workflowId string) (*domain.Workflow, error)
+	AdvanceWorkflow(tenantId, jobId string) error
+}
+
+type workflowService struct {
+	jobRepo           repository.JobRepository
+	workflowRepo      repository.WorkflowRepository
+	tenantService     TenantService
+	jobEventPublisher publishers.JobEventPublisher
+}
+
+func NewWorkflowService(jobRepo repository.JobRepository, workflowRepo repository.WorkflowRepository, tenantService TenantService, jobEventPublisher publishers.JobEventPublisher) WorkflowService {
+	return &workflowService{
+		jobRepo:           jobRepo,
+		workflowRepo:      workflowRepo,
+		tenantService:     tenantService,
+		jobEventPublisher: jobEventPublisher,
+	}
+}
+
+// SubmitWorkflow stores every job of the workflow as pending and dispatches
+// the ones without parents. Workflow jobs are not subject to the 5 minute
+// rerun rule, since one workflow may touch the same object several times,
+// but the whole workflow counts against the tenant's quotas up front.
+func (ws *workflowService) SubmitWorkflow(workflowRequest *domain.WorkflowRequest) (*domain.Workflow, error) {
+	if err := validateWorkflow(workflowRequest); err != nil {
+		return nil, err
+	}
+
+	if err := ws.tenantService.CheckQuota(workflowRequest.TenantId, int64(len(workflowRequest.Jobs))); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+
+	workflow := domain.Workflow{
+		WorkflowId: uuid.New().String(),
+		TenantId:   workflowRequest.TenantId,
+		Timestamp:  now,
+	}
+
+	jobs := []*domain.Job{}
+
+	for _, jobRequest := range workflowRequest.Jobs {
+		jobType := jobRequest.Type
+		if jobType == "" {
+			jobType = domain.DefaultJobType
+		}
+
+		job := domain.Job{
+			JobId:      uuid.New().String(),
+			TenantId:   workflowRequest.TenantId,
+			ObjectId:   jobRequest.ObjectId,
+			Type:       jobType,
+			Params:     jobRequest.Params,
+			Status:     domain.JobPending,
+			Timestamp:  now,
+			CreatedAt:  now,
+			WorkflowId: workflow.WorkflowId,
+			ApiKeyId:   workflowRequest.ApiKeyId,
+		}
+		jobs = append(jobs, &job)
+
+		workflow.Nodes = append(workflow.Nodes, domain.WorkflowNode{
+			Key:       jobRequest.Key,
+			JobId:     job.JobId,
+			DependsOn: jobRequest.DependsOn,
+		})
+	}
+
+	if err := ws.workflowRepo.SetWorkflow(&workflow); err != nil {
+		return nil, NewError(ErrInternal, "could not set workflow to mongo %v", err.Error())
+	}
+
+	for _, job := range jobs {
+		if err := ws.jobRepo.SetJob(job); err != nil {
+			return nil, NewError(ErrInternal, "could not set workflow job to mongo %v", err.Error())
+		}
+	}
+
+	if err := ws.advance(&workflow, jobs); err != nil {
+		return nil, err
+	}
+
+	setWorkflowStatus(&workflow, jobs)
+
+	return &workflow, nil
+}
+
+func (ws *workflowService) GetWorkflow(tenantId, workflowId string) (*domain.Workflow, error) {
+	workflow, err := ws.workflowRepo.GetWorkflowByWorkflowId(tenantId, workflowId)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, NewError(ErrWorkflowNotFound, "no workflow with id: %v", workflowId)
+		}
+		return nil, NewError(ErrInternal, "could not get workflow from mongo %v", err.Error())
+	}
+
+	jobs, err := ws.jobRepo.GetJobsByWorkflowId(tenantId, workflowId)
+	if err != nil {
+		return nil, NewError(ErrInternal, "could not get workflow jobs from mongo %v", err.Error())
+	}
+
+	setWorkflowStatus(workflow, jobs)
+
+	return workflow, nil
+}
+
+// AdvanceWorkflow is called after a job reached a new status. If the job
+// belongs to a workflow, children whose parents all finished are dispatched
+// and children of failed parents are skipped.
+func (ws *workflowService) AdvanceWorkflow(tenantId, jobId string) error {
+	job, err := ws.jobRepo.GetJobByJobId(tenantId, jobId)
+	if err != nil {
+		return NewError(ErrInternal, "could not get job from mongo %v", err.Error())
+	}
+
+	if job.WorkflowId == "" {
+		return nil
+	}
+
+	workflow, err := ws.workflowRepo.GetWorkflowByWorkflowId(tenantId, job.WorkflowId)
+	if err != nil {
+		return NewError(ErrInternal, "could not get workflow from mongo %v", err.Error())
+	}
+
+	jobs, err := ws.jobRepo.GetJobsByWorkflowId(tenantId, job.WorkflowId)
+	if err != nil {
+		return NewError(ErrInternal, "could not get workflow jobs from mongo %v", err.Error())
+	}
+
+	return ws.advance(workflow, jobs)
+}
+
+func (ws *workflowService) advance(workflow *domain.Workflow, jobs []*domain.Job) error {
+	jobsByKey := map[string]*domain.Job{}
+	jobsById := map[string]*domain.Job{}
+
+	for _, job := range jobs {
+		jobsById[job.JobId] = job
+	}
+	for _, node := range workflow.Nodes {
+		jobsByKey[node.Key] = jobsById[node.JobId]
+	}
+
+	// Skipping a job can unblock the skipping of its own children, so keep
+	// going until nothing changes.
+	for changed := true; changed; {
+		changed = false
+
+		for _, node := range workflow.Nodes {
+			job := jobsByKey[node.Key]
+			if job == nil || job.Status != domain.JobPending {
+				continue
+			}
+
+			ready, failed := true, false
+			for _, parentKey := range node.DependsOn {
+				parent := jobsByKey[parentKey]
+				switch {
+				case parent == nil:
+					ready = false
+				case parent.Status == domain.JobFinished:
+				case parent.IsTerminal():
+					failed = true
+				default:
+					ready = false
+				}
+			}
+
+			switch {
+			case failed:
+				if err := ws.transition(job, domain.JobSkipped); err != nil && err != errAlreadyTransitioned {
+					return err
+				}
+				changed = true
+			case ready:
+				if err := ws.dispatch(job); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (ws *workflowService) transition(job *domain.Job, status string) error {
+	fromStatus := job.Status
+
+	job.Status = status
+	job.Timestamp = time.Now().Unix()
+
+	ok, err := ws.jobRepo.TransitionJobStatus(job, fromStatus)
+	if err != nil {
+		job.Status = fromStatus
+		return NewError(ErrInternal, "could not update workflow job to mongo %v", err.Error())
+	}
+
+	// Another listener moved the job first; it owns the side effects.
+	if !ok {
+		return errAlreadyTransitioned
+	}
+
+	return nil
+}
+
+func (ws *workflowService) dispatch(job *domain.Job) error {
+	if err := ws.transition(job, domain.JobProcessing); err != nil {
+		if err == errAlreadyTransitioned {
+			return nil
+		}
+		return err
+	}
+
+	eventJob := events.NewJobEvent(contracts.SubjectJobCreated, job)
+
+	if err := ws.jobEventPublisher.PublishData(eventJob); err != nil {
+		// Put the job back so the next advance can retry it.
+		job.Status = domain.JobPending
+		ws.jobRepo.TransitionJobStatus(job, domain.JobProcessing)
+
+		return NewError(ErrInternal, "could not publish workflow job %v: %v", job.JobId, err.Error())
+	}
+
+	return nil
+}
+
+func setWorkflowStatus(workflow *domain.Workflow, jobs []*domain.Job) {
+	statuses := map[string]string{}
+	for _, job := range jobs {
+		statuses[job.JobId] = job.Status
+	}
+
+	workflow.Status = domain.WorkflowFinished
+
+	for i := range workflow.Nodes {
+		status := statuses[workflow.Nodes[i].JobId]
+		workflow.Nodes[i].Status = status
+
+		switch status {
+		case domain.JobFinished:
+		case domain.JobFailed, domain.JobCancelled, domain.JobSkipped:
+			if workflow.Status == domain.WorkflowFinished {
+				workflow.Status = domain.WorkflowFailed
+			}
+		default:
+			workflow.Status = domain.WorkflowRunning
+		}
+	}
+}
+
+func validateWorkflow(workflowRequest *domain.WorkflowRequest) error {
+	if len(workflowRequest.Jobs) == 0 {
+		return NewError(ErrInvalidRequest, "a workflow must contain at least one job")
+	}
+
+	pending := map[string]int{}
+	children := map[string][]string{}
+
+	for _, jobRequest := range workflowRequest.Jobs {
+		if jobRequest.Key == "" {
+			return NewError(ErrInvalidRequest, "every workflow job must have a key")
+		}
+		if jobRequest.ObjectId == "" {
+			return NewError(ErrInvalidRequest, "workflow job %v must provide an object_id", jobRequest.Key)
+		}
+		if _, ok := pending[jobRequest.Key]; ok {
+			return NewError(ErrInvalidRequest, "duplicate workflow job key: %v", jobRequest.Key)
+		}
+		pending[jobRequest.Key] = len(jobRequest.DependsOn)
+	}
+
+	for _, jobRequest := range workflowRequest.Jobs {
+		for _, parentKey := range jobRequest.DependsOn {
+			if _, ok := pending[parentKey]; !ok {
+				return NewError(ErrInvalidRequest, "workflow job %v depends on unknown job %v", jobRequest.Key, parentKey)
+			}
+			children[parentKey] = append(children[parentKey], jobRequest.Key)
+		}
+	}
+
+	// Kahn's algorithm: if some job never becomes free of parents, the
+	// graph has a cycle.
+	queue := []string{}
+	for key, count := range pending {
+		if count == 0 {
+			queue = append(queue, key)
+		}
+	}
+
+	visited := 0
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		visited++
+
+		for _, child := range children[key] {
+			pending[child]--
+			if pending[child] == 0 {
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	if visited != len(workflowRequest.Jobs) {
+		return NewError(ErrInvalidRequest, "workflow dependencies must not contain cycles")
+	}
+
+	return nil
+}
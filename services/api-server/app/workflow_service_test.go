@@ -0,0 +1,214 @@
+package app
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+)
+
+func workflowJob(key string, dependsOn ...string) domain.WorkflowJobRequest {
+	return domain.WorkflowJobRequest{Key: key, ObjectId: "object-" + key, DependsOn: dependsOn}
+}
+
+func TestValidateWorkflow(t *testing.T) {
+	tests := []struct {
+		name  string
+		jobs  []domain.WorkflowJobRequest
+		valid bool
+	}{
+		{name: "no jobs"},
+		{name: "single job", jobs: []domain.WorkflowJobRequest{workflowJob("a")}, valid: true},
+		{name: "chain", jobs: []domain.WorkflowJobRequest{workflowJob("a"), workflowJob("b", "a"), workflowJob("c", "b")}, valid: true},
+		{name: "diamond", jobs: []domain.WorkflowJobRequest{workflowJob("a"), workflowJob("b", "a"), workflowJob("c", "a"), workflowJob("d", "b", "c")}, valid: true},
+		{name: "child listed before its parent", jobs: []domain.WorkflowJobRequest{workflowJob("b", "a"), workflowJob("a")}, valid: true},
+		{name: "parent listed twice", jobs: []domain.WorkflowJobRequest{workflowJob("a"), workflowJob("b", "a", "a")}, valid: true},
+		{name: "missing key", jobs: []domain.WorkflowJobRequest{workflowJob("")}},
+		{name: "missing object id", jobs: []domain.WorkflowJobRequest{{Key: "a"}}},
+		{name: "duplicate key", jobs: []domain.WorkflowJobRequest{workflowJob("a"), workflowJob("a")}},
+		{name: "unknown dependency", jobs: []domain.WorkflowJobRequest{workflowJob("a"), workflowJob("b", "c")}},
+		{name: "self dependency", jobs: []domain.WorkflowJobRequest{workflowJob("a", "a")}},
+		{name: "two job cycle", jobs: []domain.WorkflowJobRequest{workflowJob("a", "b"), workflowJob("b", "a")}},
+		{name: "cycle below a root", jobs: []domain.WorkflowJobRequest{workflowJob("a"), workflowJob("b", "a", "d"), workflowJob("c", "b"), workflowJob("d", "c")}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateWorkflow(&domain.WorkflowRequest{Jobs: test.jobs, TenantId: domain.DefaultTenantId})
+
+			if test.valid && err != nil {
+				t.Fatalf("expected a valid workflow, got %v", err)
+			}
+			if !test.valid && !errors.Is(err, ErrInvalidRequest) {
+				t.Fatalf("expected an invalid request, got %v", err)
+			}
+		})
+	}
+}
+
+type endedJob struct {
+	key    string
+	status string
+}
+
+func TestAdvanceWorkflow(t *testing.T) {
+	// a -> b -> d and a -> c -> d, with e on its own
+	jobs := []domain.WorkflowJobRequest{workflowJob("a"), workflowJob("b", "a"), workflowJob("c", "a"), workflowJob("d", "b", "c"), workflowJob("e")}
+
+	tests := []struct {
+		name string
+		// ended are moved from processing to their status, in order
+		ended      []endedJob
+		statuses   map[string]string
+		dispatched []string
+		status     string
+	}{
+		{
+			name:       "roots are dispatched",
+			statuses:   map[string]string{"a": domain.JobProcessing, "b": domain.JobPending, "c": domain.JobPending, "d": domain.JobPending, "e": domain.JobProcessing},
+			dispatched: []string{"a", "e"},
+			status:     domain.WorkflowRunning,
+		},
+		{
+			name:       "a finished parent dispatches its children",
+			ended:      []endedJob{{"a", domain.JobFinished}},
+			statuses:   map[string]string{"a": domain.JobFinished, "b": domain.JobProcessing, "c": domain.JobProcessing, "d": domain.JobPending, "e": domain.JobProcessing},
+			dispatched: []string{"a", "e", "b", "c"},
+			status:     domain.WorkflowRunning,
+		},
+		{
+			name:       "a child waits for every parent",
+			ended:      []endedJob{{"a", domain.JobFinished}, {"b", domain.JobFinished}},
+			statuses:   map[string]string{"a": domain.JobFinished, "b": domain.JobFinished, "c": domain.JobProcessing, "d": domain.JobPending, "e": domain.JobProcessing},
+			dispatched: []string{"a", "e", "b", "c"},
+			status:     domain.WorkflowRunning,
+		},
+		{
+			name:       "a child runs once every parent finished",
+			ended:      []endedJob{{"a", domain.JobFinished}, {"b", domain.JobFinished}, {"c", domain.JobFinished}},
+			statuses:   map[string]string{"a": domain.JobFinished, "b": domain.JobFinished, "c": domain.JobFinished, "d": domain.JobProcessing, "e": domain.JobProcessing},
+			dispatched: []string{"a", "e", "b", "c", "d"},
+			status:     domain.WorkflowRunning,
+		},
+		{
+			name:       "a cancelled root skips every descendant",
+			ended:      []endedJob{{"a", domain.JobCancelled}},
+			statuses:   map[string]string{"a": domain.JobCancelled, "b": domain.JobSkipped, "c": domain.JobSkipped, "d": domain.JobSkipped, "e": domain.JobProcessing},
+			dispatched: []string{"a", "e"},
+			status:     domain.WorkflowRunning,
+		},
+		{
+			name:       "a failed step skips its children",
+			ended:      []endedJob{{"a", domain.JobFailed}},
+			statuses:   map[string]string{"a": domain.JobFailed, "b": domain.JobSkipped, "c": domain.JobSkipped, "d": domain.JobSkipped, "e": domain.JobProcessing},
+			dispatched: []string{"a", "e"},
+			status:     domain.WorkflowRunning,
+		},
+		{
+			name:       "one failed parent skips a child",
+			ended:      []endedJob{{"a", domain.JobFinished}, {"b", domain.JobCancelled}},
+			statuses:   map[string]string{"a": domain.JobFinished, "b": domain.JobCancelled, "c": domain.JobProcessing, "d": domain.JobSkipped, "e": domain.JobProcessing},
+			dispatched: []string{"a", "e", "b", "c"},
+			status:     domain.WorkflowRunning,
+		},
+		{
+			name:       "every job finished",
+			ended:      []endedJob{{"a", domain.JobFinished}, {"e", domain.JobFinished}, {"b", domain.JobFinished}, {"c", domain.JobFinished}, {"d", domain.JobFinished}},
+			statuses:   map[string]string{"a": domain.JobFinished, "b": domain.JobFinished, "c": domain.JobFinished, "d": domain.JobFinished, "e": domain.JobFinished},
+			dispatched: []string{"a", "e", "b", "c", "d"},
+			status:     domain.WorkflowFinished,
+		},
+		{
+			name:       "a failed step fails the workflow",
+			ended:      []endedJob{{"a", domain.JobFinished}, {"e", domain.JobFinished}, {"b", domain.JobFinished}, {"c", domain.JobFailed}},
+			statuses:   map[string]string{"a": domain.JobFinished, "b": domain.JobFinished, "c": domain.JobFailed, "d": domain.JobSkipped, "e": domain.JobFinished},
+			dispatched: []string{"a", "e", "b", "c"},
+			status:     domain.WorkflowFailed,
+		},
+		{
+			name:       "a failed workflow ends once nothing runs",
+			ended:      []endedJob{{"e", domain.JobFinished}, {"a", domain.JobCancelled}},
+			statuses:   map[string]string{"a": domain.JobCancelled, "b": domain.JobSkipped, "c": domain.JobSkipped, "d": domain.JobSkipped, "e": domain.JobFinished},
+			dispatched: []string{"a", "e"},
+			status:     domain.WorkflowFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepository()
+			publisher := &recordingPublisher{}
+			service := NewWorkflowService(repo, repository.NewMemoryWorkflowRepository(), unlimitedTenantService{}, publisher)
+
+			workflow, err := service.SubmitWorkflow(&domain.WorkflowRequest{Jobs: jobs, TenantId: domain.DefaultTenantId})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			jobIds := map[string]string{}
+			keys := map[string]string{}
+			for _, node := range workflow.Nodes {
+				jobIds[node.Key] = node.JobId
+				keys[node.JobId] = node.Key
+			}
+
+			for _, ended := range test.ended {
+				job, _ := repo.GetJobByJobId(domain.DefaultTenantId, jobIds[ended.key])
+				job.Status = ended.status
+				if ok, _ := repo.TransitionJobStatus(job, domain.JobProcessing); !ok {
+					t.Fatalf("could not end job %v", ended.key)
+				}
+				if err := service.AdvanceWorkflow(domain.DefaultTenantId, job.JobId); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			workflow, err = service.GetWorkflow(domain.DefaultTenantId, workflow.WorkflowId)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, node := range workflow.Nodes {
+				if node.Status != test.statuses[node.Key] {
+					t.Errorf("expected job %v to be %v, got %v", node.Key, test.statuses[node.Key], node.Status)
+				}
+			}
+			if workflow.Status != test.status {
+				t.Errorf("expected the workflow to be %v, got %v", test.status, workflow.Status)
+			}
+
+			dispatched := []string{}
+			for _, event := range publisher.events {
+				if event.Subject != contracts.SubjectJobCreated {
+					t.Errorf("expected only job:created events, got %v", event.Subject)
+				}
+				dispatched = append(dispatched, keys[event.Job.JobId])
+			}
+			if !sameKeys(dispatched, test.dispatched) {
+				t.Errorf("expected %v to be dispatched, got %v", test.dispatched, dispatched)
+			}
+		})
+	}
+}
+
+// sameKeys compares keys regardless of the order jobs dispatched together
+// are published in.
+func sameKeys(got, expected []string) bool {
+	if len(got) != len(expected) {
+		return false
+	}
+
+	counts := map[string]int{}
+	for _, key := range expected {
+		counts[key]++
+	}
+	for _, key := range got {
+		counts[key]--
+		if counts[key] < 0 {
+			return false
+		}
+	}
+	return true
+}
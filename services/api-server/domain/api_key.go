@@ -0,0 +1,45 @@
+package domain
+
+const (
+	ScopeJobsRead  = "jobs:read"
+	ScopeJobsWrite = "jobs:write"
+	ScopeAdmin     = "admin"
+)
+
+var Scopes = []string{ScopeJobsRead, ScopeJobsWrite, ScopeAdmin}
+
+type ApiKey struct {
+	Id        string   `json:"id,omitempty" bson:"_id"`
+	KeyId     string   `json:"key_id"`
+	TenantId  string   `json:"tenant_id"`
+	Name      string   `json:"name"`
+	Prefix    string   `json:"prefix"`
+	Hash      string   `json:"-"`
+	Scopes    []string `json:"scopes"`
+	Revoked   bool     `json:"revoked"`
+	Timestamp int64    `json:"timestamp" bson:"timestamp"`
+	RotatedAt int64    `json:"rotated_at,omitempty"`
+}
+
+// HasScope reports whether the key grants scope. Admin keys grant every scope.
+func (key *ApiKey) HasScope(scope string) bool {
+	for _, s := range key.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+type ApiKeyRequest struct {
+	Name     string   `json:"name"`
+	TenantId string   `json:"tenant_id"`
+	Scopes   []string `json:"scopes"`
+}
+
+// IssuedApiKey is only returned when a key is issued or rotated, as the
+// plain key is never stored.
+type IssuedApiKey struct {
+	*ApiKey
+	Key string `json:"key"`
+}
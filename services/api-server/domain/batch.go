@@ -0,0 +1,40 @@
+package domain
+
+const MaxBatchSize = 10000
+
+const (
+	// BatchSubmitting is a batch whose jobs are still being created
+	BatchSubmitting = "submitting"
+	BatchActive     = "active"
+	BatchCompleted  = "completed"
+	BatchCancelled  = "cancelled"
+)
+
+type Batch struct {
+	Id         string           `json:"id,omitempty" bson:"_id"`
+	BatchId    string           `json:"batch_id"`
+	TenantId   string           `json:"tenant_id"`
+	Status     string           `json:"status"`
+	Total      int              `json:"total"`
+	Rejected   int              `json:"rejected"`
+	Timestamp  int64            `json:"timestamp" bson:"timestamp"`
+	Counts     map[string]int   `json:"counts,omitempty" bson:"-"`
+	Completion float64          `json:"completion" bson:"-"`
+	Errors     []BatchItemError `json:"errors,omitempty" bson:"-"`
+}
+
+type BatchRequest struct {
+	ObjectIds []string          `json:"object_ids"`
+	Type      string            `json:"type"`
+	Params    map[string]string `json:"params"`
+	ApiKeyId  string            `json:"-"`
+	TenantId  string            `json:"-"`
+}
+
+// BatchItemError is why the job of one object was not created, Code is the
+// stable error code a single job request would have answered.
+type BatchItemError struct {
+	ObjectId string `json:"object_id"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
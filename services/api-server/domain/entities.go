@@ -1,14 +1,65 @@
 package domain
 
+const DefaultJobType = "default"
+
+const (
+	JobPending    = "pending"
+	JobProcessing = "processing"
+	JobFinished   = "finished"
+	JobFailed     = "failed"
+	JobCancelled  = "cancelled"
+	JobSkipped    = "skipped"
+)
+
 type Job struct {
-	Id            string `json:"id,omitempty" bson:"_id"`
-	JobId         string `json:"job_id"`
-	ObjectId      string `json:"object_id"`
-	Status        string `json:"status"`
-	Timestamp     int64  `json:"timestamp" bson:"timestamp"`
-	SleepTimeUsed int    `json:"sleep_time_used"`
+	Id            string            `json:"id,omitempty" bson:"_id"`
+	JobId         string            `json:"job_id"`
+	TenantId      string            `json:"tenant_id"`
+	ObjectId      string            `json:"object_id"`
+	Type          string            `json:"type"`
+	Params        map[string]string `json:"params,omitempty"`
+	Status        string            `json:"status"`
+	Timestamp     int64             `json:"timestamp" bson:"timestamp"`
+	CreatedAt     int64             `json:"created_at"`
+	SleepTimeUsed int               `json:"sleep_time_used"`
+	ScheduleId    string            `json:"schedule_id,omitempty"`
+	WorkflowId    string            `json:"workflow_id,omitempty"`
+	BatchId       string            `json:"batch_id,omitempty"`
+	ApiKeyId      string            `json:"api_key_id,omitempty"`
+	WorkerId      string            `json:"worker_id,omitempty"`
+	// PreviousJobId is the job the object's claim pointed to before this
+	// one. Only the request that created the job knows it.
+	PreviousJobId string `json:"-" bson:"-"`
+}
+
+func (job *Job) IsTerminal() bool {
+	return job.Status == JobFinished || job.Status == JobFailed || job.Status == JobCancelled || job.Status == JobSkipped
+}
+
+type JobRequest struct {
+	ObjectId   string            `json:"object_id"`
+	Type       string            `json:"type"`
+	Params     map[string]string `json:"params"`
+	ScheduleId string            `json:"-"`
+	BatchId    string            `json:"-"`
+	ApiKeyId   string            `json:"-"`
+	TenantId   string            `json:"-"`
 }
 
 type ResponseJob struct {
 	JobId string `json:"job_id"`
 }
+
+const (
+	DefaultJobListLimit = 100
+	MaxJobListLimit     = 1000
+)
+
+// JobFilter selects the jobs of a tenant, newest first. Empty fields match
+// every job.
+type JobFilter struct {
+	TenantId string `json:"-"`
+	ObjectId string `json:"object_id"`
+	Status   string `json:"status"`
+	Limit    int64  `json:"limit"`
+}
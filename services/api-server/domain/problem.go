@@ -0,0 +1,16 @@
+package domain
+
+// Problem is an RFC 7807 problem details body. Code is the stable error code
+// clients should match on, the other extension members are only set when
+// they apply.
+type Problem struct {
+	Type       string    `json:"type"`
+	Title      string    `json:"title"`
+	Status     int       `json:"status"`
+	Detail     string    `json:"detail,omitempty"`
+	Instance   string    `json:"instance,omitempty"`
+	Code       string    `json:"code"`
+	Policy     string    `json:"policy,omitempty"`
+	RetryAfter int64     `json:"retry_after,omitempty"`
+	Error      *JobError `json:"error,omitempty"`
+}
@@ -0,0 +1,14 @@
+package domain
+
+// QuarantinedMessage is an event that could not be decoded. It is kept with
+// its raw payload (base64 in JSON) so it can be inspected, and acked so it
+// is not redelivered.
+type QuarantinedMessage struct {
+	Id        string `json:"id,omitempty" bson:"_id"`
+	MessageId string `json:"message_id"`
+	Subject   string `json:"subject"`
+	Sequence  uint64 `json:"sequence"`
+	Data      []byte `json:"data"`
+	Error     string `json:"error"`
+	Timestamp int64  `json:"timestamp"`
+}
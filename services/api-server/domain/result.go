@@ -0,0 +1,23 @@
+package domain
+
+// JobResult is what the executor of a finished job returned, a JSON
+// document or an error. Data is stored with it up to the inline limit,
+// larger data is in GridFS as File, written there by the job server when it
+// was too large for the event.
+type JobResult struct {
+	Id        string    `json:"id,omitempty" bson:"_id"`
+	JobId     string    `json:"job_id"`
+	TenantId  string    `json:"tenant_id"`
+	Data      []byte    `json:"-"`
+	File      string    `json:"-"`
+	Size      int64     `json:"size"`
+	Error     *JobError `json:"error,omitempty"`
+	Timestamp int64     `json:"timestamp"`
+}
+
+// JobError is the error an executor failed with.
+type JobError struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"`
+}
@@ -0,0 +1,39 @@
+package domain
+
+const (
+	// MisfireSkip drops the ticks missed while no scheduler was running
+	// and waits for the next regular tick.
+	MisfireSkip = "skip"
+	// MisfireRunOnce collapses all missed ticks into a single catch-up run.
+	MisfireRunOnce = "run_once"
+)
+
+type Schedule struct {
+	Id            string            `json:"id,omitempty" bson:"_id"`
+	ScheduleId    string            `json:"schedule_id"`
+	TenantId      string            `json:"tenant_id"`
+	ObjectId      string            `json:"object_id"`
+	Type          string            `json:"type"`
+	Params        map[string]string `json:"params,omitempty"`
+	CronExpr      string            `json:"cron_expr"`
+	MisfirePolicy string            `json:"misfire_policy"`
+	Paused        bool              `json:"paused"`
+	LastRun       int64             `json:"last_run"`
+	NextRun       int64             `json:"next_run"`
+	ApiKeyId      string            `json:"api_key_id,omitempty"`
+	Timestamp     int64             `json:"timestamp" bson:"timestamp"`
+	// ClaimedRun and PreviousLastRun are the runs a claimed tick moved the
+	// schedule on from, so that a tick that could not fire is handed back.
+	ClaimedRun      int64 `json:"-" bson:"-"`
+	PreviousLastRun int64 `json:"-" bson:"-"`
+}
+
+type ScheduleRequest struct {
+	ObjectId      string            `json:"object_id"`
+	Type          string            `json:"type"`
+	Params        map[string]string `json:"params"`
+	CronExpr      string            `json:"cron_expr"`
+	MisfirePolicy string            `json:"misfire_policy"`
+	ApiKeyId      string            `json:"-"`
+	TenantId      string            `json:"-"`
+}
@@ -0,0 +1,17 @@
+package domain
+
+// DefaultTenantId owns the bootstrap admin key and every job created before
+// tenants existed.
+const DefaultTenantId = "default"
+
+// OperatorTenantId is the tenant whose admin keys administer every tenant,
+// admin keys of other tenants only administer their own.
+const OperatorTenantId = DefaultTenantId
+
+// Tenant holds the quotas of one tenant. A limit of 0 means unlimited.
+type Tenant struct {
+	Id                string `json:"id,omitempty" bson:"_id"`
+	TenantId          string `json:"tenant_id"`
+	MaxConcurrentJobs int64  `json:"max_concurrent_jobs"`
+	MaxDailyJobs      int64  `json:"max_daily_jobs"`
+}
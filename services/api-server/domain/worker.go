@@ -0,0 +1,20 @@
+package domain
+
+const (
+	WorkerAlive = "alive"
+	WorkerDead  = "dead"
+)
+
+// Worker is a job server as last reported by its heartbeats. Status is
+// derived from LastSeen whenever a worker is read.
+type Worker struct {
+	Id        string   `json:"id,omitempty" bson:"_id"`
+	WorkerId  string   `json:"worker_id"`
+	Status    string   `json:"status" bson:"-"`
+	Capacity  int      `json:"capacity"`
+	Busy      int64    `json:"busy"`
+	JobTypes  []string `json:"job_types"`
+	Version   string   `json:"version"`
+	StartedAt int64    `json:"started_at"`
+	LastSeen  int64    `json:"last_seen"`
+}
@@ -0,0 +1,37 @@
+package domain
+
+const (
+	WorkflowRunning  = "running"
+	WorkflowFinished = "finished"
+	WorkflowFailed   = "failed"
+)
+
+type Workflow struct {
+	Id         string         `json:"id,omitempty" bson:"_id"`
+	WorkflowId string         `json:"workflow_id"`
+	TenantId   string         `json:"tenant_id"`
+	Status     string         `json:"status" bson:"-"`
+	Nodes      []WorkflowNode `json:"jobs"`
+	Timestamp  int64          `json:"timestamp" bson:"timestamp"`
+}
+
+type WorkflowNode struct {
+	Key       string   `json:"key"`
+	JobId     string   `json:"job_id"`
+	DependsOn []string `json:"depends_on,omitempty"`
+	Status    string   `json:"status" bson:"-"`
+}
+
+type WorkflowRequest struct {
+	Jobs     []WorkflowJobRequest `json:"jobs"`
+	ApiKeyId string               `json:"-"`
+	TenantId string               `json:"-"`
+}
+
+type WorkflowJobRequest struct {
+	Key       string            `json:"key"`
+	ObjectId  string            `json:"object_id"`
+	Type      string            `json:"type"`
+	Params    map[string]string `json:"params"`
+	DependsOn []string          `json:"depends_on"`
+}
@@ -1,8 +1,77 @@
 package events
 
-import "github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+import (
+	"time"
 
-type JobEvent struct {
-	Subject string      `json:"subject"`
-	Job     *domain.Job `json:"job"`
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+)
+
+// NewJobEvent is the event published on subject about job.
+func NewJobEvent(subject string, job *domain.Job) *contracts.JobEvent {
+	return &contracts.JobEvent{
+		Subject:  subject,
+		TenantId: job.TenantId,
+		Job: contracts.Job{
+			JobId:         job.JobId,
+			TenantId:      job.TenantId,
+			ObjectId:      job.ObjectId,
+			Type:          job.Type,
+			Params:        job.Params,
+			Status:        job.Status,
+			Timestamp:     job.Timestamp,
+			CreatedAt:     job.CreatedAt,
+			SleepTimeUsed: job.SleepTimeUsed,
+			ScheduleId:    job.ScheduleId,
+			WorkflowId:    job.WorkflowId,
+			BatchId:       job.BatchId,
+			ApiKeyId:      job.ApiKeyId,
+			WorkerId:      job.WorkerId,
+		},
+	}
+}
+
+// JobFromEvent is the job reported by jobEvent, in the event's tenant.
+func JobFromEvent(jobEvent *contracts.JobEvent) *domain.Job {
+	job := jobEvent.Job
+
+	return &domain.Job{
+		JobId:         job.JobId,
+		TenantId:      jobEvent.TenantId,
+		ObjectId:      job.ObjectId,
+		Type:          job.Type,
+		Params:        job.Params,
+		Status:        job.Status,
+		Timestamp:     job.Timestamp,
+		CreatedAt:     job.CreatedAt,
+		SleepTimeUsed: job.SleepTimeUsed,
+		ScheduleId:    job.ScheduleId,
+		WorkflowId:    job.WorkflowId,
+		BatchId:       job.BatchId,
+		ApiKeyId:      job.ApiKeyId,
+		WorkerId:      job.WorkerId,
+	}
+}
+
+// ResultFromEvent is the result of the job finished or failed by jobEvent,
+// or nil when it carries none.
+func ResultFromEvent(jobEvent *contracts.JobEvent) *domain.JobResult {
+	result := jobEvent.Job.Result
+	if result == nil || (jobEvent.Job.Status != domain.JobFinished && jobEvent.Job.Status != domain.JobFailed) {
+		return nil
+	}
+
+	jobResult := &domain.JobResult{
+		JobId:     jobEvent.Job.JobId,
+		TenantId:  jobEvent.TenantId,
+		Data:      result.Data,
+		File:      result.File,
+		Size:      result.Size,
+		Timestamp: time.Now().Unix(),
+	}
+	if result.Error != nil {
+		jobResult.Error = &domain.JobError{Code: result.Error.Code, Message: result.Error.Message, Details: result.Error.Details}
+	}
+
+	return jobResult
 }
@@ -0,0 +1,62 @@
+package listeners
+
+import (
+	"log"
+
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/natsconn"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/stan.go"
+)
+
+type heartbeatListener struct {
+	client         natsconn.ConnectionManager
+	subject        string
+	queueGroupName string
+	workerService  app.WorkerService
+}
+
+// NewHeartbeatListener records worker heartbeats. They are sent on the plain
+// NATS connection, so a missed heartbeat is simply lost, never replayed.
+func NewHeartbeatListener(client natsconn.ConnectionManager, subject, queueGroupName string, workerService app.WorkerService) JobEventListenerInterface {
+	return &heartbeatListener{
+		client:         client,
+		subject:        subject,
+		queueGroupName: queueGroupName,
+		workerService:  workerService,
+	}
+}
+
+func (hl *heartbeatListener) Listen() {
+	err := hl.client.Subscribe(func(conn stan.Conn) error {
+		_, err := conn.NatsConn().QueueSubscribe(hl.subject, hl.queueGroupName, hl.msgHandler)
+		return err
+	})
+
+	if err != nil {
+		log.Fatalf("heartbeat listener subscribe error: %v\n", err)
+	}
+}
+
+func (hl *heartbeatListener) msgHandler(msg *nats.Msg) {
+	heartbeat, err := contracts.DecodeWorkerHeartbeatMessage(msg.Header, msg.Data)
+	if err != nil {
+		log.Printf("could not decode heartbeat: %v\n", err.Error())
+		return
+	}
+
+	worker := domain.Worker{
+		WorkerId:  heartbeat.WorkerId,
+		Capacity:  heartbeat.Capacity,
+		Busy:      heartbeat.Busy,
+		JobTypes:  heartbeat.JobTypes,
+		Version:   heartbeat.Version,
+		StartedAt: heartbeat.StartedAt,
+	}
+
+	if err := hl.workerService.RecordHeartbeat(&worker); err != nil {
+		log.Printf("could not record heartbeat of worker %v: %v\n", heartbeat.WorkerId, err.Error())
+	}
+}
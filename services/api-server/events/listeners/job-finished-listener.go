@@ -1,10 +1,11 @@
 package listeners
 
 import (
-	"encoding/json"
 	"log"
 	"time"
 
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/natsconn"
 	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
 	"github.com/bogdan-copocean/hasty-server/services/api-server/events"
 	"github.com/nats-io/stan.go"
@@ -15,18 +16,29 @@ type JobEventListenerInterface interface {
 }
 
 type jobEventListener struct {
-	client         stan.Conn
-	subject        string
-	queueGroupName string
-	apiService     app.ApiService
+	client            natsconn.ConnectionManager
+	subject           string
+	queueGroupName    string
+	durableName       string
+	apiService        app.ApiService
+	workflowService   app.WorkflowService
+	quarantineService app.QuarantineService
+	resultService     app.ResultService
 }
 
-func NewJobEventListener(client stan.Conn, subject, queueGroupName string, apiService app.ApiService) JobEventListenerInterface {
+// NewJobEventListener subscribes to subject as queueGroupName. Every
+// listener needs a durableName of its own, it is where NATS Streaming keeps
+// the group's position in the channel.
+func NewJobEventListener(client natsconn.ConnectionManager, subject, queueGroupName, durableName string, apiService app.ApiService, workflowService app.WorkflowService, quarantineService app.QuarantineService, resultService app.ResultService) JobEventListenerInterface {
 	return &jobEventListener{
-		client:         client,
-		queueGroupName: queueGroupName,
-		subject:        subject,
-		apiService:     apiService,
+		client:            client,
+		queueGroupName:    queueGroupName,
+		durableName:       durableName,
+		subject:           subject,
+		apiService:        apiService,
+		workflowService:   workflowService,
+		quarantineService: quarantineService,
+		resultService:     resultService,
 	}
 }
 
@@ -34,30 +46,65 @@ func (nl *jobEventListener) Listen() {
 
 	aw, _ := time.ParseDuration("50s")
 
-	_, err := nl.client.QueueSubscribe(nl.subject, nl.queueGroupName, func(msg *stan.Msg) {
-		go msgHandler(msg, nl.apiService)
-	},
-		stan.SetManualAckMode(),
-		stan.AckWait(aw),
-		stan.DeliverAllAvailable(),
-		stan.DurableName("job-created-durable-name"),
-	)
+	err := nl.client.Subscribe(func(conn stan.Conn) error {
+		_, err := conn.QueueSubscribe(nl.subject, nl.queueGroupName, func(msg *stan.Msg) {
+			go msgHandler(msg, nl.apiService, nl.workflowService, nl.quarantineService, nl.resultService)
+		},
+			stan.SetManualAckMode(),
+			stan.AckWait(aw),
+			stan.DeliverAllAvailable(),
+			stan.DurableName(nl.durableName),
+		)
+		return err
+	})
 
 	if err != nil {
 		log.Fatalf("job finished listener subscribe error: %v\n", err)
 	}
 }
 
-func msgHandler(msg *stan.Msg, apiService app.ApiService) {
-	jobEvent := events.JobEvent{}
+// msgHandler never stops the process. Messages that cannot be decoded, or
+// have a schema version this server does not know, are quarantined and
+// acked, since redelivering them cannot help. Anything else that fails is
+// left unacked, so the message is redelivered after AckWait.
+func msgHandler(msg *stan.Msg, apiService app.ApiService, workflowService app.WorkflowService, quarantineService app.QuarantineService, resultService app.ResultService) {
+	jobEvent, err := contracts.DecodeJobEventMessage(nil, msg.Data)
+	if err != nil {
+		quarantined, qErr := quarantineService.Quarantine(msg.Subject, msg.Sequence, msg.Data, err)
+		if qErr != nil {
+			log.Printf("could not quarantine msg %v on %v: %v\n", msg.Sequence, msg.Subject, qErr.Error())
+			return
+		}
+
+		log.Printf("quarantined msg %v on %v as %v: %v\n", msg.Sequence, msg.Subject, quarantined.MessageId, err.Error())
+		msg.Ack()
+		return
+	}
+
+	job := events.JobFromEvent(jobEvent)
 
-	err := json.Unmarshal(msg.Data, &jobEvent)
+	updated, err := apiService.UpdateJob(job)
 	if err != nil {
-		log.Fatalf("could not unmarshal msg: %v\n", err.Error())
+		log.Printf("could not update job %v, waiting for redelivery: %v\n", job.JobId, err.Error())
+		return
+	}
+
+	if result := events.ResultFromEvent(jobEvent); result != nil {
+		// A job no longer processing, e.g. cancelled through the api
+		// meanwhile, keeps no result
+		save := resultService.SaveResult
+		if !updated {
+			save = resultService.DiscardResult
+		}
+
+		if err := save(result); err != nil {
+			log.Printf("could not save the result of job %v, waiting for redelivery: %v\n", job.JobId, err.Error())
+			return
+		}
 	}
 
-	if err := apiService.UpdateJob(jobEvent.Job); err != nil {
-		log.Fatalf("could not update to repo: %v\n", err.Error())
+	if err := workflowService.AdvanceWorkflow(job.TenantId, job.JobId); err != nil {
+		log.Printf("could not advance workflow for job %v: %v\n", job.JobId, err.Error())
 	}
 
 	msg.Ack()
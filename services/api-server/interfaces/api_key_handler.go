@@ -0,0 +1,89 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/unrolled/render"
+)
+
+type ApiKeyHandlerInterface interface {
+	PostHandler(w http.ResponseWriter, r *http.Request)
+	GetAllHandler(w http.ResponseWriter, r *http.Request)
+	RotateHandler(w http.ResponseWriter, r *http.Request)
+	RevokeHandler(w http.ResponseWriter, r *http.Request)
+}
+
+type apiKeyHandler struct {
+	apiKeyService app.ApiKeyService
+}
+
+func NewApiKeyHandler(apiKeyService app.ApiKeyService) ApiKeyHandlerInterface {
+	return &apiKeyHandler{apiKeyService: apiKeyService}
+}
+
+func (handler *apiKeyHandler) PostHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	apiKeyRequest := domain.ApiKeyRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&apiKeyRequest); err != nil {
+		writeProblem(w, r, app.NewError(app.ErrInvalidRequest, "%v", err.Error()))
+		return
+	}
+
+	issuedKey, err := handler.apiKeyService.IssueKey(tenantIdFromContext(r.Context()), &apiKeyRequest)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	render.JSON(w, http.StatusCreated, map[string]interface{}{
+		"message": issuedKey,
+	})
+}
+
+func (handler *apiKeyHandler) GetAllHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	apiKeys, err := handler.apiKeyService.GetKeys(tenantIdFromContext(r.Context()))
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": apiKeys,
+	})
+}
+
+func (handler *apiKeyHandler) RotateHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	issuedKey, err := handler.apiKeyService.RotateKey(tenantIdFromContext(r.Context()), chi.URLParam(r, "keyId"))
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": issuedKey,
+	})
+}
+
+func (handler *apiKeyHandler) RevokeHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	apiKey, err := handler.apiKeyService.RevokeKey(tenantIdFromContext(r.Context()), chi.URLParam(r, "keyId"))
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": apiKey,
+	})
+}
@@ -0,0 +1,72 @@
+package interfaces
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+)
+
+type contextKey string
+
+const apiKeyContextKey contextKey = "apiKey"
+
+// Authenticate resolves the api key sent as "Authorization: Bearer <key>"
+// or "X-API-Key: <key>" and stores it in the request context.
+func Authenticate(apiKeyService app.ApiKeyService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("X-API-Key")
+			if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+				key = strings.TrimPrefix(auth, "Bearer ")
+			}
+
+			apiKey, err := apiKeyService.Authenticate(key)
+			if err != nil {
+				writeProblem(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey, apiKey)))
+		})
+	}
+}
+
+// RequireScope rejects requests whose api key does not grant scope. It must
+// run after Authenticate.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			apiKey := ApiKeyFromContext(r.Context())
+			if apiKey == nil || !apiKey.HasScope(scope) {
+				writeProblem(w, r, app.NewError(app.ErrForbidden, "api key is missing the %v scope", scope))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ApiKeyFromContext(ctx context.Context) *domain.ApiKey {
+	apiKey, _ := ctx.Value(apiKeyContextKey).(*domain.ApiKey)
+	return apiKey
+}
+
+func apiKeyIdFromContext(ctx context.Context) string {
+	if apiKey := ApiKeyFromContext(ctx); apiKey != nil {
+		return apiKey.KeyId
+	}
+	return ""
+}
+
+// tenantIdFromContext returns the tenant of the calling api key, which
+// scopes everything the request can read or create.
+func tenantIdFromContext(ctx context.Context) string {
+	if apiKey := ApiKeyFromContext(ctx); apiKey != nil && apiKey.TenantId != "" {
+		return apiKey.TenantId
+	}
+	return domain.DefaultTenantId
+}
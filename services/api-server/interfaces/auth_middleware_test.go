@@ -0,0 +1,171 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+)
+
+func issueTestKey(t *testing.T, apiKeyService app.ApiKeyService, scopes ...string) *domain.IssuedApiKey {
+	t.Helper()
+
+	issued, err := apiKeyService.IssueKey(domain.OperatorTenantId, &domain.ApiKeyRequest{TenantId: domain.DefaultTenantId, Scopes: scopes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return issued
+}
+
+func TestRoutesRequireTheirScope(t *testing.T) {
+	apiKeyService := app.NewApiKeyService(repository.NewMemoryApiKeyRepository())
+	router := newTestRouter(repository.NewMemoryRepository(), repository.NewMemoryResultRepository(), apiKeyService)
+
+	reader := issueTestKey(t, apiKeyService, domain.ScopeJobsRead)
+	writer := issueTestKey(t, apiKeyService, domain.ScopeJobsWrite)
+	admin := issueTestKey(t, apiKeyService, domain.ScopeAdmin)
+	revoked := issueTestKey(t, apiKeyService, domain.ScopeAdmin)
+	if _, err := apiKeyService.RevokeKey(domain.OperatorTenantId, revoked.ApiKey.KeyId); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		headers map[string]string
+		status  int
+	}{
+		{name: "health is public", method: http.MethodGet, path: "/healthz", status: http.StatusOK},
+		{name: "missing key", method: http.MethodGet, path: "/", status: http.StatusUnauthorized},
+		{name: "unknown key", method: http.MethodGet, path: "/", headers: map[string]string{"X-API-Key": "hsk_unknown"}, status: http.StatusUnauthorized},
+		{name: "revoked key", method: http.MethodGet, path: "/", headers: map[string]string{"X-API-Key": revoked.Key}, status: http.StatusUnauthorized},
+		{name: "read with X-API-Key", method: http.MethodGet, path: "/", headers: map[string]string{"X-API-Key": reader.Key}, status: http.StatusOK},
+		{name: "read with a bearer token", method: http.MethodGet, path: "/", headers: map[string]string{"Authorization": "Bearer " + reader.Key}, status: http.StatusOK},
+		{name: "bearer token wins over X-API-Key", method: http.MethodGet, path: "/", headers: map[string]string{"Authorization": "Bearer " + reader.Key, "X-API-Key": "hsk_unknown"}, status: http.StatusOK},
+		{name: "write needs jobs:write", method: http.MethodPost, path: "/", body: `{"object_id": "object-1"}`, headers: map[string]string{"X-API-Key": reader.Key}, status: http.StatusForbidden},
+		{name: "write", method: http.MethodPost, path: "/", body: `{"object_id": "object-1"}`, headers: map[string]string{"X-API-Key": writer.Key}, status: http.StatusCreated},
+		{name: "read needs jobs:read", method: http.MethodGet, path: "/", headers: map[string]string{"X-API-Key": writer.Key}, status: http.StatusForbidden},
+		{name: "schedules need jobs:write", method: http.MethodPost, path: "/schedules", body: `{"object_id": "object-1", "cron_expr": "* * * * *"}`, headers: map[string]string{"X-API-Key": reader.Key}, status: http.StatusForbidden},
+		{name: "admin routes need admin", method: http.MethodGet, path: "/admin/keys", headers: map[string]string{"X-API-Key": writer.Key}, status: http.StatusForbidden},
+		{name: "admin", method: http.MethodGet, path: "/admin/keys", headers: map[string]string{"X-API-Key": admin.Key}, status: http.StatusOK},
+		{name: "admin grants jobs:read", method: http.MethodGet, path: "/", headers: map[string]string{"X-API-Key": admin.Key}, status: http.StatusOK},
+		{name: "admin grants jobs:write", method: http.MethodPost, path: "/", body: `{"object_id": "object-2"}`, headers: map[string]string{"X-API-Key": admin.Key}, status: http.StatusCreated},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			for name, value := range test.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != test.status {
+				t.Errorf("expected %v, got %v: %v", test.status, rec.Code, rec.Body.String())
+			}
+			if rec.Code >= 400 && !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/problem+json") {
+				t.Errorf("expected a problem, got %v", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestJobsRecordTheKeyThatCreatedThem(t *testing.T) {
+	apiKeyService := app.NewApiKeyService(repository.NewMemoryApiKeyRepository())
+	repo := repository.NewMemoryRepository()
+	router := newTestRouter(repo, repository.NewMemoryResultRepository(), apiKeyService)
+
+	writer := issueTestKey(t, apiKeyService, domain.ScopeJobsRead, domain.ScopeJobsWrite)
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"object_id": "object-1"}`))
+	req.Header.Set("X-API-Key", writer.Key)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	created := struct {
+		Message domain.ResponseJob `json:"message"`
+	}{}
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("expected a created job, got %v: %v", rec.Code, err)
+	}
+
+	stored, err := repo.GetJobByJobId(domain.DefaultTenantId, created.Message.JobId)
+	if err != nil || stored.ApiKeyId != writer.ApiKey.KeyId {
+		t.Errorf("expected the job to record key %v, got %+v: %v", writer.ApiKey.KeyId, stored, err)
+	}
+}
+
+func TestAdminKeysOnlyAdministerTheirTenant(t *testing.T) {
+	apiKeyService := app.NewApiKeyService(repository.NewMemoryApiKeyRepository())
+	router := newTestRouter(repository.NewMemoryRepository(), repository.NewMemoryResultRepository(), apiKeyService)
+
+	issued := map[string]*domain.IssuedApiKey{}
+	for _, tenantId := range []string{"tenant-a", "tenant-b"} {
+		key, err := apiKeyService.IssueKey(domain.OperatorTenantId, &domain.ApiKeyRequest{TenantId: tenantId, Scopes: []string{domain.ScopeAdmin}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		issued[tenantId] = key
+	}
+	adminOfA := issued["tenant-a"].Key
+	keyOfB := issued["tenant-b"].ApiKey.KeyId
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "issue a key of another tenant", method: http.MethodPost, path: "/admin/keys", body: `{"tenant_id": "tenant-b", "scopes": ["admin"]}`, status: http.StatusForbidden},
+		{name: "rotate a key of another tenant", method: http.MethodPost, path: "/admin/keys/" + keyOfB + "/rotate", status: http.StatusNotFound},
+		{name: "revoke a key of another tenant", method: http.MethodDelete, path: "/admin/keys/" + keyOfB, status: http.StatusNotFound},
+		{name: "get another tenant", method: http.MethodGet, path: "/admin/tenants/tenant-b", status: http.StatusForbidden},
+		{name: "set another tenant", method: http.MethodPut, path: "/admin/tenants/tenant-b", body: `{"max_daily_jobs": 1}`, status: http.StatusForbidden},
+		{name: "issue a key of its own tenant", method: http.MethodPost, path: "/admin/keys", body: `{"tenant_id": "tenant-a", "scopes": ["jobs:read"]}`, status: http.StatusCreated},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			req.Header.Set("X-API-Key", adminOfA)
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != test.status {
+				t.Errorf("expected %v, got %v: %v", test.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	if _, err := apiKeyService.Authenticate(issued["tenant-b"].Key); err != nil {
+		t.Errorf("expected the key of tenant-b to still work, got %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/keys", nil)
+	req.Header.Set("X-API-Key", adminOfA)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	listed := struct {
+		Message []*domain.ApiKey `json:"message"`
+	}{}
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil {
+		t.Fatal(err)
+	}
+	for _, apiKey := range listed.Message {
+		if apiKey.TenantId != "tenant-a" {
+			t.Errorf("expected only keys of tenant-a, got one of %v", apiKey.TenantId)
+		}
+	}
+}
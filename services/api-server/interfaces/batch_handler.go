@@ -0,0 +1,76 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/unrolled/render"
+)
+
+type BatchHandlerInterface interface {
+	PostHandler(w http.ResponseWriter, r *http.Request)
+	GetHandler(w http.ResponseWriter, r *http.Request)
+	CancelHandler(w http.ResponseWriter, r *http.Request)
+}
+
+type batchHandler struct {
+	batchService app.BatchService
+}
+
+func NewBatchHandler(batchService app.BatchService) BatchHandlerInterface {
+	return &batchHandler{batchService: batchService}
+}
+
+func (handler *batchHandler) PostHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	batchRequest := domain.BatchRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&batchRequest); err != nil {
+		writeProblem(w, r, app.NewError(app.ErrInvalidRequest, "%v", err.Error()))
+		return
+	}
+	batchRequest.ApiKeyId = apiKeyIdFromContext(r.Context())
+	batchRequest.TenantId = tenantIdFromContext(r.Context())
+
+	batch, err := handler.batchService.SubmitBatch(&batchRequest)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	render.JSON(w, http.StatusCreated, map[string]interface{}{
+		"message": batch,
+	})
+}
+
+func (handler *batchHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	batch, err := handler.batchService.GetBatch(tenantIdFromContext(r.Context()), chi.URLParam(r, "batchId"))
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": batch,
+	})
+}
+
+func (handler *batchHandler) CancelHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	batch, err := handler.batchService.CancelBatch(tenantIdFromContext(r.Context()), chi.URLParam(r, "batchId"))
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": batch,
+	})
+}
@@ -0,0 +1,26 @@
+package interfaces
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+)
+
+// RejectWhileDatabaseDown answers 503 with Retry-After while the mongo
+// circuit breaker is open, instead of letting requests wait for timeouts.
+func RejectWhileDatabaseDown(breaker repository.CircuitBreaker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ok, retryAfter := breaker.Allow(); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				writeProblem(w, r, app.NewError(app.ErrDatabaseUnavailable, "the database is unavailable, try again later"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package interfaces
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//go:embed openapi.json
+var openApiSpec []byte
+
+//go:embed docs.html
+var docsPage []byte
+
+type DocsHandlerInterface interface {
+	SpecHandler(w http.ResponseWriter, r *http.Request)
+	UIHandler(w http.ResponseWriter, r *http.Request)
+}
+
+type docsHandler struct{}
+
+func NewDocsHandler() DocsHandlerInterface {
+	return &docsHandler{}
+}
+
+// SpecHandler serves the OpenAPI document embedded at build time.
+func (handler *docsHandler) SpecHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openApiSpec)
+}
+
+// UIHandler serves a page that renders /openapi.json with Redoc.
+func (handler *docsHandler) UIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(docsPage)
+}
@@ -0,0 +1,268 @@
+package interfaces
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/events/publishers"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/jobspb"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// methodScopes is the scope every gRPC method needs, like the REST routes.
+var methodScopes = map[string]string{
+	"/hasty.jobs.v1.Jobs/CreateJob": domain.ScopeJobsWrite,
+	"/hasty.jobs.v1.Jobs/GetJob":    domain.ScopeJobsRead,
+	"/hasty.jobs.v1.Jobs/ListJobs":  domain.ScopeJobsRead,
+	"/hasty.jobs.v1.Jobs/CancelJob": domain.ScopeJobsWrite,
+	"/hasty.jobs.v1.Jobs/WatchJob":  domain.ScopeJobsRead,
+}
+
+var grpcCodes = []struct {
+	err  error
+	code codes.Code
+}{
+	{app.ErrInvalidRequest, codes.InvalidArgument},
+	{app.ErrJobNotFound, codes.NotFound},
+	{app.ErrCooldownActive, codes.ResourceExhausted},
+	{app.ErrJobInProgress, codes.FailedPrecondition},
+	{app.ErrObjectBusy, codes.Aborted},
+	{app.ErrQuotaExceeded, codes.ResourceExhausted},
+	{app.ErrJobFinished, codes.FailedPrecondition},
+	{app.ErrPublishFailed, codes.Unavailable},
+	{app.ErrDatabaseUnavailable, codes.Unavailable},
+}
+
+type jobsServer struct {
+	jobspb.UnimplementedJobsServer
+	apiService         app.ApiService
+	jobEventPublisher  publishers.JobEventPublisher
+	jobCancelPublisher publishers.JobEventPublisher
+}
+
+func NewJobsServer(apiService app.ApiService, jobEventPublisher, jobCancelPublisher publishers.JobEventPublisher) jobspb.JobsServer {
+	return &jobsServer{apiService: apiService, jobEventPublisher: jobEventPublisher, jobCancelPublisher: jobCancelPublisher}
+}
+
+// NewGrpcServer serves jobsServer with the same api keys, scopes and
+// database circuit breaker as the REST api, and gRPC reflection for tools
+// like grpcurl.
+func NewGrpcServer(jobsServer jobspb.JobsServer, apiKeyService app.ApiKeyService, breaker repository.CircuitBreaker) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			ctx, err := authorizeCall(ctx, info.FullMethod, apiKeyService, breaker)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := authorizeCall(stream.Context(), info.FullMethod, apiKeyService, breaker)
+			if err != nil {
+				return err
+			}
+			return handler(srv, &authorizedStream{ServerStream: stream, ctx: ctx})
+		}),
+	)
+
+	jobspb.RegisterJobsServer(server, jobsServer)
+	reflection.Register(server)
+
+	return server
+}
+
+// authorizeCall is Authenticate, RequireScope and RejectWhileDatabaseDown
+// for gRPC. Reflection needs no api key.
+func authorizeCall(ctx context.Context, method string, apiKeyService app.ApiKeyService, breaker repository.CircuitBreaker) (context.Context, error) {
+	scope, ok := methodScopes[method]
+	if !ok {
+		return ctx, nil
+	}
+
+	if ok, retryAfter := breaker.Allow(); !ok {
+		grpc.SetTrailer(ctx, metadata.Pairs("retry-after", strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))))
+		return nil, status.Error(codes.Unavailable, "the database is unavailable, try again later")
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	key := ""
+	if values := md.Get("x-api-key"); len(values) > 0 {
+		key = values[0]
+	}
+	if values := md.Get("authorization"); len(values) > 0 && strings.HasPrefix(values[0], "Bearer ") {
+		key = strings.TrimPrefix(values[0], "Bearer ")
+	}
+
+	// Only a missing, unknown or revoked key is unauthenticated, a key that
+	// could not be looked up is the server's error
+	apiKey, err := apiKeyService.Authenticate(key)
+	if errors.Is(err, app.ErrUnauthorized) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+
+	if !apiKey.HasScope(scope) {
+		return nil, status.Error(codes.PermissionDenied, "api key is missing the "+scope+" scope")
+	}
+
+	return context.WithValue(ctx, apiKeyContextKey, apiKey), nil
+}
+
+// authorizedStream carries the api key to stream handlers.
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+// grpcError maps the service errors to gRPC status codes, with the REST
+// error code and Retry-After sent as trailers.
+func grpcError(ctx context.Context, err error) error {
+	code := codes.Internal
+	errorCode := app.ErrInternal.Error()
+	for _, kind := range grpcCodes {
+		if errors.Is(err, kind.err) {
+			code = kind.code
+			errorCode = kind.err.Error()
+			break
+		}
+	}
+
+	trailer := metadata.Pairs("error-code", errorCode)
+
+	rejected := &app.RerunRejectedError{}
+	if errors.As(err, &rejected) && rejected.RetryAfter > 0 {
+		wait := rejected.RetryAfter - time.Now().Unix()
+		if wait < 1 {
+			wait = 1
+		}
+		trailer.Set("retry-after", strconv.FormatInt(wait, 10))
+	}
+
+	if errors.Is(err, app.ErrPublishFailed) {
+		trailer.Set("retry-after", strconv.Itoa(int(publishRetryAfter.Seconds())))
+	}
+
+	grpc.SetTrailer(ctx, trailer)
+
+	if code == codes.Internal {
+		// Internal details stay in the log
+		log.Printf("grpc: %v\n", err.Error())
+		return status.Error(code, "internal server error")
+	}
+
+	return status.Error(code, err.Error())
+}
+
+func (server *jobsServer) CreateJob(ctx context.Context, req *jobspb.CreateJobRequest) (*jobspb.CreateJobResponse, error) {
+	job, created, err := createJob(server.apiService, server.jobEventPublisher, &domain.JobRequest{
+		ObjectId: req.ObjectId,
+		Type:     req.Type,
+		Params:   req.Params,
+		ApiKeyId: apiKeyIdFromContext(ctx),
+		TenantId: tenantIdFromContext(ctx),
+	})
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+
+	return &jobspb.CreateJobResponse{JobId: job.JobId, Created: created}, nil
+}
+
+func (server *jobsServer) GetJob(ctx context.Context, req *jobspb.GetJobRequest) (*jobspb.Job, error) {
+	job, err := server.apiService.GetJob(tenantIdFromContext(ctx), req.JobId)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+
+	return jobToProto(job), nil
+}
+
+func (server *jobsServer) ListJobs(ctx context.Context, req *jobspb.ListJobsRequest) (*jobspb.ListJobsResponse, error) {
+	jobs, err := server.apiService.ListJobs(domain.JobFilter{
+		TenantId: tenantIdFromContext(ctx),
+		ObjectId: req.ObjectId,
+		Status:   req.Status,
+		Limit:    req.Limit,
+	})
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+
+	res := &jobspb.ListJobsResponse{Jobs: []*jobspb.Job{}}
+	for _, job := range jobs {
+		res.Jobs = append(res.Jobs, jobToProto(job))
+	}
+
+	return res, nil
+}
+
+func (server *jobsServer) CancelJob(ctx context.Context, req *jobspb.CancelJobRequest) (*jobspb.Job, error) {
+	job, err := cancelJob(server.apiService, server.jobCancelPublisher, tenantIdFromContext(ctx), req.JobId)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+
+	return jobToProto(job), nil
+}
+
+func (server *jobsServer) WatchJob(req *jobspb.WatchJobRequest, stream jobspb.Jobs_WatchJobServer) error {
+	ctx := stream.Context()
+
+	job, err := server.apiService.GetJob(tenantIdFromContext(ctx), req.JobId)
+	if err != nil {
+		return grpcError(ctx, err)
+	}
+
+	send := func(job *domain.Job) error {
+		return stream.Send(jobToProto(job))
+	}
+
+	// gRPC keeps the connection alive on its own
+	idle := func() error { return nil }
+
+	if err := watchJob(ctx, server.apiService, job, send, idle); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return grpcError(ctx, err)
+	}
+
+	return nil
+}
+
+func jobToProto(job *domain.Job) *jobspb.Job {
+	return &jobspb.Job{
+		JobId:         job.JobId,
+		TenantId:      job.TenantId,
+		ObjectId:      job.ObjectId,
+		Type:          job.Type,
+		Params:        job.Params,
+		Status:        job.Status,
+		Timestamp:     job.Timestamp,
+		CreatedAt:     job.CreatedAt,
+		SleepTimeUsed: int32(job.SleepTimeUsed),
+		ScheduleId:    job.ScheduleId,
+		WorkflowId:    job.WorkflowId,
+		BatchId:       job.BatchId,
+		ApiKeyId:      job.ApiKeyId,
+		WorkerId:      job.WorkerId,
+	}
+}
@@ -0,0 +1,155 @@
+package interfaces
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/jobspb"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// scopedKeyService accepts "writer" and "reader" keys, and fails to look
+// up "unreachable" and "down".
+type scopedKeyService struct {
+	app.ApiKeyService
+}
+
+func (scopedKeyService) Authenticate(key string) (*domain.ApiKey, error) {
+	switch key {
+	case "writer":
+		return &domain.ApiKey{KeyId: "writer", TenantId: domain.DefaultTenantId, Scopes: []string{domain.ScopeJobsRead, domain.ScopeJobsWrite}}, nil
+	case "reader":
+		return &domain.ApiKey{KeyId: "reader", TenantId: domain.DefaultTenantId, Scopes: []string{domain.ScopeJobsRead}}, nil
+	case "unreachable":
+		return nil, app.NewError(app.ErrInternal, "could not get api key from mongo: %v", errors.New("connection refused"))
+	case "down":
+		return nil, app.NewError(app.ErrDatabaseUnavailable, "the database is unavailable, try again later")
+	}
+	return nil, app.NewError(app.ErrUnauthorized, "invalid api key")
+}
+
+func newTestJobsClient(t *testing.T) jobspb.JobsClient {
+	t.Helper()
+
+	service := app.NewApiService(repository.NewMemoryRepository(), unlimitedTenantService{}, app.RerunPolicies{
+		domain.DefaultJobType: app.NewFixedCooldownPolicy(app.DefaultCooldown),
+	})
+	server := NewGrpcServer(NewJobsServer(service, discardPublisher{}, discardPublisher{}), scopedKeyService{}, repository.NewCircuitBreaker(3, 10*time.Second))
+
+	lis := bufconn.Listen(1024 * 1024)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return jobspb.NewJobsClient(conn)
+}
+
+func withKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+}
+
+func TestGrpcJobs(t *testing.T) {
+	jobs := newTestJobsClient(t)
+	ctx := withKey("writer")
+
+	created, err := jobs.CreateJob(ctx, &jobspb.CreateJobRequest{ObjectId: "object-1", Params: map[string]string{"size": "small"}})
+	if err != nil || !created.Created {
+		t.Fatalf("expected a created job, got %v: %v", created, err)
+	}
+
+	var trailer metadata.MD
+	_, err = jobs.CreateJob(ctx, &jobspb.CreateJobRequest{ObjectId: "object-1"}, grpc.Trailer(&trailer))
+	if status.Code(err) != codes.FailedPrecondition || trailer.Get("error-code")[0] != "job_in_progress" || len(trailer.Get("retry-after")) != 0 {
+		t.Errorf("expected the running job to reject the rerun, got %v %v", err, trailer)
+	}
+
+	job, err := jobs.GetJob(ctx, &jobspb.GetJobRequest{JobId: created.JobId})
+	if err != nil || job.Status != domain.JobProcessing || job.Params["size"] != "small" || job.ApiKeyId != "writer" {
+		t.Errorf("unexpected job %v: %v", job, err)
+	}
+
+	list, err := jobs.ListJobs(ctx, &jobspb.ListJobsRequest{Status: domain.JobProcessing})
+	if err != nil || len(list.Jobs) != 1 || list.Jobs[0].JobId != created.JobId {
+		t.Errorf("expected the job to be listed, got %v: %v", list, err)
+	}
+
+	if _, err := jobs.ListJobs(ctx, &jobspb.ListJobsRequest{Status: "unknown"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected an invalid argument, got %v", err)
+	}
+
+	stream, err := jobs.WatchJob(ctx, &jobspb.WatchJobRequest{JobId: created.JobId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if watched, err := stream.Recv(); err != nil || watched.Status != domain.JobProcessing {
+		t.Fatalf("expected the job right away, got %v: %v", watched, err)
+	}
+
+	if cancelled, err := jobs.CancelJob(ctx, &jobspb.CancelJobRequest{JobId: created.JobId}); err != nil || cancelled.Status != domain.JobCancelled {
+		t.Fatalf("expected the job to be cancelled, got %v: %v", cancelled, err)
+	}
+
+	if watched, err := stream.Recv(); err != nil || watched.Status != domain.JobCancelled {
+		t.Errorf("expected the cancelled job, got %v: %v", watched, err)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("expected the stream to end, got %v", err)
+	}
+
+	if _, err := jobs.CancelJob(ctx, &jobspb.CancelJobRequest{JobId: created.JobId}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected the finished job to fail the cancel, got %v", err)
+	}
+
+	trailer = nil
+	_, err = jobs.CreateJob(ctx, &jobspb.CreateJobRequest{ObjectId: "object-1"}, grpc.Trailer(&trailer))
+	if status.Code(err) != codes.ResourceExhausted || trailer.Get("error-code")[0] != "cooldown_active" || len(trailer.Get("retry-after")) != 1 {
+		t.Errorf("expected the cooldown with a retry-after, got %v %v", err, trailer)
+	}
+
+	if _, err := jobs.GetJob(ctx, &jobspb.GetJobRequest{JobId: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestGrpcAuthorization(t *testing.T) {
+	jobs := newTestJobsClient(t)
+
+	if _, err := jobs.GetJob(context.Background(), &jobspb.GetJobRequest{JobId: "job-1"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected a missing key to be rejected, got %v", err)
+	}
+
+	if _, err := jobs.GetJob(withKey("unreachable"), &jobspb.GetJobRequest{JobId: "job-1"}); status.Code(err) != codes.Internal {
+		t.Errorf("expected a key that could not be looked up to be an internal error, got %v", err)
+	}
+
+	if _, err := jobs.GetJob(withKey("down"), &jobspb.GetJobRequest{JobId: "job-1"}); status.Code(err) != codes.Unavailable {
+		t.Errorf("expected an unavailable database to be unavailable, got %v", err)
+	}
+
+	if _, err := jobs.CreateJob(withKey("reader"), &jobspb.CreateJobRequest{ObjectId: "object-1"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected a read key to be denied, got %v", err)
+	}
+
+	bearer := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer reader")
+	if _, err := jobs.ListJobs(bearer, &jobspb.ListJobsRequest{}); err != nil {
+		t.Errorf("expected a bearer read key to list jobs, got %v", err)
+	}
+}
@@ -2,11 +2,14 @@ package interfaces
 
 import (
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
 	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
-	"github.com/bogdan-copocean/hasty-server/services/api-server/events"
 	"github.com/bogdan-copocean/hasty-server/services/api-server/events/publishers"
 	"github.com/go-chi/chi/v5"
 	"github.com/unrolled/render"
@@ -15,57 +18,52 @@ import (
 type ApiHandlerInterface interface {
 	PostHandler(w http.ResponseWriter, r *http.Request)
 	GetHandler(w http.ResponseWriter, r *http.Request)
+	ListHandler(w http.ResponseWriter, r *http.Request)
+	CancelHandler(w http.ResponseWriter, r *http.Request)
+	WatchHandler(w http.ResponseWriter, r *http.Request)
 }
 
 type apiHandler struct {
-	apiService        app.ApiService
-	jobEventPublisher publishers.JobEventPublisher
+	apiService         app.ApiService
+	jobEventPublisher  publishers.JobEventPublisher
+	jobCancelPublisher publishers.JobEventPublisher
 }
 
-func NewApiHandler(apiService app.ApiService, jobEventPublisher publishers.JobEventPublisher) ApiHandlerInterface {
-	return &apiHandler{apiService: apiService, jobEventPublisher: jobEventPublisher}
+// NewApiHandler publishes new jobs with jobEventPublisher and jobs cancelled
+// through the api with jobCancelPublisher, so workflows skip what depends on
+// them.
+func NewApiHandler(apiService app.ApiService, jobEventPublisher, jobCancelPublisher publishers.JobEventPublisher) ApiHandlerInterface {
+	return &apiHandler{apiService: apiService, jobEventPublisher: jobEventPublisher, jobCancelPublisher: jobCancelPublisher}
 }
 
+// WatchKeepAlive is the longest a watch stream stays silent, so proxies do
+// not close it while a job runs.
+const WatchKeepAlive = 15 * time.Second
+
+// PostHandler answers errors with application/problem+json, see
+// writeProblem for the status of every error code.
 func (handler *apiHandler) PostHandler(w http.ResponseWriter, r *http.Request) {
 	render := render.New()
 	w.Header().Set("Content-Type", "application/json")
 
-	objectIdMap := map[string]string{}
+	jobRequest := domain.JobRequest{}
 
-	if err := json.NewDecoder(r.Body).Decode(&objectIdMap); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		render.JSON(w, http.StatusBadRequest, map[string]string{
-			"message": err.Error(),
-		})
-		return
-	}
-	objectId, ok := objectIdMap["object_id"]
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		render.JSON(w, http.StatusBadRequest, map[string]string{
-			"message": "you must provide an object_id",
-		})
+	if err := json.NewDecoder(r.Body).Decode(&jobRequest); err != nil {
+		writeProblem(w, r, app.NewError(app.ErrInvalidRequest, "%v", err.Error()))
 		return
 	}
+	jobRequest.ApiKeyId = apiKeyIdFromContext(r.Context())
+	jobRequest.TenantId = tenantIdFromContext(r.Context())
 
-	job, err := handler.apiService.ProcessJob(objectId)
+	job, created, err := createJob(handler.apiService, handler.jobEventPublisher, &jobRequest)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		render.JSON(w, http.StatusBadRequest, map[string]string{
-			"message": err.Error(),
-		})
+		writeProblem(w, r, err)
 		return
 	}
 
-	eventJob := events.JobEvent{
-		Subject: "job:created",
-		Job:     job,
-	}
-
-	if err := handler.jobEventPublisher.PublishData(&eventJob); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		render.JSON(w, http.StatusBadRequest, map[string]string{
-			"message": err.Error(),
+	if !created {
+		render.JSON(w, http.StatusOK, map[string]interface{}{
+			"message": domain.ResponseJob{JobId: job.JobId},
 		})
 		return
 	}
@@ -83,12 +81,9 @@ func (handler *apiHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
 
 	jobId := chi.URLParam(r, "jobId")
 
-	job, err := handler.apiService.GetJob(jobId)
+	job, err := handler.apiService.GetJob(tenantIdFromContext(r.Context()), jobId)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		render.JSON(w, http.StatusBadRequest, map[string]string{
-			"message": err.Error(),
-		})
+		writeProblem(w, r, err)
 		return
 	}
 
@@ -97,3 +92,100 @@ func (handler *apiHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
 		"message": job,
 	})
 }
+
+// ListHandler filters with the status, object_id and limit query parameters.
+func (handler *apiHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	query := r.URL.Query()
+	filter := domain.JobFilter{
+		TenantId: tenantIdFromContext(r.Context()),
+		ObjectId: query.Get("object_id"),
+		Status:   query.Get("status"),
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		parsed, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || parsed < 1 {
+			writeProblem(w, r, app.NewError(app.ErrInvalidRequest, "limit must be a positive integer"))
+			return
+		}
+		filter.Limit = parsed
+	}
+
+	jobs, err := handler.apiService.ListJobs(filter)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": jobs,
+	})
+}
+
+func (handler *apiHandler) CancelHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	job, err := cancelJob(handler.apiService, handler.jobCancelPublisher, tenantIdFromContext(r.Context()), chi.URLParam(r, "jobId"))
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": job,
+	})
+}
+
+// WatchHandler streams the job as server-sent "job" events, one right away
+// and one on every change, and ends the stream once the job is terminal.
+func (handler *apiHandler) WatchHandler(w http.ResponseWriter, r *http.Request) {
+	tenantId := tenantIdFromContext(r.Context())
+	jobId := chi.URLParam(r, "jobId")
+
+	job, err := handler.apiService.GetJob(tenantId, jobId)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeProblem(w, r, app.NewError(app.ErrInternal, "streaming is not supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// nginx must not buffer the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	lastWrite := time.Now()
+
+	sendJob := func(job *domain.Job) error {
+		data, err := json.Marshal(job)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "event: job\ndata: %s\n\n", data)
+		flusher.Flush()
+		lastWrite = time.Now()
+		return nil
+	}
+
+	keepAlive := func() error {
+		if time.Since(lastWrite) >= WatchKeepAlive {
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+			lastWrite = time.Now()
+		}
+		return nil
+	}
+
+	if err := watchJob(r.Context(), handler.apiService, job, sendJob, keepAlive); err != nil {
+		// Clients reconnect and get the error as a problem
+		log.Printf("could not watch job %v: %v\n", jobId, err.Error())
+	}
+}
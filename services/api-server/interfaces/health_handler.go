@@ -0,0 +1,43 @@
+package interfaces
+
+import (
+	"net/http"
+
+	"github.com/bogdan-copocean/hasty-server/natsconn"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+	"github.com/unrolled/render"
+)
+
+type HealthHandlerInterface interface {
+	GetHandler(w http.ResponseWriter, r *http.Request)
+}
+
+type healthHandler struct {
+	conn    natsconn.ConnectionManager
+	breaker repository.CircuitBreaker
+}
+
+func NewHealthHandler(conn natsconn.ConnectionManager, breaker repository.CircuitBreaker) HealthHandlerInterface {
+	return &healthHandler{conn: conn, breaker: breaker}
+}
+
+// GetHandler answers 503 while NATS is reconnecting or the mongo circuit is
+// open, so load balancers stop sending requests that would fail.
+func (handler *healthHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	nats := handler.conn.Status()
+	mongo := handler.breaker.Stats()
+
+	status := http.StatusOK
+	if nats.State != natsconn.ConnectionConnected || mongo.State == repository.CircuitOpen {
+		status = http.StatusServiceUnavailable
+	}
+
+	render.JSON(w, status, map[string]interface{}{
+		"message": map[string]interface{}{
+			"nats":  nats,
+			"mongo": mongo,
+		},
+	})
+}
@@ -0,0 +1,93 @@
+package interfaces
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/events"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/events/publishers"
+)
+
+// WatchPollInterval is how often a watched job is read again.
+const WatchPollInterval = time.Second
+
+// createJob is shared by the REST and gRPC apis. A created job is published
+// on "job:created"; when that fails the job is discarded, since nobody
+// would ever run it, and ErrPublishFailed is returned.
+func createJob(apiService app.ApiService, publisher publishers.JobEventPublisher, jobRequest *domain.JobRequest) (*domain.Job, bool, error) {
+	job, created, err := apiService.ProcessJob(jobRequest)
+	if err != nil || !created {
+		return job, created, err
+	}
+
+	eventJob := events.NewJobEvent(contracts.SubjectJobCreated, job)
+
+	if err := publisher.PublishData(eventJob); err != nil {
+		// Nobody will ever run the job, so it must not block the retry
+		if err := apiService.DiscardJob(job); err != nil {
+			log.Printf("could not discard unqueued job %v: %v\n", job.JobId, err.Error())
+		}
+
+		return nil, false, app.NewError(app.ErrPublishFailed, "the job could not be queued, try again later: %v", err.Error())
+	}
+
+	return job, true, nil
+}
+
+// cancelJob cancels the job and publishes it on "job:cancelled", so the
+// workflow it belongs to skips what depends on it.
+func cancelJob(apiService app.ApiService, publisher publishers.JobEventPublisher, tenantId, jobId string) (*domain.Job, error) {
+	job, err := apiService.CancelJob(tenantId, jobId)
+	if err != nil {
+		return nil, err
+	}
+
+	eventJob := events.NewJobEvent(contracts.SubjectJobCancelled, job)
+
+	// The job is cancelled either way, only its workflow waits for the event
+	if err := publisher.PublishData(eventJob); err != nil {
+		log.Printf("could not publish cancellation of job %v: %v\n", job.JobId, err.Error())
+	}
+
+	return job, nil
+}
+
+// watchJob calls send with job and then with every change of it, polling
+// every WatchPollInterval, until the job is terminal or ctx is done. idle is
+// called after every poll that found no change.
+func watchJob(ctx context.Context, apiService app.ApiService, job *domain.Job, send func(job *domain.Job) error, idle func() error) error {
+	ticker := time.NewTicker(WatchPollInterval)
+	defer ticker.Stop()
+
+	var sent *domain.Job
+	for {
+		if sent == nil || job.Status != sent.Status || job.Timestamp != sent.Timestamp {
+			if err := send(job); err != nil {
+				return err
+			}
+			sent = job
+		} else if err := idle(); err != nil {
+			return err
+		}
+
+		if job.IsTerminal() {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		next, err := apiService.GetJob(job.TenantId, job.JobId)
+		if err != nil {
+			return err
+		}
+		job = next
+	}
+}
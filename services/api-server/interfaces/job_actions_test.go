@@ -0,0 +1,54 @@
+package interfaces
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+)
+
+type failingPublisher struct{}
+
+func (failingPublisher) PublishData(jobEvent *contracts.JobEvent) error {
+	return errors.New("the publish buffer is full")
+}
+
+func TestCreateJobRetriesAfterFailedPublish(t *testing.T) {
+	for _, policy := range []app.RerunPolicy{app.NewFixedCooldownPolicy(app.DefaultCooldown), app.NewInProgressPolicy()} {
+		repo := repository.NewMemoryRepository()
+		service := app.NewApiService(repo, unlimitedTenantService{}, app.RerunPolicies{domain.DefaultJobType: policy})
+		jobRequest := func() *domain.JobRequest {
+			return &domain.JobRequest{ObjectId: "object-1", TenantId: domain.DefaultTenantId}
+		}
+
+		// A run of the object long ago, which the claim goes back to
+		previous := &domain.Job{JobId: "job-0", ObjectId: "object-1", TenantId: domain.DefaultTenantId, Status: domain.JobFinished}
+		if err := repo.SetJob(previous); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.ClaimObject(domain.DefaultTenantId, "object-1", "", previous.JobId); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, _, err := createJob(service, failingPublisher{}, jobRequest()); !errors.Is(err, app.ErrPublishFailed) {
+			t.Fatalf("%v: expected publish_failed, got %v", policy.Name(), err)
+		}
+		if claim, _ := repo.GetObjectClaim(domain.DefaultTenantId, "object-1"); claim != previous.JobId {
+			t.Errorf("%v: expected the claim back on the previous job, got %q", policy.Name(), claim)
+		}
+		if jobs, _ := repo.GetJobs(domain.JobFilter{TenantId: domain.DefaultTenantId, Limit: 10}); len(jobs) != 1 {
+			t.Errorf("%v: expected the unqueued job to be deleted, got %v jobs", policy.Name(), len(jobs))
+		}
+
+		job, created, err := createJob(service, discardPublisher{}, jobRequest())
+		if err != nil || !created {
+			t.Fatalf("%v: expected the retry to create a job, got %v, %v", policy.Name(), created, err)
+		}
+		if claim, _ := repo.GetObjectClaim(domain.DefaultTenantId, "object-1"); claim != job.JobId {
+			t.Errorf("%v: expected the retry to hold the claim, got %q", policy.Name(), claim)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package interfaces
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/natsconn"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/go-chi/chi/v5"
+)
+
+type adminKeyService struct {
+	app.ApiKeyService
+}
+
+func (adminKeyService) Authenticate(key string) (*domain.ApiKey, error) {
+	return &domain.ApiKey{KeyId: "key-1", TenantId: domain.DefaultTenantId, Scopes: []string{domain.ScopeAdmin}}, nil
+}
+
+type unlimitedTenantService struct {
+	app.TenantService
+}
+
+func (unlimitedTenantService) CheckQuota(tenantId string, newJobs int64) error {
+	return nil
+}
+
+type discardPublisher struct{}
+
+func (discardPublisher) PublishData(jobEvent *contracts.JobEvent) error {
+	return nil
+}
+
+type connectedNats struct {
+	natsconn.ConnectionManager
+}
+
+func (connectedNats) Status() natsconn.ConnectionStatus {
+	return natsconn.ConnectionStatus{State: natsconn.ConnectionConnected, ClientId: "api-server", BufferSize: 1000}
+}
+
+func loadSpec(t *testing.T) *openapi3.T {
+	t.Helper()
+
+	doc, err := openapi3.NewLoader().LoadFromData(openApiSpec)
+	if err != nil {
+		t.Fatalf("could not load openapi.json: %v", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("openapi.json is not a valid OpenAPI document: %v", err)
+	}
+
+	return doc
+}
+
+func newTestRouter(repo repository.JobRepository, resultRepo repository.ResultRepository, apiKeyService app.ApiKeyService) chi.Router {
+	service := app.NewApiService(repo, unlimitedTenantService{}, app.RerunPolicies{
+		domain.DefaultJobType: app.NewFixedCooldownPolicy(app.DefaultCooldown),
+	})
+
+	return NewRouter(Handlers{
+		Api:        NewApiHandler(service, discardPublisher{}, discardPublisher{}),
+		Result:     NewResultHandler(app.NewResultService(resultRepo, repo)),
+		Schedule:   NewScheduleHandler(app.NewScheduleService(repository.NewMemoryScheduleRepository())),
+		Workflow:   NewWorkflowHandler(nil),
+		Batch:      NewBatchHandler(nil),
+		ApiKey:     NewApiKeyHandler(apiKeyService),
+		Tenant:     NewTenantHandler(nil),
+		Worker:     NewWorkerHandler(nil),
+		Quarantine: NewQuarantineHandler(nil),
+		Retention:  NewRetentionHandler(nil),
+		Health:     NewHealthHandler(connectedNats{}, repository.NewCircuitBreaker(3, 10*time.Second)),
+		Docs:       NewDocsHandler(),
+	}, apiKeyService, repository.NewCircuitBreaker(3, 10*time.Second))
+}
+
+func TestSpecDescribesEveryRoute(t *testing.T) {
+	doc := loadSpec(t)
+
+	err := chi.Walk(newTestRouter(repository.NewMemoryRepository(), repository.NewMemoryResultRepository(), adminKeyService{}), func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		path := doc.Paths.Find(route)
+		if path == nil || path.GetOperation(method) == nil {
+			t.Errorf("%v %v is not described in openapi.json", method, route)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResponsesMatchSpec(t *testing.T) {
+	doc := loadSpec(t)
+	specRouter, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The docs page and event streams are only checked for their status and
+	// content type
+	openapi3filter.RegisterBodyDecoder("text/html", openapi3filter.FileBodyDecoder)
+	openapi3filter.RegisterBodyDecoder("text/event-stream", openapi3filter.FileBodyDecoder)
+
+	repo := repository.NewMemoryRepository()
+	resultRepo := repository.NewMemoryResultRepository()
+	router := newTestRouter(repo, resultRepo, adminKeyService{})
+
+	created := validateResponse(t, specRouter, router, http.MethodPost, "/", `{"object_id": "object-1"}`, http.StatusCreated)
+	jobId := created["job_id"]
+
+	validateResponse(t, specRouter, router, http.MethodPost, "/", `{"object_id": "object-1"}`, http.StatusConflict)
+	validateResponse(t, specRouter, router, http.MethodPost, "/", `{"type": "default"}`, http.StatusBadRequest)
+	validateResponse(t, specRouter, router, http.MethodPost, "/", `not json`, http.StatusBadRequest)
+	validateResponse(t, specRouter, router, http.MethodGet, "/"+jobId, "", http.StatusOK)
+	validateResponse(t, specRouter, router, http.MethodGet, "/missing-job", "", http.StatusNotFound)
+	validateResponse(t, specRouter, router, http.MethodGet, "/?status=processing&limit=10", "", http.StatusOK)
+	validateResponse(t, specRouter, router, http.MethodGet, "/?status=unknown", "", http.StatusBadRequest)
+	validateResponse(t, specRouter, router, http.MethodPost, "/"+jobId+"/cancel", "", http.StatusOK)
+	validateResponse(t, specRouter, router, http.MethodPost, "/"+jobId+"/cancel", "", http.StatusConflict)
+	validateResponse(t, specRouter, router, http.MethodPost, "/", `{"object_id": "object-1"}`, http.StatusTooManyRequests)
+	validateResponse(t, specRouter, router, http.MethodGet, "/"+jobId+"/events", "", http.StatusOK)
+	validateResponse(t, specRouter, router, http.MethodGet, "/"+jobId+"/result", "", http.StatusNotFound)
+	validateResponse(t, specRouter, router, http.MethodGet, "/missing-job/result", "", http.StatusNotFound)
+
+	succeeded := finishJob(t, router, repo, resultRepo, "object-2", &domain.JobResult{Data: []byte(`{"pages": 3}`)})
+	validateResponse(t, specRouter, router, http.MethodGet, "/"+succeeded+"/result", "", http.StatusOK)
+
+	failed := finishJob(t, router, repo, resultRepo, "object-3", &domain.JobResult{Error: &domain.JobError{Code: "corrupt", Message: "the object is corrupt"}})
+	validateResponse(t, specRouter, router, http.MethodGet, "/"+failed+"/result", "", http.StatusConflict)
+
+	validateResponse(t, specRouter, router, http.MethodPost, "/schedules", `{"object_id": "object-1", "cron_expr": "not cron"}`, http.StatusBadRequest)
+	validateResponse(t, specRouter, router, http.MethodGet, "/schedules/missing-schedule", "", http.StatusNotFound)
+
+	validateResponse(t, specRouter, router, http.MethodGet, "/healthz", "", http.StatusOK)
+	validateResponse(t, specRouter, router, http.MethodGet, "/openapi.json", "", http.StatusOK)
+	validateResponse(t, specRouter, router, http.MethodGet, "/docs", "", http.StatusOK)
+}
+
+// finishJob creates a job for objectId and finishes it with result, like the
+// job-finished listener does.
+func finishJob(t *testing.T, router http.Handler, repo repository.JobRepository, resultRepo repository.ResultRepository, objectId string, result *domain.JobResult) string {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/", strings.NewReader(`{"object_id": "`+objectId+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", "test-key")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var created struct {
+		Message map[string]interface{} `json:"message"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("could not create a job for %v: %v", objectId, rec.Body.String())
+	}
+
+	job := &domain.Job{TenantId: domain.DefaultTenantId, JobId: created.Message["job_id"].(string), Status: domain.JobFinished}
+	if err := repo.UpdateJobStatusAndTimeSlept(job); err != nil {
+		t.Fatal(err)
+	}
+
+	result.TenantId, result.JobId = job.TenantId, job.JobId
+	if err := resultRepo.SetResult(result); err != nil {
+		t.Fatal(err)
+	}
+
+	return job.JobId
+}
+
+// validateResponse serves the request with the real router and checks the
+// status and the response against the operation the spec declares for it.
+// It returns the "message" object of JSON responses.
+func validateResponse(t *testing.T, specRouter routers.Router, router http.Handler, method, path, body string, expectedStatus int) map[string]string {
+	t.Helper()
+
+	req := httptest.NewRequest(method, "http://localhost"+path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", "test-key")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != expectedStatus {
+		t.Fatalf("%v %v: expected %v, got %v: %v", method, path, expectedStatus, rec.Code, rec.Body.String())
+	}
+
+	route, pathParams, err := specRouter.FindRoute(req)
+	if err != nil {
+		t.Fatalf("%v %v: %v", method, path, err)
+	}
+
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: pathParams,
+			Route:      route,
+		},
+		Status: rec.Code,
+		Header: rec.Header(),
+		Body:   io.NopCloser(bytes.NewReader(rec.Body.Bytes())),
+	}
+	if err := openapi3filter.ValidateResponse(context.Background(), input); err != nil {
+		t.Fatalf("%v %v: response does not match openapi.json: %v", method, path, err)
+	}
+
+	var message struct {
+		Message map[string]string `json:"message"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &message)
+
+	return message.Message
+}
@@ -0,0 +1,98 @@
+package interfaces
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/unrolled/render"
+)
+
+const ProblemContentType = "application/problem+json"
+
+type problemKind struct {
+	err    error
+	status int
+	title  string
+}
+
+var problemKinds = []problemKind{
+	{app.ErrInvalidRequest, http.StatusBadRequest, "The request is invalid"},
+	{app.ErrUnauthorized, http.StatusUnauthorized, "The api key is missing or invalid"},
+	{app.ErrForbidden, http.StatusForbidden, "The api key is not allowed to do this"},
+	{app.ErrJobNotFound, http.StatusNotFound, "The job does not exist"},
+	{app.ErrCooldownActive, http.StatusTooManyRequests, "The object was run too recently"},
+	{app.ErrJobInProgress, http.StatusConflict, "A job for the object is still running"},
+	{app.ErrObjectBusy, http.StatusConflict, "Too many concurrent jobs for the object"},
+	{app.ErrQuotaExceeded, http.StatusTooManyRequests, "The tenant reached its quota"},
+	{app.ErrJobFinished, http.StatusConflict, "The job already finished"},
+	{app.ErrPublishFailed, http.StatusServiceUnavailable, "The job could not be queued"},
+	{app.ErrResultNotFound, http.StatusNotFound, "The job has no result"},
+	{app.ErrJobFailed, http.StatusConflict, "The job failed"},
+	{app.ErrScheduleNotFound, http.StatusNotFound, "The schedule does not exist"},
+	{app.ErrWorkflowNotFound, http.StatusNotFound, "The workflow does not exist"},
+	{app.ErrBatchNotFound, http.StatusNotFound, "The batch does not exist"},
+	{app.ErrBatchFinished, http.StatusConflict, "The batch is not active"},
+	{app.ErrApiKeyNotFound, http.StatusNotFound, "The api key does not exist"},
+	{app.ErrApiKeyRevoked, http.StatusConflict, "The api key was revoked"},
+	{app.ErrWorkerNotFound, http.StatusNotFound, "The worker does not exist"},
+	{app.ErrMessageNotFound, http.StatusNotFound, "The quarantined message does not exist"},
+	{app.ErrDatabaseUnavailable, http.StatusServiceUnavailable, "The database is unavailable"},
+}
+
+// publishRetryAfter is suggested to clients whose job could not be queued.
+const publishRetryAfter = 5 * time.Second
+
+func writeProblem(w http.ResponseWriter, r *http.Request, err error) {
+	problem := domain.Problem{
+		Status:   http.StatusInternalServerError,
+		Title:    "Internal server error",
+		Code:     app.ErrInternal.Error(),
+		Instance: r.URL.Path,
+	}
+
+	for _, kind := range problemKinds {
+		if errors.Is(err, kind.err) {
+			problem.Status = kind.status
+			problem.Title = kind.title
+			problem.Code = kind.err.Error()
+			problem.Detail = err.Error()
+			break
+		}
+	}
+
+	if problem.Status == http.StatusInternalServerError {
+		// Internal details stay in the log
+		log.Printf("%v %v: %v\n", r.Method, r.URL.Path, err.Error())
+	}
+
+	rejected := &app.RerunRejectedError{}
+	if errors.As(err, &rejected) {
+		problem.Policy = rejected.Policy
+		problem.RetryAfter = rejected.RetryAfter
+		if rejected.RetryAfter > 0 {
+			wait := rejected.RetryAfter - time.Now().Unix()
+			if wait < 1 {
+				wait = 1
+			}
+			w.Header().Set("Retry-After", strconv.FormatInt(wait, 10))
+		}
+	}
+
+	failed := &app.JobFailedError{}
+	if errors.As(err, &failed) {
+		problem.Error = &failed.JobError
+	}
+
+	if errors.Is(err, app.ErrPublishFailed) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(publishRetryAfter.Seconds())))
+	}
+
+	problem.Type = "/problems/" + problem.Code
+
+	render.New(render.Options{JSONContentType: ProblemContentType}).JSON(w, problem.Status, problem)
+}
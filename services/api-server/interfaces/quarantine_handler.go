@@ -0,0 +1,60 @@
+package interfaces
+
+import (
+	"net/http"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/go-chi/chi/v5"
+	"github.com/unrolled/render"
+)
+
+type QuarantineHandlerInterface interface {
+	GetAllHandler(w http.ResponseWriter, r *http.Request)
+	GetHandler(w http.ResponseWriter, r *http.Request)
+	DeleteHandler(w http.ResponseWriter, r *http.Request)
+}
+
+type quarantineHandler struct {
+	quarantineService app.QuarantineService
+}
+
+func NewQuarantineHandler(quarantineService app.QuarantineService) QuarantineHandlerInterface {
+	return &quarantineHandler{quarantineService: quarantineService}
+}
+
+func (handler *quarantineHandler) GetAllHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	messages, err := handler.quarantineService.GetMessages()
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": messages,
+	})
+}
+
+func (handler *quarantineHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	message, err := handler.quarantineService.GetMessage(chi.URLParam(r, "messageId"))
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": message,
+	})
+}
+
+func (handler *quarantineHandler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if err := handler.quarantineService.DeleteMessage(chi.URLParam(r, "messageId")); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,44 @@
+package interfaces
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/go-chi/chi/v5"
+)
+
+type ResultHandlerInterface interface {
+	GetHandler(w http.ResponseWriter, r *http.Request)
+}
+
+type resultHandler struct {
+	resultService app.ResultService
+}
+
+func NewResultHandler(resultService app.ResultService) ResultHandlerInterface {
+	return &resultHandler{resultService: resultService}
+}
+
+// GetHandler answers with the result document itself, streamed as it is
+// read from the database.
+func (handler *resultHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	jobId := chi.URLParam(r, "jobId")
+
+	result, data, err := handler.resultService.GetResult(tenantIdFromContext(r.Context()), jobId)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	defer data.Close()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.FormatInt(result.Size, 10))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, data); err != nil {
+		log.Printf("%v %v: could not stream the result: %v\n", r.Method, r.URL.Path, err.Error())
+	}
+}
@@ -0,0 +1,47 @@
+package interfaces
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/unrolled/render"
+)
+
+type RetentionHandlerInterface interface {
+	PurgeHandler(w http.ResponseWriter, r *http.Request)
+}
+
+type retentionHandler struct {
+	retentionService app.RetentionService
+}
+
+func NewRetentionHandler(retentionService app.RetentionService) RetentionHandlerInterface {
+	return &retentionHandler{retentionService: retentionService}
+}
+
+// PurgeHandler purges the expired jobs right away, or with ?dry_run=true
+// reports how many would be purged.
+func (handler *retentionHandler) PurgeHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			writeProblem(w, r, app.NewError(app.ErrInvalidRequest, "dry_run must be true or false"))
+			return
+		}
+		dryRun = parsed
+	}
+
+	report, err := handler.retentionService.Purge(dryRun)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": report,
+	})
+}
@@ -0,0 +1,88 @@
+package interfaces
+
+import (
+	"expvar"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+type Handlers struct {
+	Api        ApiHandlerInterface
+	Result     ResultHandlerInterface
+	Schedule   ScheduleHandlerInterface
+	Workflow   WorkflowHandlerInterface
+	Batch      BatchHandlerInterface
+	ApiKey     ApiKeyHandlerInterface
+	Tenant     TenantHandlerInterface
+	Worker     WorkerHandlerInterface
+	Quarantine QuarantineHandlerInterface
+	Retention  RetentionHandlerInterface
+	Health     HealthHandlerInterface
+	Docs       DocsHandlerInterface
+}
+
+// NewRouter registers every route of the api server. Every route must be
+// described in openapi.json, which the tests check.
+func NewRouter(handlers Handlers, apiKeyService app.ApiKeyService, breaker repository.CircuitBreaker) chi.Router {
+	r := chi.NewRouter()
+	r.Use(middleware.Logger)
+
+	read := RequireScope(domain.ScopeJobsRead)
+	write := RequireScope(domain.ScopeJobsWrite)
+	admin := RequireScope(domain.ScopeAdmin)
+
+	r.Get("/healthz", handlers.Health.GetHandler)
+	r.Get("/openapi.json", handlers.Docs.SpecHandler)
+	r.Get("/docs", handlers.Docs.UIHandler)
+
+	r.Group(func(r chi.Router) {
+		r.Use(RejectWhileDatabaseDown(breaker))
+		r.Use(Authenticate(apiKeyService))
+
+		r.With(write).Post("/", handlers.Api.PostHandler)
+		r.With(read).Get("/", handlers.Api.ListHandler)
+		r.With(read).Get("/{jobId}", handlers.Api.GetHandler)
+		r.With(write).Post("/{jobId}/cancel", handlers.Api.CancelHandler)
+		r.With(read).Get("/{jobId}/events", handlers.Api.WatchHandler)
+		r.With(read).Get("/{jobId}/result", handlers.Result.GetHandler)
+
+		r.With(write).Post("/schedules", handlers.Schedule.PostHandler)
+		r.With(read).Get("/schedules", handlers.Schedule.GetAllHandler)
+		r.With(read).Get("/schedules/{scheduleId}", handlers.Schedule.GetHandler)
+		r.With(write).Post("/schedules/{scheduleId}/pause", handlers.Schedule.PauseHandler)
+		r.With(write).Post("/schedules/{scheduleId}/resume", handlers.Schedule.ResumeHandler)
+		r.With(write).Delete("/schedules/{scheduleId}", handlers.Schedule.DeleteHandler)
+
+		r.With(write).Post("/workflows", handlers.Workflow.PostHandler)
+		r.With(read).Get("/workflows/{workflowId}", handlers.Workflow.GetHandler)
+
+		r.With(write).Post("/batches", handlers.Batch.PostHandler)
+		r.With(read).Get("/batches/{batchId}", handlers.Batch.GetHandler)
+		r.With(write).Post("/batches/{batchId}/cancel", handlers.Batch.CancelHandler)
+
+		r.With(admin).Get("/workers", handlers.Worker.GetAllHandler)
+		r.With(admin).Get("/workers/{workerId}", handlers.Worker.GetHandler)
+
+		r.With(admin).Post("/admin/keys", handlers.ApiKey.PostHandler)
+		r.With(admin).Get("/admin/keys", handlers.ApiKey.GetAllHandler)
+		r.With(admin).Post("/admin/keys/{keyId}/rotate", handlers.ApiKey.RotateHandler)
+		r.With(admin).Delete("/admin/keys/{keyId}", handlers.ApiKey.RevokeHandler)
+
+		r.With(admin).Get("/admin/tenants/{tenantId}", handlers.Tenant.GetHandler)
+		r.With(admin).Put("/admin/tenants/{tenantId}", handlers.Tenant.PutHandler)
+
+		r.With(admin).Get("/admin/quarantine", handlers.Quarantine.GetAllHandler)
+		r.With(admin).Get("/admin/quarantine/{messageId}", handlers.Quarantine.GetHandler)
+		r.With(admin).Delete("/admin/quarantine/{messageId}", handlers.Quarantine.DeleteHandler)
+
+		r.With(admin).Post("/admin/retention/purge", handlers.Retention.PurgeHandler)
+
+		r.With(admin).Get("/debug/vars", expvar.Handler().ServeHTTP)
+	})
+
+	return r
+}
@@ -0,0 +1,102 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/unrolled/render"
+)
+
+type ScheduleHandlerInterface interface {
+	PostHandler(w http.ResponseWriter, r *http.Request)
+	GetAllHandler(w http.ResponseWriter, r *http.Request)
+	GetHandler(w http.ResponseWriter, r *http.Request)
+	PauseHandler(w http.ResponseWriter, r *http.Request)
+	ResumeHandler(w http.ResponseWriter, r *http.Request)
+	DeleteHandler(w http.ResponseWriter, r *http.Request)
+}
+
+type scheduleHandler struct {
+	scheduleService app.ScheduleService
+}
+
+func NewScheduleHandler(scheduleService app.ScheduleService) ScheduleHandlerInterface {
+	return &scheduleHandler{scheduleService: scheduleService}
+}
+
+func (handler *scheduleHandler) PostHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	scheduleRequest := domain.ScheduleRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&scheduleRequest); err != nil {
+		writeProblem(w, r, app.NewError(app.ErrInvalidRequest, "%v", err.Error()))
+		return
+	}
+	scheduleRequest.ApiKeyId = apiKeyIdFromContext(r.Context())
+	scheduleRequest.TenantId = tenantIdFromContext(r.Context())
+
+	schedule, err := handler.scheduleService.CreateSchedule(&scheduleRequest)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	render.JSON(w, http.StatusCreated, map[string]interface{}{
+		"message": schedule,
+	})
+}
+
+func (handler *scheduleHandler) GetAllHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	schedules, err := handler.scheduleService.GetSchedules(tenantIdFromContext(r.Context()))
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": schedules,
+	})
+}
+
+func (handler *scheduleHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	schedule, err := handler.scheduleService.GetSchedule(tenantIdFromContext(r.Context()), chi.URLParam(r, "scheduleId"))
+	handler.respond(w, r, schedule, err)
+}
+
+func (handler *scheduleHandler) PauseHandler(w http.ResponseWriter, r *http.Request) {
+	schedule, err := handler.scheduleService.PauseSchedule(tenantIdFromContext(r.Context()), chi.URLParam(r, "scheduleId"))
+	handler.respond(w, r, schedule, err)
+}
+
+func (handler *scheduleHandler) ResumeHandler(w http.ResponseWriter, r *http.Request) {
+	schedule, err := handler.scheduleService.ResumeSchedule(tenantIdFromContext(r.Context()), chi.URLParam(r, "scheduleId"))
+	handler.respond(w, r, schedule, err)
+}
+
+func (handler *scheduleHandler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if err := handler.scheduleService.DeleteSchedule(tenantIdFromContext(r.Context()), chi.URLParam(r, "scheduleId")); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (handler *scheduleHandler) respond(w http.ResponseWriter, r *http.Request, schedule *domain.Schedule, err error) {
+	render := render.New()
+
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": schedule,
+	})
+}
@@ -0,0 +1,72 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/unrolled/render"
+)
+
+type TenantHandlerInterface interface {
+	GetHandler(w http.ResponseWriter, r *http.Request)
+	PutHandler(w http.ResponseWriter, r *http.Request)
+}
+
+type tenantHandler struct {
+	tenantService app.TenantService
+}
+
+func NewTenantHandler(tenantService app.TenantService) TenantHandlerInterface {
+	return &tenantHandler{tenantService: tenantService}
+}
+
+func (handler *tenantHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	tenantId := chi.URLParam(r, "tenantId")
+	if err := app.CheckTenantAdmin(tenantIdFromContext(r.Context()), tenantId); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	tenant, err := handler.tenantService.GetTenant(tenantId)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": tenant,
+	})
+}
+
+func (handler *tenantHandler) PutHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	tenantId := chi.URLParam(r, "tenantId")
+	if err := app.CheckTenantAdmin(tenantIdFromContext(r.Context()), tenantId); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	tenant := domain.Tenant{}
+
+	if err := json.NewDecoder(r.Body).Decode(&tenant); err != nil {
+		writeProblem(w, r, app.NewError(app.ErrInvalidRequest, "%v", err.Error()))
+		return
+	}
+	tenant.TenantId = tenantId
+
+	updated, err := handler.tenantService.SetTenant(&tenant)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": updated,
+	})
+}
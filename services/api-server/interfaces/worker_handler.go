@@ -0,0 +1,50 @@
+package interfaces
+
+import (
+	"net/http"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/go-chi/chi/v5"
+	"github.com/unrolled/render"
+)
+
+type WorkerHandlerInterface interface {
+	GetAllHandler(w http.ResponseWriter, r *http.Request)
+	GetHandler(w http.ResponseWriter, r *http.Request)
+}
+
+type workerHandler struct {
+	workerService app.WorkerService
+}
+
+func NewWorkerHandler(workerService app.WorkerService) WorkerHandlerInterface {
+	return &workerHandler{workerService: workerService}
+}
+
+func (handler *workerHandler) GetAllHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	workers, err := handler.workerService.GetWorkers()
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": workers,
+	})
+}
+
+func (handler *workerHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	worker, err := handler.workerService.GetWorker(chi.URLParam(r, "workerId"))
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": worker,
+	})
+}
@@ -0,0 +1,61 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/unrolled/render"
+)
+
+type WorkflowHandlerInterface interface {
+	PostHandler(w http.ResponseWriter, r *http.Request)
+	GetHandler(w http.ResponseWriter, r *http.Request)
+}
+
+type workflowHandler struct {
+	workflowService app.WorkflowService
+}
+
+func NewWorkflowHandler(workflowService app.WorkflowService) WorkflowHandlerInterface {
+	return &workflowHandler{workflowService: workflowService}
+}
+
+func (handler *workflowHandler) PostHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	workflowRequest := domain.WorkflowRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&workflowRequest); err != nil {
+		writeProblem(w, r, app.NewError(app.ErrInvalidRequest, "%v", err.Error()))
+		return
+	}
+	workflowRequest.ApiKeyId = apiKeyIdFromContext(r.Context())
+	workflowRequest.TenantId = tenantIdFromContext(r.Context())
+
+	workflow, err := handler.workflowService.SubmitWorkflow(&workflowRequest)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	render.JSON(w, http.StatusCreated, map[string]interface{}{
+		"message": workflow,
+	})
+}
+
+func (handler *workflowHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	workflow, err := handler.workflowService.GetWorkflow(tenantIdFromContext(r.Context()), chi.URLParam(r, "workflowId"))
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": workflow,
+	})
+}
@@ -1,59 +1,291 @@
 package main
 
 import (
+	"expvar"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/natsconn"
+	"github.com/bogdan-copocean/hasty-server/retention"
 	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
-	"github.com/bogdan-copocean/hasty-server/services/api-server/events"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
 	"github.com/bogdan-copocean/hasty-server/services/api-server/events/listeners"
 	"github.com/bogdan-copocean/hasty-server/services/api-server/events/publishers"
 	"github.com/bogdan-copocean/hasty-server/services/api-server/interfaces"
 	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/scheduler"
 )
 
 func main() {
-	r := chi.NewRouter()
-	r.Use(middleware.Logger)
-
 	clientId, err := os.Hostname()
 	if err != nil {
 		log.Fatalf("could not get the host name: %v\n", err)
 	}
 
-	// Mongo Repository
-	repo := repository.ConnectToMongo()
+	// Mongo Repositories
+	breaker := repository.NewCircuitBreaker(3, 10*time.Second)
+	expvar.Publish("mongo", expvar.Func(func() interface{} { return breaker.Stats() }))
+	mongoUrl := os.Getenv("MONGO_URL")
+	if mongoUrl == "" {
+		mongoUrl = repository.DefaultMongoUrl
+	}
+	client := repository.ConnectToMongo(mongoUrl, breaker)
+	db := client.Database(repository.DatabaseName)
+
+	// Jobs are stored in STORAGE, mongo (the default) or postgres at
+	// POSTGRES_URL. Everything else stays in Mongo
+	storage := os.Getenv("STORAGE")
+	migrators := []repository.Migrator{repository.NewMigrator(db, repository.Migrations)}
+	var repo repository.JobRepository
+	switch storage {
+	case "", "mongo":
+		repo = repository.NewMongoRepository(client, db.Collection(repository.JobsCollection))
+	case "postgres":
+		pool := repository.ConnectToPostgres(os.Getenv("POSTGRES_URL"))
+		migrators = append(migrators, repository.NewPostgresMigrator(pool, repository.PostgresMigrations))
+		repo = repository.NewPostgresRepository(pool)
+	default:
+		log.Fatalf("unknown STORAGE %q, expected mongo or postgres\n", storage)
+	}
+
+	// Schema migrations, "api-server migrate" runs them without serving
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(migrate(migrators, os.Args[2:]))
+	}
+	for _, migrator := range migrators {
+		if os.Getenv("MIGRATE_ON_START") != "false" {
+			if _, err := migrator.Migrate(); err != nil {
+				log.Fatalf("could not migrate the %v database: %v\n", migrator.Name(), err)
+			}
+		} else if pending, err := migrator.Pending(); err == nil && len(pending) > 0 {
+			log.Printf("%v %v migrations are pending, run api-server migrate\n", len(pending), migrator.Name())
+		}
+	}
+
+	scheduleRepo := repository.NewScheduleRepository(client, db.Collection(repository.SchedulesCollection))
+	workflowRepo := repository.NewWorkflowRepository(client, db.Collection(repository.WorkflowsCollection))
+	batchRepo := repository.NewBatchRepository(client, db.Collection(repository.BatchesCollection))
+	apiKeyRepo := repository.NewApiKeyRepository(client, db.Collection(repository.ApiKeysCollection))
+	tenantRepo := repository.NewTenantRepository(client, db.Collection(repository.TenantsCollection))
+	workerRepo := repository.NewWorkerRepository(client, db.Collection(repository.WorkersCollection))
+	quarantineRepo := repository.NewQuarantineRepository(client, db.Collection(repository.QuarantineCollection))
+	retentionRepo := repository.NewRetentionRepository(client, db.Collection(repository.JobsCollection), db.Collection(repository.ResultsCollection))
+
+	// Job results larger than RESULT_INLINE_LIMIT bytes go to GridFS
+	resultRepo := repository.NewResultRepository(client, db.Collection(repository.ResultsCollection), int(getEnvInt64("RESULT_INLINE_LIMIT", repository.DefaultResultInlineLimit)))
 
 	// Services
-	service := app.NewApiService(repo)
+	tenantService := app.NewTenantService(tenantRepo, repo, domain.Tenant{
+		MaxConcurrentJobs: getEnvInt64("TENANT_MAX_CONCURRENT_JOBS", 0),
+		MaxDailyJobs:      getEnvInt64("TENANT_MAX_DAILY_JOBS", 0),
+	})
+	rerunPolicies, err := app.ParseRerunPolicies(os.Getenv("RERUN_POLICIES"))
+	if err != nil {
+		log.Fatalf("could not parse RERUN_POLICIES: %v\n", err)
+	}
+	service := app.NewApiService(repo, tenantService, rerunPolicies)
+	scheduleService := app.NewScheduleService(scheduleRepo)
+	apiKeyService := app.NewApiKeyService(apiKeyRepo)
+	workerService := app.NewWorkerService(workerRepo)
+	quarantineService := app.NewQuarantineService(quarantineRepo)
+	resultService := app.NewResultService(resultRepo, repo)
+
+	// Retention, e.g. RETENTION=finished=30d,cancelled=90d, archived to
+	// RETENTION_ARCHIVE before deletion
+	retentionPolicy, err := retention.ParsePolicy(os.Getenv("RETENTION"))
+	if err != nil {
+		log.Fatalf("could not parse RETENTION: %v\n", err)
+	}
+	archiver, err := retention.ParseArchiver(os.Getenv("RETENTION_ARCHIVE"), db.Collection(repository.JobsArchiveCollection), repository.JobsCollection)
+	if err != nil {
+		log.Fatalf("could not parse RETENTION_ARCHIVE: %v\n", err)
+	}
+	if len(retentionPolicy) > 0 && storage == "postgres" {
+		log.Fatalln("RETENTION only purges jobs stored in mongo")
+	}
+	retentionService := app.NewRetentionService(retentionRepo, retentionPolicy, archiver)
+
+	// A fresh deployment has no keys, HASTY_ADMIN_KEY seeds the first admin key
+	if adminKey := os.Getenv("HASTY_ADMIN_KEY"); adminKey != "" {
+		if err := apiKeyService.BootstrapAdminKey(adminKey); err != nil {
+			log.Fatalf("could not bootstrap admin api key: %v\n", err)
+		}
+	}
 
-	// Nats
-	conn := events.ConnectToNats(clientId)
+	// Nats at NATS_URL, publishes are buffered while reconnecting, up to
+	// NATS_PUBLISH_BUFFER
+	natsUrl := os.Getenv("NATS_URL")
+	if natsUrl == "" {
+		natsUrl = natsconn.DefaultNatsUrl
+	}
+	conn := natsconn.ConnectToNats(natsUrl, clientId, int(getEnvInt64("NATS_PUBLISH_BUFFER", 1000)))
+	expvar.Publish("nats", expvar.Func(func() interface{} { return conn.Status() }))
+
+	// Job events are published as EVENT_ENCODING, consumers read both
+	eventEncoding, err := contracts.ParseEncoding(os.Getenv("EVENT_ENCODING"))
+	if err != nil {
+		log.Fatalf("could not parse EVENT_ENCODING: %v\n", err)
+	}
+
+	// Events are CloudEvents from this host, sent in CLOUDEVENTS_MODE,
+	// consumers read both modes
+	cloudEventsMode, err := contracts.ParseCloudEventsMode(os.Getenv("CLOUDEVENTS_MODE"))
+	if err != nil {
+		log.Fatalf("could not parse CLOUDEVENTS_MODE: %v\n", err)
+	}
+	eventSource := contracts.CloudEventSource("api-server", clientId)
 
 	// Job Created Publisher
-	jobCreatedSubject := "job:created"
-	publisher := publishers.NewJobEventPublisher(conn, jobCreatedSubject)
+	jobCreatedSubject := contracts.SubjectJobCreated
+	publisher := publishers.NewJobEventPublisher(conn, jobCreatedSubject, eventSource, eventEncoding, cloudEventsMode)
+
+	// Job Cancelled Publisher, for jobs cancelled through the api
+	jobCancelledSubject := contracts.SubjectJobCancelled
+	cancelledPublisher := publishers.NewJobEventPublisher(conn, jobCancelledSubject, eventSource, eventEncoding, cloudEventsMode)
+
+	workflowService := app.NewWorkflowService(repo, workflowRepo, tenantService, publisher)
+	batchService := app.NewBatchService(service, repo, batchRepo, publisher, cancelledPublisher)
 
 	// Job Finished listener
-	jobEventFinishedSubject := "job:finished"
+	jobEventFinishedSubject := contracts.SubjectJobFinished
 	jobEventFinishedQGroup := "job-finished-group"
-	finishedListener := listeners.NewJobEventListener(conn, jobEventFinishedSubject, jobEventFinishedQGroup, service)
+	jobEventFinishedDurableName := "job-finished-durable-name"
+	finishedListener := listeners.NewJobEventListener(conn, jobEventFinishedSubject, jobEventFinishedQGroup, jobEventFinishedDurableName, service, workflowService, quarantineService, resultService)
 	finishedListener.Listen()
 
 	// Job Cancelled listener
-	jobEventCancelledSubject := "job:cancelled"
 	jobEventCancelledQGroup := "job-cancelled-group"
-	cancelledListener := listeners.NewJobEventListener(conn, jobEventCancelledSubject, jobEventCancelledQGroup, service)
+	jobEventCancelledDurableName := "job-cancelled-durable-name"
+	cancelledListener := listeners.NewJobEventListener(conn, jobCancelledSubject, jobEventCancelledQGroup, jobEventCancelledDurableName, service, workflowService, quarantineService, resultService)
 	cancelledListener.Listen()
 
+	// Worker heartbeat listener
+	heartbeatSubject := contracts.SubjectWorkerHeartbeat
+	heartbeatQGroup := "worker-heartbeat-group"
+	heartbeatListener := listeners.NewHeartbeatListener(conn, heartbeatSubject, heartbeatQGroup, workerService)
+	heartbeatListener.Listen()
+
+	// Scheduler
+	jobScheduler := scheduler.NewScheduler(scheduleService, service, publisher)
+	jobScheduler.Start()
+
+	// Expired jobs are purged every RETENTION_INTERVAL
+	retentionService.Start(getEnvDuration("RETENTION_INTERVAL", retention.DefaultPurgeInterval))
+
 	// Handlers
-	handler := interfaces.NewApiHandler(service, publisher)
+	r := interfaces.NewRouter(interfaces.Handlers{
+		Api:        interfaces.NewApiHandler(service, publisher, cancelledPublisher),
+		Result:     interfaces.NewResultHandler(resultService),
+		Schedule:   interfaces.NewScheduleHandler(scheduleService),
+		Workflow:   interfaces.NewWorkflowHandler(workflowService),
+		Batch:      interfaces.NewBatchHandler(batchService),
+		ApiKey:     interfaces.NewApiKeyHandler(apiKeyService),
+		Tenant:     interfaces.NewTenantHandler(tenantService),
+		Worker:     interfaces.NewWorkerHandler(workerService),
+		Quarantine: interfaces.NewQuarantineHandler(quarantineService),
+		Retention:  interfaces.NewRetentionHandler(retentionService),
+		Health:     interfaces.NewHealthHandler(conn, breaker),
+		Docs:       interfaces.NewDocsHandler(),
+	}, apiKeyService, breaker)
 
-	r.Post("/", handler.PostHandler)
-	r.Get("/{jobId}", handler.GetHandler)
+	// gRPC, the job api on its own port
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%v", getEnvInt64("GRPC_PORT", 9092)))
+	if err != nil {
+		log.Fatalf("could not listen for grpc: %v\n", err)
+	}
+	grpcServer := interfaces.NewGrpcServer(interfaces.NewJobsServer(service, publisher, cancelledPublisher), apiKeyService, breaker)
+	go grpcServer.Serve(lis)
 
 	http.ListenAndServe(":9090", r)
 }
+
+// migrate runs migrateDatabase on every database, naming them when there
+// are several, and returns the first failing exit code.
+func migrate(migrators []repository.Migrator, args []string) int {
+	for _, migrator := range migrators {
+		if len(migrators) > 1 {
+			fmt.Printf("%v:\n", migrator.Name())
+		}
+		if code := migrateDatabase(migrator, args); code != 0 {
+			return code
+		}
+	}
+	return 0
+}
+
+// migrateDatabase applies the pending migrations, or with "status" lists the
+// applied and pending ones, and returns the exit code.
+func migrateDatabase(migrator repository.Migrator, args []string) int {
+	if len(args) > 0 && args[0] == "status" {
+		applied, err := migrator.Applied()
+		if err != nil {
+			log.Printf("could not read the applied migrations: %v\n", err)
+			return 1
+		}
+		pending, err := migrator.Pending()
+		if err != nil {
+			log.Printf("could not read the pending migrations: %v\n", err)
+			return 1
+		}
+
+		for _, migration := range applied {
+			fmt.Printf("%4d  %-7v  %-20v  %v\n", migration.Version, "applied", time.Unix(migration.AppliedAt, 0).UTC().Format(time.RFC3339), migration.Description)
+		}
+		for _, migration := range pending {
+			fmt.Printf("%4d  %-7v  %-20v  %v\n", migration.Version, "pending", "", migration.Description)
+		}
+		return 0
+	}
+
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "usage: api-server migrate [status]")
+		return 2
+	}
+
+	done, err := migrator.Migrate()
+	for _, migration := range done {
+		fmt.Printf("%4d  applied  %v\n", migration.Version, migration.Description)
+	}
+	if err != nil {
+		log.Printf("could not migrate the database: %v\n", err)
+		return 1
+	}
+	if len(done) == 0 {
+		fmt.Println("the database is up to date")
+	}
+	return 0
+}
+
+func getEnvInt64(key string, fallback int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Fatalf("%v must be an integer: %v\n", key, err)
+	}
+
+	return parsed
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		log.Fatalf("%v must be a positive duration\n", key)
+	}
+
+	return parsed
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ApiKeyRepository interface {
+	GetApiKeyByHash(hash string) (*domain.ApiKey, error)
+	GetApiKeyByKeyId(keyId string) (*domain.ApiKey, error)
+	GetApiKeys() ([]*domain.ApiKey, error)
+	CountApiKeys() (int64, error)
+	SetApiKey(apiKey *domain.ApiKey) error
+	UpdateApiKeyHash(apiKey *domain.ApiKey) error
+	UpdateApiKeyRevoked(apiKey *domain.ApiKey) error
+}
+
+type apiKeyRepository struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+}
+
+func NewApiKeyRepository(client *mongo.Client, collection *mongo.Collection) ApiKeyRepository {
+	return &apiKeyRepository{client: client, collection: collection}
+}
+
+func (repo *apiKeyRepository) GetApiKeyByHash(hash string) (*domain.ApiKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	apiKey := domain.ApiKey{}
+
+	if err := repo.collection.FindOne(ctx, bson.M{"hash": hash}).Decode(&apiKey); err != nil {
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+func (repo *apiKeyRepository) GetApiKeyByKeyId(keyId string) (*domain.ApiKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	apiKey := domain.ApiKey{}
+
+	if err := repo.collection.FindOne(ctx, bson.M{"keyId": keyId}).Decode(&apiKey); err != nil {
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+func (repo *apiKeyRepository) GetApiKeys() ([]*domain.ApiKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"timestamp": 1})
+	cursor, err := repo.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys := []*domain.ApiKey{}
+	if err := cursor.All(ctx, &apiKeys); err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+func (repo *apiKeyRepository) CountApiKeys() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return repo.collection.CountDocuments(ctx, bson.M{})
+}
+
+func (repo *apiKeyRepository) SetApiKey(apiKey *domain.ApiKey) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := repo.collection.InsertOne(ctx, bson.M{
+		"keyId":     apiKey.KeyId,
+		"tenantId":  apiKey.TenantId,
+		"name":      apiKey.Name,
+		"prefix":    apiKey.Prefix,
+		"hash":      apiKey.Hash,
+		"scopes":    apiKey.Scopes,
+		"revoked":   apiKey.Revoked,
+		"timestamp": apiKey.Timestamp,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	oid, ok := res.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return errors.New("could not type assert oid")
+	}
+	apiKey.Id = oid.Hex()
+
+	return nil
+}
+
+func (repo *apiKeyRepository) UpdateApiKeyHash(apiKey *domain.ApiKey) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"prefix": apiKey.Prefix, "hash": apiKey.Hash, "rotatedAt": apiKey.RotatedAt}}
+
+	if err := repo.collection.FindOneAndUpdate(ctx, bson.M{"keyId": apiKey.KeyId, "revoked": false}, update).Err(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repo *apiKeyRepository) UpdateApiKeyRevoked(apiKey *domain.ApiKey) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := repo.collection.FindOneAndUpdate(ctx, bson.M{"keyId": apiKey.KeyId}, bson.M{"$set": bson.M{"revoked": apiKey.Revoked}}).Err(); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type BatchRepository interface {
+	GetBatchByBatchId(tenantId, batchId string) (*domain.Batch, error)
+	SetBatch(batch *domain.Batch) error
+	UpdateBatchStatus(batch *domain.Batch) error
+	UpdateBatchTotals(batch *domain.Batch) error
+}
+
+type batchRepository struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+}
+
+func NewBatchRepository(client *mongo.Client, collection *mongo.Collection) BatchRepository {
+	return &batchRepository{client: client, collection: collection}
+}
+
+func (repo *batchRepository) GetBatchByBatchId(tenantId, batchId string) (*domain.Batch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	batch := domain.Batch{}
+
+	if err := repo.collection.FindOne(ctx, bson.M{"tenantId": tenantId, "batchId": batchId}).Decode(&batch); err != nil {
+		return nil, err
+	}
+
+	return &batch, nil
+}
+
+func (repo *batchRepository) SetBatch(batch *domain.Batch) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := repo.collection.InsertOne(ctx, bson.M{
+		"batchId":   batch.BatchId,
+		"tenantId":  batch.TenantId,
+		"status":    batch.Status,
+		"total":     batch.Total,
+		"rejected":  batch.Rejected,
+		"timestamp": batch.Timestamp,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	oid, ok := res.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return errors.New("could not type assert oid")
+	}
+	batch.Id = oid.Hex()
+
+	return nil
+}
+
+func (repo *batchRepository) UpdateBatchStatus(batch *domain.Batch) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := repo.collection.FindOneAndUpdate(ctx, bson.M{"tenantId": batch.TenantId, "batchId": batch.BatchId}, bson.M{"$set": bson.M{"status": batch.Status}}).Err(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateBatchTotals stores the status, total and rejected count of a batch
+// once its jobs are created.
+func (repo *batchRepository) UpdateBatchTotals(batch *domain.Batch) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"status": batch.Status, "total": batch.Total, "rejected": batch.Rejected}}
+
+	if err := repo.collection.FindOneAndUpdate(ctx, bson.M{"tenantId": batch.TenantId, "batchId": batch.BatchId}, update).Err(); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// CircuitBreaker tracks whether mongo is reachable. It opens after
+// failureThreshold failures in a row and stays open for openTimeout. Then it
+// lets requests through again (half open) until the next success closes it
+// or the next failure opens it again.
+type CircuitBreaker interface {
+	// Allow reports whether a call should reach mongo, and if not, how long
+	// the caller should wait before trying again.
+	Allow() (bool, time.Duration)
+	RecordSuccess()
+	RecordFailure(err error)
+	Stats() CircuitStats
+}
+
+type CircuitStats struct {
+	State               string `json:"state"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	Opens               uint64 `json:"opens"`
+	OpenedAt            int64  `json:"opened_at,omitempty"`
+	LastError           string `json:"last_error,omitempty"`
+}
+
+type circuitBreaker struct {
+	failureThreshold int
+	openTimeout      time.Duration
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	opens    uint64
+	openedAt time.Time
+	lastErr  string
+}
+
+func NewCircuitBreaker(failureThreshold int, openTimeout time.Duration) CircuitBreaker {
+	return &circuitBreaker{
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+		state:            CircuitClosed,
+	}
+}
+
+func (cb *circuitBreaker) Allow() (bool, time.Duration) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state != CircuitOpen {
+		return true, 0
+	}
+
+	if wait := time.Until(cb.openedAt.Add(cb.openTimeout)); wait > 0 {
+		return false, wait
+	}
+
+	cb.state = CircuitHalfOpen
+	return true, 0
+}
+
+func (cb *circuitBreaker) RecordSuccess() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.state = CircuitClosed
+	cb.failures = 0
+}
+
+func (cb *circuitBreaker) RecordFailure(err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures++
+	cb.lastErr = err.Error()
+
+	if cb.state == CircuitHalfOpen || (cb.state == CircuitClosed && cb.failures >= cb.failureThreshold) {
+		cb.state = CircuitOpen
+		cb.openedAt = time.Now()
+		cb.opens++
+	}
+}
+
+func (cb *circuitBreaker) Stats() CircuitStats {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	stats := CircuitStats{
+		State:               cb.state,
+		ConsecutiveFailures: cb.failures,
+		Opens:               cb.opens,
+		LastError:           cb.lastErr,
+	}
+	if cb.state != CircuitClosed {
+		stats.OpenedAt = cb.openedAt.Unix()
+	}
+
+	return stats
+}
+
+// breakerMonitors feed the breaker from the driver, so every repository
+// call is covered without wrapping each one. Server heartbeats notice an
+// unreachable database even when no command can be sent to it.
+func breakerMonitors(breaker CircuitBreaker) (*event.CommandMonitor, *event.ServerMonitor) {
+	commandMonitor := &event.CommandMonitor{
+		Succeeded: func(_ context.Context, _ *event.CommandSucceededEvent) {
+			breaker.RecordSuccess()
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			if commandUnavailable(e.Failure) {
+				breaker.RecordFailure(errors.New(e.Failure))
+			}
+		},
+	}
+
+	serverMonitor := &event.ServerMonitor{
+		ServerHeartbeatSucceeded: func(_ *event.ServerHeartbeatSucceededEvent) {
+			breaker.RecordSuccess()
+		},
+		ServerHeartbeatFailed: func(e *event.ServerHeartbeatFailedEvent) {
+			breaker.RecordFailure(e.Failure)
+		},
+	}
+
+	return commandMonitor, serverMonitor
+}
+
+// commandUnavailable tells a command that did not reach mongo, e.g. a
+// network error or a timeout, from an error mongo answered with. The driver
+// only reports the failure as text, where mongo's errors start with their
+// code name, e.g. "(BadValue) ...".
+func commandUnavailable(failure string) bool {
+	return !strings.HasPrefix(failure, "(")
+}
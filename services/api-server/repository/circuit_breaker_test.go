@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	breaker := NewCircuitBreaker(3, time.Minute)
+	failure := errors.New("connection refused")
+
+	// openTimeout elapses by moving the time it opened at back
+	elapse := func() {
+		cb := breaker.(*circuitBreaker)
+		cb.mu.Lock()
+		cb.openedAt = cb.openedAt.Add(-time.Minute)
+		cb.mu.Unlock()
+	}
+
+	steps := []struct {
+		name          string
+		act           func()
+		expectedState string
+		expectedAllow bool
+	}{
+		{"starts closed", func() {}, CircuitClosed, true},
+		{"stays closed below the threshold", func() { breaker.RecordFailure(failure); breaker.RecordFailure(failure) }, CircuitClosed, true},
+		{"a success resets the failures", func() { breaker.RecordSuccess(); breaker.RecordFailure(failure); breaker.RecordFailure(failure) }, CircuitClosed, true},
+		{"opens at the threshold", func() { breaker.RecordFailure(failure) }, CircuitOpen, false},
+		{"half opens after the timeout", elapse, CircuitHalfOpen, true},
+		{"a failure while half open opens again", func() { breaker.RecordFailure(failure) }, CircuitOpen, false},
+		{"a success while half open closes", func() { elapse(); breaker.Allow(); breaker.RecordSuccess() }, CircuitClosed, true},
+	}
+
+	for _, step := range steps {
+		step.act()
+
+		allowed, wait := breaker.Allow()
+		if state := breaker.Stats().State; state != step.expectedState || allowed != step.expectedAllow {
+			t.Fatalf("%v: expected %v and allowed %v, got %v and %v", step.name, step.expectedState, step.expectedAllow, state, allowed)
+		}
+		if !allowed && (wait <= 0 || wait > time.Minute) {
+			t.Errorf("%v: expected to wait for up to a minute, got %v", step.name, wait)
+		}
+	}
+
+	if stats := breaker.Stats(); stats.Opens != 2 || stats.LastError != failure.Error() {
+		t.Errorf("expected 2 opens and the last error, got %+v", stats)
+	}
+}
+
+func TestBreakerMonitorsIgnoreErrorsMongoAnswered(t *testing.T) {
+	breaker := NewCircuitBreaker(1, time.Minute)
+	commandMonitor, serverMonitor := breakerMonitors(breaker)
+
+	commandMonitor.Failed(context.Background(), &event.CommandFailedEvent{Failure: "(DuplicateKey) E11000 duplicate key error"})
+	if state := breaker.Stats().State; state != CircuitClosed {
+		t.Fatalf("expected an error mongo answered with to keep the circuit closed, got %v", state)
+	}
+
+	commandMonitor.Failed(context.Background(), &event.CommandFailedEvent{Failure: "connection(api_mongo_db:27017[-3]) incomplete read of message header: i/o timeout"})
+	if state := breaker.Stats().State; state != CircuitOpen {
+		t.Fatalf("expected a command that did not reach mongo to open the circuit, got %v", state)
+	}
+
+	breaker.(*circuitBreaker).openedAt = time.Now().Add(-time.Minute)
+	breaker.Allow()
+	serverMonitor.ServerHeartbeatSucceeded(&event.ServerHeartbeatSucceededEvent{})
+	if state := breaker.Stats().State; state != CircuitClosed {
+		t.Errorf("expected a heartbeat to close the circuit, got %v", state)
+	}
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrNotFound is returned for a missing job by every JobRepository. It is
+// mongo.ErrNoDocuments, which the other repositories return.
+var ErrNotFound = mongo.ErrNoDocuments
+
+// JobRepository stores the jobs and the per object claims. Mongo, Postgres
+// and memory implementations pass the same conformance suite, in
+// job_repository_test.go.
+type JobRepository interface {
+	GetJobByJobId(tenantId, jobId string) (*domain.Job, error)
+	GetJobByObjectId(tenantId, objectId string) (*domain.Job, error)
+	// GetJobs returns the newest jobs first.
+	GetJobs(filter domain.JobFilter) ([]*domain.Job, error)
+	GetJobsByWorkflowId(tenantId, workflowId string) ([]*domain.Job, error)
+	GetJobStatusCountsByBatchId(tenantId, batchId string) (map[string]int, error)
+	CountJobsByStatus(tenantId, status string) (int64, error)
+	CountJobsCreatedSince(tenantId string, since int64) (int64, error)
+	SetJob(job *domain.Job) error
+	DeleteJob(job *domain.Job) error
+	// UpdateJobStatusAndTimeSlept only updates a job that is processing.
+	UpdateJobStatusAndTimeSlept(job *domain.Job) error
+	TransitionJobStatus(job *domain.Job, fromStatus string) (bool, error)
+	// CancelJobsByBatchId cancels the batch's pending and processing jobs
+	// and returns them.
+	CancelJobsByBatchId(tenantId, batchId string, timestamp int64) ([]*domain.Job, error)
+	GetObjectClaim(tenantId, objectId string) (string, error)
+	// ClaimObject moves the object's claim from expectedJobId to newJobId.
+	// An empty newJobId releases the claim.
+	ClaimObject(tenantId, objectId, expectedJobId, newJobId string) (bool, error)
+}
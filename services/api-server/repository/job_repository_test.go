@@ -0,0 +1,390 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The conformance suite runs against memory always, and against Mongo and
+// Postgres when MONGO_TEST_URI and POSTGRES_TEST_URL point at a server.
+// Every test gets an empty database of its own.
+
+func TestMemoryRepositoryConformance(t *testing.T) {
+	testJobRepository(t, func(t *testing.T) JobRepository {
+		return NewMemoryRepository()
+	})
+}
+
+func TestMongoRepositoryConformance(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI is not set")
+	}
+
+	testJobRepository(t, func(t *testing.T) JobRepository {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		db := client.Database("hasty_test_" + strings.ReplaceAll(uuid.NewString(), "-", ""))
+		t.Cleanup(func() {
+			db.Drop(context.Background())
+			client.Disconnect(context.Background())
+		})
+
+		return NewMongoRepository(client, db.Collection(JobsCollection))
+	})
+}
+
+func TestPostgresRepositoryConformance(t *testing.T) {
+	url := os.Getenv("POSTGRES_TEST_URL")
+	if url == "" {
+		t.Skip("POSTGRES_TEST_URL is not set")
+	}
+
+	testJobRepository(t, func(t *testing.T) JobRepository {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		admin, err := pgxpool.Connect(ctx, url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		schema := "hasty_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+		if _, err := admin.Exec(ctx, `CREATE SCHEMA `+schema); err != nil {
+			t.Fatal(err)
+		}
+
+		config, err := pgxpool.ParseConfig(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.ConnConfig.RuntimeParams["search_path"] = schema
+
+		pool, err := pgxpool.ConnectConfig(ctx, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			pool.Close()
+			admin.Exec(context.Background(), `DROP SCHEMA `+schema+` CASCADE`)
+			admin.Close()
+		})
+
+		if _, err := NewPostgresMigrator(pool, PostgresMigrations).Migrate(); err != nil {
+			t.Fatal(err)
+		}
+
+		return NewPostgresRepository(pool)
+	})
+}
+
+func newTestJob(jobId, objectId string, createdAt int64) *domain.Job {
+	return &domain.Job{
+		JobId:     jobId,
+		TenantId:  "tenant-1",
+		ObjectId:  objectId,
+		Type:      "thumbnail",
+		Status:    domain.JobProcessing,
+		Timestamp: createdAt,
+		CreatedAt: createdAt,
+	}
+}
+
+func setJobs(t *testing.T, repo JobRepository, jobs ...*domain.Job) {
+	t.Helper()
+
+	for _, job := range jobs {
+		if err := repo.SetJob(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func jobIds(jobs []*domain.Job) []string {
+	ids := []string{}
+	for _, job := range jobs {
+		ids = append(ids, job.JobId)
+	}
+	return ids
+}
+
+// testJobRepository is what every JobRepository must do, newRepository
+// returns an empty one.
+func testJobRepository(t *testing.T, newRepository func(t *testing.T) JobRepository) {
+	t.Run("SetAndGetJob", func(t *testing.T) {
+		repo := newRepository(t)
+
+		job := newTestJob("job-1", "object-1", 100)
+		job.Params = map[string]string{"size": "small"}
+		job.ScheduleId, job.WorkflowId, job.BatchId, job.ApiKeyId = "schedule-1", "workflow-1", "batch-1", "key-1"
+		setJobs(t, repo, job)
+
+		if job.Id == "" {
+			t.Error("expected SetJob to set the id")
+		}
+
+		found, err := repo.GetJobByJobId("tenant-1", "job-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(found, job) {
+			t.Errorf("expected %+v, got %+v", job, found)
+		}
+
+		if _, err := repo.GetJobByJobId("tenant-2", "job-1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected another tenant's job to be not found, got %v", err)
+		}
+		if _, err := repo.GetJobByObjectId("tenant-1", "object-2"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected a missing object to be not found, got %v", err)
+		}
+	})
+
+	t.Run("GetJobByObjectIdReturnsTheLatest", func(t *testing.T) {
+		repo := newRepository(t)
+		setJobs(t, repo, newTestJob("job-1", "object-1", 100), newTestJob("job-2", "object-1", 200), newTestJob("job-3", "object-2", 300))
+
+		if job, err := repo.GetJobByObjectId("tenant-1", "object-1"); err != nil || job.JobId != "job-2" {
+			t.Errorf("expected job-2, got %+v: %v", job, err)
+		}
+	})
+
+	t.Run("GetJobs", func(t *testing.T) {
+		repo := newRepository(t)
+		cancelled := newTestJob("job-3", "object-1", 300)
+		cancelled.Status = domain.JobCancelled
+		other := newTestJob("job-4", "object-1", 400)
+		other.TenantId = "tenant-2"
+		setJobs(t, repo, newTestJob("job-1", "object-1", 100), newTestJob("job-2", "object-2", 200), cancelled, other)
+
+		filters := []struct {
+			filter domain.JobFilter
+			want   string
+		}{
+			{domain.JobFilter{TenantId: "tenant-1"}, "job-3,job-2,job-1"},
+			{domain.JobFilter{TenantId: "tenant-1", Limit: 2}, "job-3,job-2"},
+			{domain.JobFilter{TenantId: "tenant-1", ObjectId: "object-1"}, "job-3,job-1"},
+			{domain.JobFilter{TenantId: "tenant-1", Status: domain.JobProcessing}, "job-2,job-1"},
+			{domain.JobFilter{TenantId: "tenant-3"}, ""},
+		}
+		for _, f := range filters {
+			jobs, err := repo.GetJobs(f.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(jobIds(jobs), ","); got != f.want {
+				t.Errorf("%+v: expected %q, got %q", f.filter, f.want, got)
+			}
+		}
+	})
+
+	t.Run("WorkflowsBatchesAndCounts", func(t *testing.T) {
+		repo := newRepository(t)
+		jobs := []*domain.Job{newTestJob("job-1", "object-1", 100), newTestJob("job-2", "object-2", 200), newTestJob("job-3", "object-3", 300)}
+		jobs[0].WorkflowId, jobs[1].WorkflowId = "workflow-1", "workflow-1"
+		jobs[0].BatchId, jobs[1].BatchId, jobs[2].BatchId = "batch-1", "batch-1", "batch-1"
+		jobs[2].Status = domain.JobFinished
+		setJobs(t, repo, jobs...)
+
+		workflowJobs, err := repo.GetJobsByWorkflowId("tenant-1", "workflow-1")
+		if err != nil || len(workflowJobs) != 2 {
+			t.Errorf("expected the workflow's 2 jobs, got %v: %v", jobIds(workflowJobs), err)
+		}
+
+		counts, err := repo.GetJobStatusCountsByBatchId("tenant-1", "batch-1")
+		if err != nil || !reflect.DeepEqual(counts, map[string]int{domain.JobProcessing: 2, domain.JobFinished: 1}) {
+			t.Errorf("unexpected batch counts %v: %v", counts, err)
+		}
+
+		if count, err := repo.CountJobsByStatus("tenant-1", domain.JobProcessing); err != nil || count != 2 {
+			t.Errorf("expected 2 processing jobs, got %v: %v", count, err)
+		}
+		if count, err := repo.CountJobsCreatedSince("tenant-1", 200); err != nil || count != 2 {
+			t.Errorf("expected 2 jobs created since 200, got %v: %v", count, err)
+		}
+
+		cancelled, err := repo.CancelJobsByBatchId("tenant-1", "batch-1", 500)
+		if err != nil || len(cancelled) != 2 || cancelled[0].Status != domain.JobCancelled {
+			t.Errorf("expected the 2 open jobs to be cancelled, got %v: %v", jobIds(cancelled), err)
+		}
+		if job, _ := repo.GetJobByJobId("tenant-1", "job-1"); job.Status != domain.JobCancelled || job.Timestamp != 500 {
+			t.Errorf("expected job-1 to be cancelled at 500, got %+v", job)
+		}
+		if job, _ := repo.GetJobByJobId("tenant-1", "job-3"); job.Status != domain.JobFinished {
+			t.Errorf("expected the finished job to stay finished, got %+v", job)
+		}
+	})
+
+	t.Run("StatusUpdates", func(t *testing.T) {
+		repo := newRepository(t)
+		setJobs(t, repo, newTestJob("job-1", "object-1", 100))
+
+		result := &domain.Job{JobId: "job-1", TenantId: "tenant-1", Status: domain.JobFinished, SleepTimeUsed: 7, WorkerId: "worker-1"}
+		if err := repo.UpdateJobStatusAndTimeSlept(result); err != nil {
+			t.Fatal(err)
+		}
+		job, _ := repo.GetJobByJobId("tenant-1", "job-1")
+		if job.Status != domain.JobFinished || job.SleepTimeUsed != 7 || job.WorkerId != "worker-1" || job.Timestamp != 100 {
+			t.Errorf("expected the result to be stored, got %+v", job)
+		}
+
+		// A finished job no longer takes results
+		result.Status, result.SleepTimeUsed = domain.JobCancelled, 9
+		if err := repo.UpdateJobStatusAndTimeSlept(result); err != nil {
+			t.Fatal(err)
+		}
+		if job, _ := repo.GetJobByJobId("tenant-1", "job-1"); job.Status != domain.JobFinished || job.SleepTimeUsed != 7 {
+			t.Errorf("expected the finished job to be kept, got %+v", job)
+		}
+
+		moved, err := repo.TransitionJobStatus(&domain.Job{JobId: "job-1", TenantId: "tenant-1", Status: domain.JobSkipped, Timestamp: 200}, domain.JobProcessing)
+		if err != nil || moved {
+			t.Errorf("expected no transition from the wrong status, got %v: %v", moved, err)
+		}
+		moved, err = repo.TransitionJobStatus(&domain.Job{JobId: "job-1", TenantId: "tenant-1", Status: domain.JobSkipped, Timestamp: 200}, domain.JobFinished)
+		if err != nil || !moved {
+			t.Errorf("expected the transition, got %v: %v", moved, err)
+		}
+		if job, _ := repo.GetJobByJobId("tenant-1", "job-1"); job.Status != domain.JobSkipped || job.Timestamp != 200 {
+			t.Errorf("expected the job to be skipped at 200, got %+v", job)
+		}
+
+		if moved, err := repo.TransitionJobStatus(&domain.Job{JobId: "missing", TenantId: "tenant-1", Status: domain.JobSkipped}, domain.JobFinished); err != nil || moved {
+			t.Errorf("expected a missing job not to move, got %v: %v", moved, err)
+		}
+	})
+
+	t.Run("ConcurrentTransitionsMoveOnce", func(t *testing.T) {
+		repo := newRepository(t)
+		setJobs(t, repo, newTestJob("job-1", "object-1", 100))
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		moves := 0
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				moved, err := repo.TransitionJobStatus(&domain.Job{JobId: "job-1", TenantId: "tenant-1", Status: domain.JobCancelled, Timestamp: 200}, domain.JobProcessing)
+				if err != nil {
+					t.Error(err)
+				}
+				if moved {
+					mu.Lock()
+					moves++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if moves != 1 {
+			t.Errorf("expected exactly one transition, got %v", moves)
+		}
+	})
+
+	t.Run("DeleteJob", func(t *testing.T) {
+		repo := newRepository(t)
+		job := newTestJob("job-1", "object-1", 100)
+		setJobs(t, repo, job)
+
+		if err := repo.DeleteJob(job); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.GetJobByJobId("tenant-1", "job-1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected the job to be deleted, got %v", err)
+		}
+		if err := repo.DeleteJob(job); err != nil {
+			t.Errorf("expected deleting a missing job to succeed, got %v", err)
+		}
+	})
+
+	t.Run("ObjectClaims", func(t *testing.T) {
+		repo := newRepository(t)
+
+		if claim, err := repo.GetObjectClaim("tenant-1", "object-1"); err != nil || claim != "" {
+			t.Errorf("expected no claim, got %q: %v", claim, err)
+		}
+
+		steps := []struct {
+			expected, new string
+			want          bool
+		}{
+			{"", "job-1", true},
+			{"", "job-2", false},
+			{"job-2", "job-3", false},
+			{"job-1", "job-2", true},
+		}
+		for _, step := range steps {
+			if claimed, err := repo.ClaimObject("tenant-1", "object-1", step.expected, step.new); err != nil || claimed != step.want {
+				t.Errorf("claim %q -> %q: expected %v, got %v: %v", step.expected, step.new, step.want, claimed, err)
+			}
+		}
+
+		if claim, err := repo.GetObjectClaim("tenant-1", "object-1"); err != nil || claim != "job-2" {
+			t.Errorf("expected job-2 to hold the claim, got %q: %v", claim, err)
+		}
+		if claim, _ := repo.GetObjectClaim("tenant-2", "object-1"); claim != "" {
+			t.Errorf("expected claims to be per tenant, got %q", claim)
+		}
+
+		releases := []struct {
+			expected, new string
+			want          bool
+		}{
+			{"job-1", "", false},
+			{"job-2", "", true},
+			{"", "job-4", true},
+		}
+		for _, step := range releases {
+			if claimed, err := repo.ClaimObject("tenant-1", "object-1", step.expected, step.new); err != nil || claimed != step.want {
+				t.Errorf("claim %q -> %q: expected %v, got %v: %v", step.expected, step.new, step.want, claimed, err)
+			}
+		}
+	})
+
+	t.Run("ConcurrentClaimsHaveOneWinner", func(t *testing.T) {
+		repo := newRepository(t)
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		winners := 0
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				claimed, err := repo.ClaimObject("tenant-1", "object-1", "", uuid.NewString())
+				if err != nil {
+					t.Error(err)
+				}
+				if claimed {
+					mu.Lock()
+					winners++
+					mu.Unlock()
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		if winners != 1 {
+			t.Errorf("expected exactly one claim, got %v", winners)
+		}
+	})
+}
@@ -0,0 +1,575 @@
+package repository
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/bogdan-copocean/hasty-server/retention"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// The memory repositories keep everything else NewMemoryRepository does not
+// in process memory, for "hasty-server dev". Like the mongo ones they
+// return ErrNotFound for missing documents.
+
+type memoryApiKeyRepository struct {
+	mu      sync.Mutex
+	apiKeys []*domain.ApiKey
+}
+
+func NewMemoryApiKeyRepository() ApiKeyRepository {
+	return &memoryApiKeyRepository{}
+}
+
+func (repo *memoryApiKeyRepository) find(match func(apiKey *domain.ApiKey) bool) (*domain.ApiKey, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, apiKey := range repo.apiKeys {
+		if match(apiKey) {
+			found := *apiKey
+			return &found, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (repo *memoryApiKeyRepository) GetApiKeyByHash(hash string) (*domain.ApiKey, error) {
+	return repo.find(func(apiKey *domain.ApiKey) bool { return apiKey.Hash == hash })
+}
+
+func (repo *memoryApiKeyRepository) GetApiKeyByKeyId(keyId string) (*domain.ApiKey, error) {
+	return repo.find(func(apiKey *domain.ApiKey) bool { return apiKey.KeyId == keyId })
+}
+
+func (repo *memoryApiKeyRepository) GetApiKeys() ([]*domain.ApiKey, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	apiKeys := []*domain.ApiKey{}
+	for _, apiKey := range repo.apiKeys {
+		found := *apiKey
+		apiKeys = append(apiKeys, &found)
+	}
+
+	return apiKeys, nil
+}
+
+func (repo *memoryApiKeyRepository) CountApiKeys() (int64, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return int64(len(repo.apiKeys)), nil
+}
+
+func (repo *memoryApiKeyRepository) SetApiKey(apiKey *domain.ApiKey) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	apiKey.Id = uuid.NewString()
+
+	stored := *apiKey
+	repo.apiKeys = append(repo.apiKeys, &stored)
+
+	return nil
+}
+
+func (repo *memoryApiKeyRepository) UpdateApiKeyHash(apiKey *domain.ApiKey) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, stored := range repo.apiKeys {
+		if stored.KeyId == apiKey.KeyId && !stored.Revoked {
+			stored.Prefix, stored.Hash, stored.RotatedAt = apiKey.Prefix, apiKey.Hash, apiKey.RotatedAt
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+func (repo *memoryApiKeyRepository) UpdateApiKeyRevoked(apiKey *domain.ApiKey) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, stored := range repo.apiKeys {
+		if stored.KeyId == apiKey.KeyId {
+			stored.Revoked = apiKey.Revoked
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+type memoryBatchRepository struct {
+	mu      sync.Mutex
+	batches []*domain.Batch
+}
+
+func NewMemoryBatchRepository() BatchRepository {
+	return &memoryBatchRepository{}
+}
+
+func (repo *memoryBatchRepository) GetBatchByBatchId(tenantId, batchId string) (*domain.Batch, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, batch := range repo.batches {
+		if batch.TenantId == tenantId && batch.BatchId == batchId {
+			found := *batch
+			return &found, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (repo *memoryBatchRepository) SetBatch(batch *domain.Batch) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	batch.Id = uuid.NewString()
+
+	stored := *batch
+	repo.batches = append(repo.batches, &stored)
+
+	return nil
+}
+
+func (repo *memoryBatchRepository) UpdateBatchStatus(batch *domain.Batch) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, stored := range repo.batches {
+		if stored.TenantId == batch.TenantId && stored.BatchId == batch.BatchId {
+			stored.Status = batch.Status
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+func (repo *memoryBatchRepository) UpdateBatchTotals(batch *domain.Batch) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, stored := range repo.batches {
+		if stored.TenantId == batch.TenantId && stored.BatchId == batch.BatchId {
+			stored.Status = batch.Status
+			stored.Total = batch.Total
+			stored.Rejected = batch.Rejected
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+type memoryScheduleRepository struct {
+	mu        sync.Mutex
+	schedules []*domain.Schedule
+}
+
+func NewMemoryScheduleRepository() ScheduleRepository {
+	return &memoryScheduleRepository{}
+}
+
+func (repo *memoryScheduleRepository) GetScheduleByScheduleId(tenantId, scheduleId string) (*domain.Schedule, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, schedule := range repo.schedules {
+		if schedule.TenantId == tenantId && schedule.ScheduleId == scheduleId {
+			found := *schedule
+			return &found, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (repo *memoryScheduleRepository) GetSchedules(tenantId string) ([]*domain.Schedule, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	schedules := []*domain.Schedule{}
+	for _, schedule := range repo.schedules {
+		if schedule.TenantId == tenantId {
+			found := *schedule
+			schedules = append(schedules, &found)
+		}
+	}
+
+	return schedules, nil
+}
+
+func (repo *memoryScheduleRepository) GetDueSchedules(now int64) ([]*domain.Schedule, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	schedules := []*domain.Schedule{}
+	for _, schedule := range repo.schedules {
+		if !schedule.Paused && schedule.NextRun <= now {
+			found := *schedule
+			schedules = append(schedules, &found)
+		}
+	}
+	sort.SliceStable(schedules, func(i, j int) bool { return schedules[i].NextRun < schedules[j].NextRun })
+
+	return schedules, nil
+}
+
+func (repo *memoryScheduleRepository) SetSchedule(schedule *domain.Schedule) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	schedule.Id = uuid.NewString()
+
+	stored := *schedule
+	repo.schedules = append(repo.schedules, &stored)
+
+	return nil
+}
+
+func (repo *memoryScheduleRepository) UpdateSchedulePaused(schedule *domain.Schedule) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, stored := range repo.schedules {
+		if stored.TenantId == schedule.TenantId && stored.ScheduleId == schedule.ScheduleId {
+			stored.Paused, stored.NextRun = schedule.Paused, schedule.NextRun
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+func (repo *memoryScheduleRepository) AdvanceSchedule(schedule *domain.Schedule, expectedNextRun int64) (bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, stored := range repo.schedules {
+		if stored.ScheduleId == schedule.ScheduleId && !stored.Paused && stored.NextRun == expectedNextRun {
+			stored.LastRun, stored.NextRun = schedule.LastRun, schedule.NextRun
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (repo *memoryScheduleRepository) DeleteSchedule(tenantId, scheduleId string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for i, stored := range repo.schedules {
+		if stored.TenantId == tenantId && stored.ScheduleId == scheduleId {
+			repo.schedules = append(repo.schedules[:i], repo.schedules[i+1:]...)
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+type memoryTenantRepository struct {
+	mu      sync.Mutex
+	tenants map[string]domain.Tenant
+}
+
+func NewMemoryTenantRepository() TenantRepository {
+	return &memoryTenantRepository{tenants: map[string]domain.Tenant{}}
+}
+
+func (repo *memoryTenantRepository) GetTenantByTenantId(tenantId string) (*domain.Tenant, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	tenant, ok := repo.tenants[tenantId]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &tenant, nil
+}
+
+func (repo *memoryTenantRepository) UpsertTenant(tenant *domain.Tenant) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	stored, ok := repo.tenants[tenant.TenantId]
+	if !ok {
+		stored = domain.Tenant{Id: uuid.NewString(), TenantId: tenant.TenantId}
+	}
+	stored.MaxConcurrentJobs, stored.MaxDailyJobs = tenant.MaxConcurrentJobs, tenant.MaxDailyJobs
+	repo.tenants[tenant.TenantId] = stored
+
+	return nil
+}
+
+type memoryWorkerRepository struct {
+	mu      sync.Mutex
+	workers map[string]domain.Worker
+}
+
+func NewMemoryWorkerRepository() WorkerRepository {
+	return &memoryWorkerRepository{workers: map[string]domain.Worker{}}
+}
+
+func (repo *memoryWorkerRepository) GetWorkerByWorkerId(workerId string) (*domain.Worker, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	worker, ok := repo.workers[workerId]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &worker, nil
+}
+
+func (repo *memoryWorkerRepository) GetWorkers() ([]*domain.Worker, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	workers := []*domain.Worker{}
+	for _, worker := range repo.workers {
+		found := worker
+		workers = append(workers, &found)
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i].WorkerId < workers[j].WorkerId })
+
+	return workers, nil
+}
+
+func (repo *memoryWorkerRepository) UpsertWorker(worker *domain.Worker) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	stored := *worker
+	stored.Id = repo.workers[worker.WorkerId].Id
+	if stored.Id == "" {
+		stored.Id = uuid.NewString()
+	}
+	repo.workers[worker.WorkerId] = stored
+
+	return nil
+}
+
+type memoryWorkflowRepository struct {
+	mu        sync.Mutex
+	workflows []*domain.Workflow
+}
+
+func NewMemoryWorkflowRepository() WorkflowRepository {
+	return &memoryWorkflowRepository{}
+}
+
+func (repo *memoryWorkflowRepository) GetWorkflowByWorkflowId(tenantId, workflowId string) (*domain.Workflow, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, workflow := range repo.workflows {
+		if workflow.TenantId == tenantId && workflow.WorkflowId == workflowId {
+			found := *workflow
+			return &found, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (repo *memoryWorkflowRepository) SetWorkflow(workflow *domain.Workflow) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	workflow.Id = uuid.NewString()
+
+	stored := *workflow
+	repo.workflows = append(repo.workflows, &stored)
+
+	return nil
+}
+
+type memoryQuarantineRepository struct {
+	mu       sync.Mutex
+	messages []*domain.QuarantinedMessage
+}
+
+func NewMemoryQuarantineRepository() QuarantineRepository {
+	return &memoryQuarantineRepository{}
+}
+
+func (repo *memoryQuarantineRepository) GetMessageByMessageId(messageId string) (*domain.QuarantinedMessage, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, message := range repo.messages {
+		if message.MessageId == messageId {
+			found := *message
+			return &found, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// GetMessages returns the newest messages first.
+func (repo *memoryQuarantineRepository) GetMessages() ([]*domain.QuarantinedMessage, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	messages := []*domain.QuarantinedMessage{}
+	for i := len(repo.messages) - 1; i >= 0; i-- {
+		found := *repo.messages[i]
+		messages = append(messages, &found)
+	}
+
+	return messages, nil
+}
+
+func (repo *memoryQuarantineRepository) SetMessage(message *domain.QuarantinedMessage) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	stored := *message
+	stored.Id = uuid.NewString()
+	repo.messages = append(repo.messages, &stored)
+
+	return nil
+}
+
+func (repo *memoryQuarantineRepository) DeleteMessage(messageId string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for i, stored := range repo.messages {
+		if stored.MessageId == messageId {
+			repo.messages = append(repo.messages[:i], repo.messages[i+1:]...)
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+type memoryRetentionRepository struct {
+	jobs    *memoryRepository
+	results *memoryResultRepository
+}
+
+// NewMemoryRetentionRepository purges the jobs of jobRepo and their results
+// in resultRepo, which have to come from NewMemoryRepository and
+// NewMemoryResultRepository.
+func NewMemoryRetentionRepository(jobRepo JobRepository, resultRepo ResultRepository) RetentionRepository {
+	return &memoryRetentionRepository{jobs: jobRepo.(*memoryRepository), results: resultRepo.(*memoryResultRepository)}
+}
+
+func (repo *memoryRetentionRepository) CountExpired(status string, before int64) (int64, error) {
+	repo.jobs.mu.Lock()
+	defer repo.jobs.mu.Unlock()
+
+	var count int64
+	for _, job := range repo.jobs.jobs {
+		if job.Status == status && job.Timestamp < before {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// PurgeExpired archives the expired jobs as BSON documents, like the mongo
+// repository, and keeps them if that fails.
+func (repo *memoryRetentionRepository) PurgeExpired(status string, before int64, archiver retention.Archiver) (int64, error) {
+	repo.jobs.mu.Lock()
+	defer repo.jobs.mu.Unlock()
+
+	docs := []bson.Raw{}
+	kept := []*domain.Job{}
+	purged := []*domain.Job{}
+	for _, job := range repo.jobs.jobs {
+		if job.Status != status || job.Timestamp >= before {
+			kept = append(kept, job)
+			continue
+		}
+
+		doc, err := bson.Marshal(job)
+		if err != nil {
+			return 0, err
+		}
+		docs = append(docs, doc)
+		purged = append(purged, job)
+	}
+
+	if len(docs) == 0 {
+		return 0, nil
+	}
+	if err := archiver.Archive(docs); err != nil {
+		return 0, err
+	}
+
+	repo.results.mu.Lock()
+	for _, job := range purged {
+		delete(repo.results.results, job.TenantId+"/"+job.JobId)
+	}
+	repo.results.mu.Unlock()
+
+	repo.jobs.jobs = kept
+	return int64(len(docs)), nil
+}
+
+type memoryResultRepository struct {
+	mu      sync.Mutex
+	results map[string]domain.JobResult
+}
+
+// NewMemoryResultRepository keeps all result data inline.
+func NewMemoryResultRepository() ResultRepository {
+	return &memoryResultRepository{results: map[string]domain.JobResult{}}
+}
+
+func (repo *memoryResultRepository) GetResult(tenantId, jobId string) (*domain.JobResult, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	result, ok := repo.results[tenantId+"/"+jobId]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &result, nil
+}
+
+func (repo *memoryResultRepository) SetResult(result *domain.JobResult) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key := result.TenantId + "/" + result.JobId
+	if _, ok := repo.results[key]; ok {
+		return nil
+	}
+
+	stored := *result
+	stored.Id = uuid.NewString()
+	stored.Size = int64(len(result.Data))
+	repo.results[key] = stored
+
+	return nil
+}
+
+func (repo *memoryResultRepository) OpenResultData(result *domain.JobResult) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(result.Data)), nil
+}
+
+func (repo *memoryResultRepository) DeleteResultData(result *domain.JobResult) error {
+	return nil
+}
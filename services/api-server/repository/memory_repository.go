@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"sync"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/google/uuid"
+)
+
+type memoryRepository struct {
+	mu     sync.Mutex
+	jobs   []*domain.Job
+	claims map[string]string
+}
+
+// NewMemoryRepository keeps jobs in process memory. It is meant for tests
+// and local runs.
+func NewMemoryRepository() JobRepository {
+	return &memoryRepository{claims: map[string]string{}}
+}
+
+func (repo *memoryRepository) GetJobByObjectId(tenantId, objectId string) (*domain.Job, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	var latest *domain.Job
+	for _, job := range repo.jobs {
+		if job.TenantId == tenantId && job.ObjectId == objectId && (latest == nil || job.Timestamp > latest.Timestamp) {
+			latest = job
+		}
+	}
+
+	if latest == nil {
+		return nil, ErrNotFound
+	}
+
+	job := *latest
+	return &job, nil
+}
+
+func (repo *memoryRepository) GetJobByJobId(tenantId, jobId string) (*domain.Job, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, job := range repo.jobs {
+		if job.TenantId == tenantId && job.JobId == jobId {
+			found := *job
+			return &found, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (repo *memoryRepository) GetJobs(filter domain.JobFilter) ([]*domain.Job, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	jobs := []*domain.Job{}
+	for i := len(repo.jobs) - 1; i >= 0; i-- {
+		job := repo.jobs[i]
+		if job.TenantId != filter.TenantId ||
+			(filter.ObjectId != "" && job.ObjectId != filter.ObjectId) ||
+			(filter.Status != "" && job.Status != filter.Status) {
+			continue
+		}
+
+		found := *job
+		jobs = append(jobs, &found)
+		if filter.Limit > 0 && int64(len(jobs)) == filter.Limit {
+			break
+		}
+	}
+
+	return jobs, nil
+}
+
+func (repo *memoryRepository) GetJobsByWorkflowId(tenantId, workflowId string) ([]*domain.Job, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	jobs := []*domain.Job{}
+	for _, job := range repo.jobs {
+		if job.TenantId == tenantId && job.WorkflowId == workflowId {
+			found := *job
+			jobs = append(jobs, &found)
+		}
+	}
+
+	return jobs, nil
+}
+
+func (repo *memoryRepository) GetJobStatusCountsByBatchId(tenantId, batchId string) (map[string]int, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	counts := map[string]int{}
+	for _, job := range repo.jobs {
+		if job.TenantId == tenantId && job.BatchId == batchId {
+			counts[job.Status]++
+		}
+	}
+
+	return counts, nil
+}
+
+func (repo *memoryRepository) CountJobsByStatus(tenantId, status string) (int64, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	var count int64
+	for _, job := range repo.jobs {
+		if job.TenantId == tenantId && job.Status == status {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (repo *memoryRepository) CountJobsCreatedSince(tenantId string, since int64) (int64, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	var count int64
+	for _, job := range repo.jobs {
+		if job.TenantId == tenantId && job.CreatedAt >= since {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (repo *memoryRepository) SetJob(job *domain.Job) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	job.Id = uuid.New().String()
+
+	stored := *job
+	repo.jobs = append(repo.jobs, &stored)
+
+	return nil
+}
+
+func (repo *memoryRepository) DeleteJob(job *domain.Job) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for i, stored := range repo.jobs {
+		if stored.TenantId == job.TenantId && stored.JobId == job.JobId {
+			repo.jobs = append(repo.jobs[:i], repo.jobs[i+1:]...)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (repo *memoryRepository) UpdateJobStatusAndTimeSlept(job *domain.Job) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, stored := range repo.jobs {
+		if stored.TenantId == job.TenantId && stored.JobId == job.JobId && stored.Status == domain.JobProcessing {
+			stored.Status = job.Status
+			stored.SleepTimeUsed = job.SleepTimeUsed
+			stored.WorkerId = job.WorkerId
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (repo *memoryRepository) TransitionJobStatus(job *domain.Job, fromStatus string) (bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, stored := range repo.jobs {
+		if stored.TenantId == job.TenantId && stored.JobId == job.JobId && stored.Status == fromStatus {
+			stored.Status = job.Status
+			stored.Timestamp = job.Timestamp
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (repo *memoryRepository) CancelJobsByBatchId(tenantId, batchId string, timestamp int64) ([]*domain.Job, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	cancelled := []*domain.Job{}
+	for _, stored := range repo.jobs {
+		if stored.TenantId == tenantId && stored.BatchId == batchId && (stored.Status == domain.JobPending || stored.Status == domain.JobProcessing) {
+			stored.Status = domain.JobCancelled
+			stored.Timestamp = timestamp
+			job := *stored
+			cancelled = append(cancelled, &job)
+		}
+	}
+
+	return cancelled, nil
+}
+
+func (repo *memoryRepository) GetObjectClaim(tenantId, objectId string) (string, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return repo.claims[tenantId+"/"+objectId], nil
+}
+
+func (repo *memoryRepository) ClaimObject(tenantId, objectId, expectedJobId, newJobId string) (bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key := tenantId + "/" + objectId
+	if repo.claims[key] != expectedJobId {
+		return false, nil
+	}
+
+	if newJobId == "" {
+		delete(repo.claims, key)
+		return true, nil
+	}
+
+	repo.claims[key] = newJobId
+	return true, nil
+}
@@ -0,0 +1,280 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	MigrationsCollection = "schema_migrations"
+
+	// MigrateTimeout bounds a whole run, index builds on a large jobs
+	// collection can take minutes. A lock older than that was left by an
+	// instance that died while migrating.
+	MigrateTimeout = 30 * time.Minute
+
+	migrationLockId = "lock"
+)
+
+// Migration moves the database from Version-1 to Version. Up must be safe
+// to run again, since an instance can stop between running it and
+// recording its version.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// AppliedMigration is the record of a migration in MigrationsCollection.
+type AppliedMigration struct {
+	Version     int    `json:"version" bson:"_id"`
+	Description string `json:"description" bson:"description"`
+	AppliedAt   int64  `json:"applied_at" bson:"appliedAt"`
+}
+
+// Migrations is the schema history of the api server's database, new
+// migrations are appended with the next version.
+var Migrations = []Migration{
+	{1, "index jobs by job id, object id, status and parent", createJobIndexes},
+	{2, "index schedules, workflows, batches, api keys, tenants, workers and quarantine", createIndexes},
+	{3, "backfill tenant, type and creation time of jobs created before they existed", backfillJobs},
+	{4, "index job results by job id", createResultIndexes},
+}
+
+type Migrator interface {
+	// Name is the database migrated, mongo or postgres.
+	Name() string
+	// Migrate applies every pending migration in order and returns them.
+	Migrate() ([]Migration, error)
+	Applied() ([]AppliedMigration, error)
+	Pending() ([]Migration, error)
+}
+
+type migrator struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+	migrations []Migration
+}
+
+func NewMigrator(db *mongo.Database, migrations []Migration) Migrator {
+	return &migrator{db: db, collection: db.Collection(MigrationsCollection), migrations: migrations}
+}
+
+func (m *migrator) Name() string {
+	return "mongo"
+}
+
+func (m *migrator) Migrate() ([]Migration, error) {
+	if err := validateMigrations(m.migrations); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), MigrateTimeout)
+	defer cancel()
+
+	// Only one instance migrates, the others wait and find nothing pending
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.unlock()
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+	for _, migration := range pendingMigrations(m.migrations, applied) {
+		log.Printf("migrating to version %v: %v\n", migration.Version, migration.Description)
+
+		if err := migration.Up(ctx, m.db); err != nil {
+			return done, fmt.Errorf("migration %v failed: %w", migration.Version, err)
+		}
+
+		record := AppliedMigration{Version: migration.Version, Description: migration.Description, AppliedAt: time.Now().Unix()}
+		if _, err := m.collection.InsertOne(ctx, record); err != nil {
+			return done, fmt.Errorf("could not record migration %v: %w", migration.Version, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+func (m *migrator) Applied() ([]AppliedMigration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return m.applied(ctx)
+}
+
+func (m *migrator) Pending() ([]Migration, error) {
+	applied, err := m.Applied()
+	if err != nil {
+		return nil, err
+	}
+
+	return pendingMigrations(m.migrations, applied), nil
+}
+
+func (m *migrator) applied(ctx context.Context) ([]AppliedMigration, error) {
+	opts := options.Find().SetSort(bson.M{"_id": 1})
+	cursor, err := m.collection.Find(ctx, bson.M{"_id": bson.M{"$type": "number"}}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := []AppliedMigration{}
+	if err := cursor.All(ctx, &applied); err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+func (m *migrator) lock(ctx context.Context) error {
+	for {
+		_, err := m.collection.InsertOne(ctx, bson.M{"_id": migrationLockId, "lockedAt": time.Now().Unix()})
+		if err == nil {
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+
+		stale := time.Now().Add(-MigrateTimeout).Unix()
+		if _, err := m.collection.DeleteOne(ctx, bson.M{"_id": migrationLockId, "lockedAt": bson.M{"$lt": stale}}); err != nil {
+			return err
+		}
+
+		log.Println("waiting for another instance to finish migrating")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (m *migrator) unlock() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := m.collection.DeleteOne(ctx, bson.M{"_id": migrationLockId}); err != nil {
+		log.Printf("could not release the migration lock: %v\n", err)
+	}
+}
+
+// validateMigrations checks the versions count up from 1, so a version is
+// never skipped or applied twice.
+func validateMigrations(migrations []Migration) error {
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return fmt.Errorf("migration %q has version %v, expected %v", migration.Description, migration.Version, i+1)
+		}
+	}
+	return nil
+}
+
+func pendingMigrations(migrations []Migration, applied []AppliedMigration) []Migration {
+	done := map[int]bool{}
+	for _, migration := range applied {
+		done[migration.Version] = true
+	}
+
+	pending := []Migration{}
+	for _, migration := range migrations {
+		if !done[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
+}
+
+func index(unique bool, keys ...string) mongo.IndexModel {
+	keyDoc := bson.D{}
+	for _, key := range keys {
+		if key[0] == '-' {
+			keyDoc = append(keyDoc, bson.E{Key: key[1:], Value: -1})
+		} else {
+			keyDoc = append(keyDoc, bson.E{Key: key, Value: 1})
+		}
+	}
+
+	opts := options.Index()
+	if unique {
+		opts.SetUnique(true)
+	}
+
+	return mongo.IndexModel{Keys: keyDoc, Options: opts}
+}
+
+// createJobIndexes covers every query of mongoRepository. A job id that is
+// not unique within its tenant fails the migration, the duplicates have to
+// be removed by hand.
+func createJobIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(JobsCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		index(true, "tenantId", "jobId"),
+		index(false, "tenantId", "objectId", "-timestamp"),
+		index(false, "tenantId", "-createdAt"),
+		index(false, "tenantId", "status", "-createdAt"),
+		index(false, "tenantId", "workflowId"),
+		index(false, "tenantId", "batchId", "status"),
+	})
+	return err
+}
+
+func createIndexes(ctx context.Context, db *mongo.Database) error {
+	indexes := map[string][]mongo.IndexModel{
+		SchedulesCollection:  {index(true, "tenantId", "scheduleId"), index(false, "paused", "nextRun")},
+		WorkflowsCollection:  {index(true, "tenantId", "workflowId")},
+		BatchesCollection:    {index(true, "tenantId", "batchId")},
+		ApiKeysCollection:    {index(true, "keyId"), index(true, "hash")},
+		TenantsCollection:    {index(true, "tenantId")},
+		WorkersCollection:    {index(true, "workerId")},
+		QuarantineCollection: {index(true, "messageId"), index(false, "-timestamp")},
+	}
+
+	for collection, models := range indexes {
+		if _, err := db.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+			return fmt.Errorf("%v: %w", collection, err)
+		}
+	}
+	return nil
+}
+
+func createResultIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(ResultsCollection).Indexes().CreateOne(ctx, index(true, "tenantId", "jobId"))
+	return err
+}
+
+// backfillJobs gives jobs from before tenants, job types and creation times
+// the values the services assume, so they can be found again.
+func backfillJobs(ctx context.Context, db *mongo.Database) error {
+	jobs := db.Collection(JobsCollection)
+
+	backfills := []struct {
+		filter bson.M
+		update interface{}
+	}{
+		{bson.M{"tenantId": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"tenantId": domain.DefaultTenantId}}},
+		{bson.M{"type": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"type": domain.DefaultJobType}}},
+		{bson.M{"createdAt": bson.M{"$exists": false}}, mongo.Pipeline{{{Key: "$set", Value: bson.M{"createdAt": "$timestamp"}}}}},
+	}
+
+	for _, backfill := range backfills {
+		if _, err := jobs.UpdateMany(ctx, backfill.filter, backfill.update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package repository
+
+import "testing"
+
+func TestMigrationsAreNumberedInOrder(t *testing.T) {
+	if err := validateMigrations(Migrations); err != nil {
+		t.Fatal(err)
+	}
+
+	skipped := []Migration{{Version: 1}, {Version: 3}}
+	if err := validateMigrations(skipped); err == nil {
+		t.Error("expected a skipped version to be rejected")
+	}
+}
+
+func TestPendingMigrations(t *testing.T) {
+	migrations := []Migration{{Version: 1}, {Version: 2}, {Version: 3}}
+
+	pending := pendingMigrations(migrations, []AppliedMigration{{Version: 1}, {Version: 3}})
+	if len(pending) != 1 || pending[0].Version != 2 {
+		t.Errorf("expected only version 2 to be pending, got %+v", pending)
+	}
+
+	if pending := pendingMigrations(migrations, nil); len(pending) != 3 {
+		t.Errorf("expected every migration to be pending on a new database, got %+v", pending)
+	}
+}
+
+func TestPostgresMigrationsAreNumberedInOrder(t *testing.T) {
+	migrator := &postgresMigrator{migrations: PostgresMigrations}
+	if err := validateMigrations(migrator.all()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, migration := range PostgresMigrations {
+		if migration.Description == "" || migration.Sql == "" {
+			t.Errorf("expected migration %v to have a description and sql", migration.Version)
+		}
+	}
+}
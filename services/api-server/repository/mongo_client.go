@@ -5,28 +5,79 @@ import (
 	"log"
 	"time"
 
+	"github.com/bogdan-copocean/hasty-server/contracts"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
-func ConnectToMongo() MongoRepository {
+const (
+	DatabaseName         = "jobs_db"
+	JobsCollection       = "jobs"
+	SchedulesCollection  = "schedules"
+	WorkflowsCollection  = "workflows"
+	BatchesCollection    = "batches"
+	ApiKeysCollection    = "api_keys"
+	TenantsCollection    = "tenants"
+	ClaimsCollection     = "object_claims"
+	WorkersCollection    = "workers"
+	QuarantineCollection = "quarantine"
+	ResultsCollection    = "job_results"
+
+	// ResultsBucket is the GridFS bucket of results above the inline limit
+	ResultsBucket = contracts.ResultsBucket
+)
+
+// DefaultMongoUrl is used unless MONGO_URL is set.
+const DefaultMongoUrl = "mongodb://api_mongo_db:27017"
+
+const (
+	MinConnectWait = time.Second
+	MaxConnectWait = 30 * time.Second
+
+	// HeartbeatInterval is how often the driver checks the server, which
+	// bounds how long the circuit breaker takes to notice an outage.
+	HeartbeatInterval = 2 * time.Second
+)
+
+// ConnectToMongo retries with backoff until mongo at url answers a ping,
+// so the service can start before the database is up. breaker is fed with
+// the outcome of every command and server heartbeat from then on.
+func ConnectToMongo(url string, breaker CircuitBreaker) *mongo.Client {
+	commandMonitor, serverMonitor := breakerMonitors(breaker)
+
+	opts := options.Client().ApplyURI(url).
+		SetHeartbeatInterval(HeartbeatInterval).
+		SetMonitor(commandMonitor).
+		SetServerMonitor(serverMonitor)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://api_mongo_db:27017"))
-	// client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://localhost:27017"))
+	client, err := mongo.Connect(ctx, opts)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
+	wait := MinConnectWait
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		err := client.Ping(ctx, readpref.Primary())
+		cancel()
 
-	if err = client.Ping(ctx, readpref.Primary()); err != nil {
-		log.Fatal(err)
+		if err == nil {
+			break
+		}
+
+		log.Printf("could not reach mongo: %v, retrying in %v\n", err, wait)
+
+		time.Sleep(wait)
+		if wait *= 2; wait > MaxConnectWait {
+			wait = MaxConnectWait
+		}
 	}
-	collection := client.Database("jobs_db").Collection("jobs")
 
-	return NewMongoRepository(client, collection)
+	log.Println("Connected to Mongo")
+
+	return client
 }
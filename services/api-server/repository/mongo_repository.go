@@ -12,60 +12,155 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type MongoRepository interface {
-	GetJobByJobId(jobId string) (*domain.Job, error)
-	GetJobByObjectId(objectId string) (*domain.Job, error)
-	SetJob(job *domain.Job) error
-	UpdateJobStatusAndTimeSlept(job *domain.Job) error
-}
-
 type mongoRepository struct {
 	client     *mongo.Client
 	collection *mongo.Collection
+	claims     *mongo.Collection
 }
 
-func NewMongoRepository(client *mongo.Client, collection *mongo.Collection) MongoRepository {
-	return &mongoRepository{client: client, collection: collection}
+// NewMongoRepository keeps the per object claims next to the jobs
+// collection, in ClaimsCollection.
+func NewMongoRepository(client *mongo.Client, collection *mongo.Collection) JobRepository {
+	return &mongoRepository{
+		client:     client,
+		collection: collection,
+		claims:     collection.Database().Collection(ClaimsCollection),
+	}
 }
 
-func (repo *mongoRepository) GetJobByObjectId(objectId string) (*domain.Job, error) {
+func (repo *mongoRepository) GetJobByObjectId(tenantId, objectId string) (*domain.Job, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	job := domain.Job{}
 
 	opts := options.FindOne().SetSort(bson.M{"timestamp": -1})
-	if err := repo.collection.FindOne(ctx, bson.M{"objectId": objectId}, opts).Decode(&job); err != nil {
+	if err := repo.collection.FindOne(ctx, bson.M{"tenantId": tenantId, "objectId": objectId}, opts).Decode(&job); err != nil {
 		return nil, err
 	}
 
 	return &job, nil
 }
 
-func (repo *mongoRepository) GetJobByJobId(jobId string) (*domain.Job, error) {
+func (repo *mongoRepository) GetJobByJobId(tenantId, jobId string) (*domain.Job, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	job := domain.Job{}
 
 	opts := options.FindOne().SetSort(bson.M{"timestamp": -1})
-	if err := repo.collection.FindOne(ctx, bson.M{"jobId": jobId}, opts).Decode(&job); err != nil {
+	if err := repo.collection.FindOne(ctx, bson.M{"tenantId": tenantId, "jobId": jobId}, opts).Decode(&job); err != nil {
 		return nil, err
 	}
 
 	return &job, nil
 }
 
+func (repo *mongoRepository) GetJobs(filter domain.JobFilter) ([]*domain.Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := bson.M{"tenantId": filter.TenantId}
+	if filter.ObjectId != "" {
+		query["objectId"] = filter.ObjectId
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(filter.Limit)
+	cursor, err := repo.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []*domain.Job{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (repo *mongoRepository) GetJobsByWorkflowId(tenantId, workflowId string) ([]*domain.Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := repo.collection.Find(ctx, bson.M{"tenantId": tenantId, "workflowId": workflowId})
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []*domain.Job{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (repo *mongoRepository) GetJobStatusCountsByBatchId(tenantId, batchId string) (map[string]int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"tenantId": tenantId, "batchId": batchId}}},
+		{{Key: "$group", Value: bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}}},
+	}
+
+	cursor, err := repo.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []struct {
+		Status string `bson:"_id"`
+		Count  int    `bson:"count"`
+	}{}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	for _, result := range results {
+		counts[result.Status] = result.Count
+	}
+
+	return counts, nil
+}
+
+func (repo *mongoRepository) CountJobsByStatus(tenantId, status string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return repo.collection.CountDocuments(ctx, bson.M{"tenantId": tenantId, "status": status})
+}
+
+func (repo *mongoRepository) CountJobsCreatedSince(tenantId string, since int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return repo.collection.CountDocuments(ctx, bson.M{"tenantId": tenantId, "createdAt": bson.M{"$gte": since}})
+}
+
 func (repo *mongoRepository) SetJob(job *domain.Job) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	res, err := repo.collection.InsertOne(ctx, bson.M{
 		"jobId":         job.JobId,
+		"tenantId":      job.TenantId,
 		"objectId":      job.ObjectId,
+		"type":          job.Type,
+		"params":        job.Params,
 		"status":        job.Status,
 		"timestamp":     job.Timestamp,
+		"createdAt":     job.CreatedAt,
 		"sleepTimeUsed": job.SleepTimeUsed,
+		"scheduleId":    job.ScheduleId,
+		"workflowId":    job.WorkflowId,
+		"batchId":       job.BatchId,
+		"apiKeyId":      job.ApiKeyId,
 	})
 
 	if err != nil {
@@ -81,13 +176,134 @@ func (repo *mongoRepository) SetJob(job *domain.Job) error {
 	return nil
 }
 
+func (repo *mongoRepository) DeleteJob(job *domain.Job) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := repo.collection.DeleteOne(ctx, bson.M{"tenantId": job.TenantId, "jobId": job.JobId}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (repo *mongoRepository) UpdateJobStatusAndTimeSlept(job *domain.Job) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := repo.collection.FindOneAndUpdate(ctx, bson.M{"jobId": job.JobId}, bson.M{"$set": bson.M{"status": job.Status, "sleepTimeUsed": job.SleepTimeUsed}}).Err(); err != nil {
+	// Only jobs still processing take the result, so a job cancelled
+	// meanwhile keeps its status and redelivered events are ignored.
+	filter := bson.M{"tenantId": job.TenantId, "jobId": job.JobId, "status": domain.JobProcessing}
+
+	if _, err := repo.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"status": job.Status, "sleepTimeUsed": job.SleepTimeUsed, "workerId": job.WorkerId}}); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// TransitionJobStatus sets the job's status and timestamp only if it is
+// still in fromStatus, so concurrent listeners cannot move it twice.
+func (repo *mongoRepository) TransitionJobStatus(job *domain.Job, fromStatus string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"tenantId": job.TenantId, "jobId": job.JobId, "status": fromStatus}
+	update := bson.M{"$set": bson.M{"status": job.Status, "timestamp": job.Timestamp}}
+
+	res, err := repo.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return res.ModifiedCount == 1, nil
+}
+
+// CancelJobsByBatchId returns the jobs it found open and that are cancelled
+// at timestamp afterwards, so a job that finished in between is left out.
+func (repo *mongoRepository) CancelJobsByBatchId(tenantId, batchId string, timestamp int64) ([]*domain.Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	open := bson.M{"$in": bson.A{domain.JobPending, domain.JobProcessing}}
+
+	ids, err := repo.collection.Distinct(ctx, "_id", bson.M{"tenantId": tenantId, "batchId": batchId, "status": open})
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"$set": bson.M{"status": domain.JobCancelled, "timestamp": timestamp}}
+	if _, err := repo.collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}, "status": open}, update); err != nil {
+		return nil, err
+	}
+
+	cursor, err := repo.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}, "status": domain.JobCancelled, "timestamp": timestamp})
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []*domain.Job{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func claimKey(tenantId, objectId string) bson.D {
+	return bson.D{{Key: "tenantId", Value: tenantId}, {Key: "objectId", Value: objectId}}
+}
+
+// GetObjectClaim returns the id of the job that last claimed the object, or
+// an empty string when the object was never claimed.
+func (repo *mongoRepository) GetObjectClaim(tenantId, objectId string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	claim := struct {
+		JobId string `bson:"jobId"`
+	}{}
+
+	err := repo.claims.FindOne(ctx, bson.M{"_id": claimKey(tenantId, objectId)}).Decode(&claim)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return claim.JobId, nil
+}
+
+// ClaimObject points the object's claim at newJobId, but only if it still
+// points at expectedJobId. The claim's _id is unique, so of two concurrent
+// creations for the same object exactly one succeeds.
+func (repo *mongoRepository) ClaimObject(tenantId, objectId, expectedJobId, newJobId string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	key := claimKey(tenantId, objectId)
+
+	if expectedJobId == "" {
+		_, err := repo.claims.InsertOne(ctx, bson.M{"_id": key, "jobId": newJobId})
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	if newJobId == "" {
+		res, err := repo.claims.DeleteOne(ctx, bson.M{"_id": key, "jobId": expectedJobId})
+		if err != nil {
+			return false, err
+		}
+		return res.DeletedCount == 1, nil
+	}
+
+	res, err := repo.claims.UpdateOne(ctx, bson.M{"_id": key, "jobId": expectedJobId}, bson.M{"$set": bson.M{"jobId": newJobId}})
+	if err != nil {
+		return false, err
+	}
+
+	return res.ModifiedCount == 1, nil
+}
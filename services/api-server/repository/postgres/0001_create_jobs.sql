@@ -0,0 +1,34 @@
+-- create the jobs and object claims tables with the indexes of every job query
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id              BIGSERIAL PRIMARY KEY,
+    job_id          TEXT NOT NULL,
+    tenant_id       TEXT NOT NULL,
+    object_id       TEXT NOT NULL,
+    type            TEXT NOT NULL,
+    params          JSONB NOT NULL DEFAULT '{}',
+    status          TEXT NOT NULL,
+    -- the job's timestamp, unix time of its last status change
+    updated_at      BIGINT NOT NULL,
+    created_at      BIGINT NOT NULL,
+    sleep_time_used INTEGER NOT NULL DEFAULT 0,
+    schedule_id     TEXT NOT NULL DEFAULT '',
+    workflow_id     TEXT NOT NULL DEFAULT '',
+    batch_id        TEXT NOT NULL DEFAULT '',
+    api_key_id      TEXT NOT NULL DEFAULT '',
+    worker_id       TEXT NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS jobs_tenant_job_id ON jobs (tenant_id, job_id);
+CREATE INDEX IF NOT EXISTS jobs_tenant_object_updated ON jobs (tenant_id, object_id, updated_at DESC);
+CREATE INDEX IF NOT EXISTS jobs_tenant_created ON jobs (tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS jobs_tenant_status_created ON jobs (tenant_id, status, created_at DESC);
+CREATE INDEX IF NOT EXISTS jobs_tenant_workflow ON jobs (tenant_id, workflow_id) WHERE workflow_id <> '';
+CREATE INDEX IF NOT EXISTS jobs_tenant_batch_status ON jobs (tenant_id, batch_id, status) WHERE batch_id <> '';
+
+CREATE TABLE IF NOT EXISTS object_claims (
+    tenant_id TEXT NOT NULL,
+    object_id TEXT NOT NULL,
+    job_id    TEXT NOT NULL,
+    PRIMARY KEY (tenant_id, object_id)
+);
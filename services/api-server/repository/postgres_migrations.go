@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// postgresMigrationLock is the advisory lock held while migrating.
+const postgresMigrationLock = 7265787
+
+//go:embed postgres/*.sql
+var postgresMigrationFiles embed.FS
+
+// PostgresMigration is a file in postgres/ named <version>_<name>.sql,
+// whose first line is a "-- description" comment.
+type PostgresMigration struct {
+	Version     int
+	Description string
+	Sql         string
+}
+
+// PostgresMigrations is the schema history of the Postgres job store, new
+// migrations are added as the file with the next version.
+var PostgresMigrations = mustReadPostgresMigrations()
+
+func mustReadPostgresMigrations() []PostgresMigration {
+	files, err := postgresMigrationFiles.ReadDir("postgres")
+	if err != nil {
+		panic(err)
+	}
+
+	migrations := []PostgresMigration{}
+	for _, file := range files {
+		data, err := postgresMigrationFiles.ReadFile(path.Join("postgres", file.Name()))
+		if err != nil {
+			panic(err)
+		}
+
+		version, err := strconv.Atoi(strings.SplitN(file.Name(), "_", 2)[0])
+		if err != nil {
+			panic(fmt.Sprintf("migration %v has no version: %v", file.Name(), err))
+		}
+
+		sql := string(data)
+		description := strings.TrimSpace(strings.TrimPrefix(strings.SplitN(sql, "\n", 2)[0], "--"))
+
+		migrations = append(migrations, PostgresMigration{Version: version, Description: description, Sql: sql})
+	}
+
+	return migrations
+}
+
+type postgresMigrator struct {
+	pool       *pgxpool.Pool
+	migrations []PostgresMigration
+}
+
+// NewPostgresMigrator records the applied versions in MigrationsCollection,
+// as a table. The Migrations it returns have no Up, their SQL runs instead.
+func NewPostgresMigrator(pool *pgxpool.Pool, migrations []PostgresMigration) Migrator {
+	return &postgresMigrator{pool: pool, migrations: migrations}
+}
+
+func (m *postgresMigrator) Name() string {
+	return "postgres"
+}
+
+func (m *postgresMigrator) Migrate() ([]Migration, error) {
+	if err := validateMigrations(m.all()); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), MigrateTimeout)
+	defer cancel()
+
+	// The advisory lock belongs to a session, so everything runs on one
+	// connection
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	// Only one instance migrates, the others wait and find nothing pending
+	if err := lockPostgres(ctx, conn); err != nil {
+		return nil, err
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, postgresMigrationLock)
+
+	applied, err := appliedPostgresMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+	for _, migration := range m.pending(applied) {
+		log.Printf("migrating to version %v: %v\n", migration.Version, migration.Description)
+
+		// A migration and its record are committed together
+		err := conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, migration.Sql); err != nil {
+				return err
+			}
+
+			_, err := tx.Exec(ctx, `INSERT INTO `+MigrationsCollection+` (version, description, applied_at) VALUES ($1, $2, $3)`,
+				migration.Version, migration.Description, time.Now().Unix())
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %v failed: %w", migration.Version, err)
+		}
+
+		done = append(done, Migration{Version: migration.Version, Description: migration.Description})
+	}
+
+	return done, nil
+}
+
+func (m *postgresMigrator) Applied() ([]AppliedMigration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	return appliedPostgresMigrations(ctx, conn)
+}
+
+func (m *postgresMigrator) Pending() ([]Migration, error) {
+	applied, err := m.Applied()
+	if err != nil {
+		return nil, err
+	}
+
+	return pendingMigrations(m.all(), applied), nil
+}
+
+func (m *postgresMigrator) all() []Migration {
+	all := []Migration{}
+	for _, migration := range m.migrations {
+		all = append(all, Migration{Version: migration.Version, Description: migration.Description})
+	}
+	return all
+}
+
+func (m *postgresMigrator) pending(applied []AppliedMigration) []PostgresMigration {
+	done := map[int]bool{}
+	for _, migration := range applied {
+		done[migration.Version] = true
+	}
+
+	pending := []PostgresMigration{}
+	for _, migration := range m.migrations {
+		if !done[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
+}
+
+func appliedPostgresMigrations(ctx context.Context, conn *pgxpool.Conn) ([]AppliedMigration, error) {
+	_, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS `+MigrationsCollection+` (
+		version INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at BIGINT NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.Query(ctx, `SELECT version, description, applied_at FROM `+MigrationsCollection+` ORDER BY version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := []AppliedMigration{}
+	for rows.Next() {
+		migration := AppliedMigration{}
+		if err := rows.Scan(&migration.Version, &migration.Description, &migration.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied = append(applied, migration)
+	}
+
+	return applied, rows.Err()
+}
+
+// lockPostgres waits for the migration lock. Postgres releases it when the
+// session of an instance that died while migrating ends.
+func lockPostgres(ctx context.Context, conn *pgxpool.Conn) error {
+	for {
+		var locked bool
+		if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, postgresMigrationLock).Scan(&locked); err != nil {
+			return err
+		}
+		if locked {
+			return nil
+		}
+
+		log.Println("waiting for another instance to finish migrating")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
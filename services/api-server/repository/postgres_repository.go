@@ -0,0 +1,313 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// jobColumns is the order every query selects a job's columns in, see
+// scanJob.
+const jobColumns = `id, job_id, tenant_id, object_id, type, params, status, updated_at, created_at,
+	sleep_time_used, schedule_id, workflow_id, batch_id, api_key_id, worker_id`
+
+// ConnectToPostgres retries with backoff until postgres answers a ping, like
+// ConnectToMongo. The pool is not fed to the circuit breaker, pgx v4 has no
+// hook for the outcome of each query, so a Postgres outage answers
+// internal_error rather than database_unavailable.
+func ConnectToPostgres(url string) *pgxpool.Pool {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pool, err := pgxpool.Connect(ctx, url)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	wait := MinConnectWait
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		err := pool.Ping(ctx)
+		cancel()
+
+		if err == nil {
+			break
+		}
+
+		log.Printf("could not reach postgres: %v, retrying in %v\n", err, wait)
+
+		time.Sleep(wait)
+		if wait *= 2; wait > MaxConnectWait {
+			wait = MaxConnectWait
+		}
+	}
+
+	log.Println("Connected to Postgres")
+
+	return pool
+}
+
+type postgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepository needs the tables of PostgresMigrations.
+func NewPostgresRepository(pool *pgxpool.Pool) JobRepository {
+	return &postgresRepository{pool: pool}
+}
+
+func scanJob(row pgx.Row) (*domain.Job, error) {
+	job := domain.Job{}
+	var id int64
+
+	err := row.Scan(&id, &job.JobId, &job.TenantId, &job.ObjectId, &job.Type, &job.Params, &job.Status, &job.Timestamp, &job.CreatedAt,
+		&job.SleepTimeUsed, &job.ScheduleId, &job.WorkflowId, &job.BatchId, &job.ApiKeyId, &job.WorkerId)
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job.Id = strconv.FormatInt(id, 10)
+	if len(job.Params) == 0 {
+		job.Params = nil
+	}
+
+	return &job, nil
+}
+
+func (repo *postgresRepository) queryJobs(ctx context.Context, sql string, args ...interface{}) ([]*domain.Job, error) {
+	rows, err := repo.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*domain.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+func (repo *postgresRepository) GetJobByObjectId(tenantId, objectId string) (*domain.Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return scanJob(repo.pool.QueryRow(ctx, `SELECT `+jobColumns+` FROM jobs
+		WHERE tenant_id = $1 AND object_id = $2 ORDER BY updated_at DESC LIMIT 1`, tenantId, objectId))
+}
+
+func (repo *postgresRepository) GetJobByJobId(tenantId, jobId string) (*domain.Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return scanJob(repo.pool.QueryRow(ctx, `SELECT `+jobColumns+` FROM jobs WHERE tenant_id = $1 AND job_id = $2`, tenantId, jobId))
+}
+
+func (repo *postgresRepository) GetJobs(filter domain.JobFilter) ([]*domain.Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// An empty object id or status matches every job, a limit of 0 is none
+	return repo.queryJobs(ctx, `SELECT `+jobColumns+` FROM jobs
+		WHERE tenant_id = $1 AND ($2 = '' OR object_id = $2) AND ($3 = '' OR status = $3)
+		ORDER BY created_at DESC, id DESC LIMIT NULLIF($4::bigint, 0)`, filter.TenantId, filter.ObjectId, filter.Status, filter.Limit)
+}
+
+func (repo *postgresRepository) GetJobsByWorkflowId(tenantId, workflowId string) ([]*domain.Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return repo.queryJobs(ctx, `SELECT `+jobColumns+` FROM jobs WHERE tenant_id = $1 AND workflow_id = $2 ORDER BY id`, tenantId, workflowId)
+}
+
+func (repo *postgresRepository) GetJobStatusCountsByBatchId(tenantId, batchId string) (map[string]int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := repo.pool.Query(ctx, `SELECT status, count(*) FROM jobs WHERE tenant_id = $1 AND batch_id = $2 GROUP BY status`, tenantId, batchId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+
+	return counts, rows.Err()
+}
+
+func (repo *postgresRepository) CountJobsByStatus(tenantId, status string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var count int64
+	err := repo.pool.QueryRow(ctx, `SELECT count(*) FROM jobs WHERE tenant_id = $1 AND status = $2`, tenantId, status).Scan(&count)
+	return count, err
+}
+
+func (repo *postgresRepository) CountJobsCreatedSince(tenantId string, since int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var count int64
+	err := repo.pool.QueryRow(ctx, `SELECT count(*) FROM jobs WHERE tenant_id = $1 AND created_at >= $2`, tenantId, since).Scan(&count)
+	return count, err
+}
+
+func (repo *postgresRepository) SetJob(job *domain.Job) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	params := job.Params
+	if params == nil {
+		params = map[string]string{}
+	}
+
+	var id int64
+	err := repo.pool.QueryRow(ctx, `INSERT INTO jobs (job_id, tenant_id, object_id, type, params, status, updated_at, created_at,
+			sleep_time_used, schedule_id, workflow_id, batch_id, api_key_id, worker_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id`,
+		job.JobId, job.TenantId, job.ObjectId, job.Type, params, job.Status, job.Timestamp, job.CreatedAt,
+		job.SleepTimeUsed, job.ScheduleId, job.WorkflowId, job.BatchId, job.ApiKeyId, job.WorkerId).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	job.Id = strconv.FormatInt(id, 10)
+
+	return nil
+}
+
+func (repo *postgresRepository) DeleteJob(job *domain.Job) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := repo.pool.Exec(ctx, `DELETE FROM jobs WHERE tenant_id = $1 AND job_id = $2`, job.TenantId, job.JobId)
+	return err
+}
+
+// lockJobStatus locks the job's row until tx ends and returns its status,
+// or an empty string when there is no such job.
+func lockJobStatus(ctx context.Context, tx pgx.Tx, tenantId, jobId string) (string, error) {
+	var status string
+
+	err := tx.QueryRow(ctx, `SELECT status FROM jobs WHERE tenant_id = $1 AND job_id = $2 FOR UPDATE`, tenantId, jobId).Scan(&status)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+
+	return status, err
+}
+
+func (repo *postgresRepository) UpdateJobStatusAndTimeSlept(job *domain.Job) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Only jobs still processing take the result, so a job cancelled
+	// meanwhile keeps its status and redelivered events are ignored.
+	return repo.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		status, err := lockJobStatus(ctx, tx, job.TenantId, job.JobId)
+		if err != nil || status != domain.JobProcessing {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE jobs SET status = $3, sleep_time_used = $4, worker_id = $5 WHERE tenant_id = $1 AND job_id = $2`,
+			job.TenantId, job.JobId, job.Status, job.SleepTimeUsed, job.WorkerId)
+		return err
+	})
+}
+
+// TransitionJobStatus sets the job's status and timestamp only if it is
+// still in fromStatus. The row stays locked between reading and updating
+// it, so concurrent listeners cannot move it twice.
+func (repo *postgresRepository) TransitionJobStatus(job *domain.Job, fromStatus string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	moved := false
+	err := repo.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		status, err := lockJobStatus(ctx, tx, job.TenantId, job.JobId)
+		if err != nil || status != fromStatus {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE jobs SET status = $3, updated_at = $4 WHERE tenant_id = $1 AND job_id = $2`,
+			job.TenantId, job.JobId, job.Status, job.Timestamp)
+		moved = err == nil
+		return err
+	})
+
+	return moved, err
+}
+
+func (repo *postgresRepository) CancelJobsByBatchId(tenantId, batchId string, timestamp int64) ([]*domain.Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return repo.queryJobs(ctx, `UPDATE jobs SET status = $3, updated_at = $4
+		WHERE tenant_id = $1 AND batch_id = $2 AND status IN ($5, $6)
+		RETURNING `+jobColumns,
+		tenantId, batchId, domain.JobCancelled, timestamp, domain.JobPending, domain.JobProcessing)
+}
+
+// GetObjectClaim returns the id of the job that last claimed the object, or
+// an empty string when the object was never claimed.
+func (repo *postgresRepository) GetObjectClaim(tenantId, objectId string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var jobId string
+	err := repo.pool.QueryRow(ctx, `SELECT job_id FROM object_claims WHERE tenant_id = $1 AND object_id = $2`, tenantId, objectId).Scan(&jobId)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+
+	return jobId, err
+}
+
+// ClaimObject points the object's claim at newJobId, but only if it still
+// points at expectedJobId. The claim's primary key is the object, so of two
+// concurrent creations for the same object exactly one succeeds.
+func (repo *postgresRepository) ClaimObject(tenantId, objectId, expectedJobId, newJobId string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if expectedJobId == "" {
+		tag, err := repo.pool.Exec(ctx, `INSERT INTO object_claims (tenant_id, object_id, job_id) VALUES ($1, $2, $3)
+			ON CONFLICT (tenant_id, object_id) DO NOTHING`, tenantId, objectId, newJobId)
+		return err == nil && tag.RowsAffected() == 1, err
+	}
+
+	if newJobId == "" {
+		tag, err := repo.pool.Exec(ctx, `DELETE FROM object_claims WHERE tenant_id = $1 AND object_id = $2 AND job_id = $3`, tenantId, objectId, expectedJobId)
+		return err == nil && tag.RowsAffected() == 1, err
+	}
+
+	tag, err := repo.pool.Exec(ctx, `UPDATE object_claims SET job_id = $4 WHERE tenant_id = $1 AND object_id = $2 AND job_id = $3`,
+		tenantId, objectId, expectedJobId, newJobId)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
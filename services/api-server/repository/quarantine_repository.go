@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type QuarantineRepository interface {
+	GetMessageByMessageId(messageId string) (*domain.QuarantinedMessage, error)
+	GetMessages() ([]*domain.QuarantinedMessage, error)
+	SetMessage(message *domain.QuarantinedMessage) error
+	DeleteMessage(messageId string) error
+}
+
+type quarantineRepository struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+}
+
+func NewQuarantineRepository(client *mongo.Client, collection *mongo.Collection) QuarantineRepository {
+	return &quarantineRepository{client: client, collection: collection}
+}
+
+func (repo *quarantineRepository) GetMessageByMessageId(messageId string) (*domain.QuarantinedMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	message := domain.QuarantinedMessage{}
+
+	if err := repo.collection.FindOne(ctx, bson.M{"messageId": messageId}).Decode(&message); err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+func (repo *quarantineRepository) GetMessages() ([]*domain.QuarantinedMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"timestamp": -1})
+	cursor, err := repo.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []*domain.QuarantinedMessage{}
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (repo *quarantineRepository) SetMessage(message *domain.QuarantinedMessage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := repo.collection.InsertOne(ctx, bson.M{
+		"messageId": message.MessageId,
+		"subject":   message.Subject,
+		"sequence":  message.Sequence,
+		"data":      message.Data,
+		"error":     message.Error,
+		"timestamp": message.Timestamp,
+	})
+
+	return err
+}
+
+func (repo *quarantineRepository) DeleteMessage(messageId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := repo.collection.DeleteOne(ctx, bson.M{"messageId": messageId})
+	if err != nil {
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
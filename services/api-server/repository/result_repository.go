@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultResultInlineLimit is the largest result data stored in the result
+// document itself.
+const DefaultResultInlineLimit = 256 << 10
+
+type ResultRepository interface {
+	GetResult(tenantId, jobId string) (*domain.JobResult, error)
+	// SetResult stores the first result of a job, a later one for the same
+	// job is dropped.
+	SetResult(result *domain.JobResult) error
+	// OpenResultData streams the data of result, from GridFS when it is
+	// not inline.
+	OpenResultData(result *domain.JobResult) (io.ReadCloser, error)
+	// DeleteResultData deletes the GridFS data of a result that is not
+	// stored.
+	DeleteResultData(result *domain.JobResult) error
+}
+
+type resultRepository struct {
+	client      *mongo.Client
+	collection  *mongo.Collection
+	inlineLimit int
+}
+
+// NewResultRepository keeps result data above inlineLimit bytes in the
+// ResultsBucket of the collection's database.
+func NewResultRepository(client *mongo.Client, collection *mongo.Collection, inlineLimit int) ResultRepository {
+	return &resultRepository{client: client, collection: collection, inlineLimit: inlineLimit}
+}
+
+// bucket is opened per call, since its deadlines are shared by every
+// operation on it.
+func (repo *resultRepository) bucket() (*gridfs.Bucket, error) {
+	return gridfs.NewBucket(repo.collection.Database(), options.GridFSBucket().SetName(ResultsBucket))
+}
+
+func (repo *resultRepository) GetResult(tenantId, jobId string) (*domain.JobResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result := domain.JobResult{}
+
+	if err := repo.collection.FindOne(ctx, bson.M{"tenantId": tenantId, "jobId": jobId}).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (repo *resultRepository) SetResult(result *domain.JobResult) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	doc := bson.M{
+		"jobId":     result.JobId,
+		"tenantId":  result.TenantId,
+		"size":      int64(len(result.Data)),
+		"timestamp": result.Timestamp,
+	}
+	if result.Error != nil {
+		doc["error"] = bson.M{"code": result.Error.Code, "message": result.Error.Message, "details": result.Error.Details}
+	}
+
+	var fileId primitive.ObjectID
+	var err error
+	switch {
+	case result.File != "":
+		// Written to the bucket by the job server
+		if fileId, err = primitive.ObjectIDFromHex(result.File); err != nil {
+			return err
+		}
+		doc["file"] = result.File
+		doc["size"] = result.Size
+	case len(result.Data) > repo.inlineLimit:
+		bucket, err := repo.bucket()
+		if err != nil {
+			return err
+		}
+		bucket.SetWriteDeadline(time.Now().Add(time.Minute))
+
+		fileId, err = bucket.UploadFromStream(result.TenantId+"/"+result.JobId, bytes.NewReader(result.Data))
+		if err != nil {
+			return err
+		}
+		doc["file"] = fileId.Hex()
+	default:
+		doc["data"] = result.Data
+	}
+
+	_, err = repo.collection.InsertOne(ctx, doc)
+	if mongo.IsDuplicateKeyError(err) {
+		// A redelivered event, the result was stored the first time. Its
+		// data is kept when the stored result refers to the same file.
+		err = nil
+		if stored, getErr := repo.GetResult(result.TenantId, result.JobId); !fileId.IsZero() && getErr == nil && stored.File != fileId.Hex() {
+			repo.deleteFile(fileId)
+		}
+	}
+
+	return err
+}
+
+func (repo *resultRepository) DeleteResultData(result *domain.JobResult) error {
+	if result.File == "" {
+		return nil
+	}
+
+	fileId, err := primitive.ObjectIDFromHex(result.File)
+	if err != nil {
+		return err
+	}
+
+	bucket, err := repo.bucket()
+	if err != nil {
+		return err
+	}
+	bucket.SetWriteDeadline(time.Now().Add(10 * time.Second))
+
+	if err := bucket.Delete(fileId); err != nil && err != gridfs.ErrFileNotFound {
+		return err
+	}
+
+	return nil
+}
+
+func (repo *resultRepository) deleteFile(fileId primitive.ObjectID) {
+	bucket, err := repo.bucket()
+	if err == nil {
+		bucket.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		bucket.Delete(fileId)
+	}
+}
+
+// OpenResultData has no deadline for GridFS data, the download lasts as
+// long as the caller reads.
+func (repo *resultRepository) OpenResultData(result *domain.JobResult) (io.ReadCloser, error) {
+	if result.File == "" {
+		return io.NopCloser(bytes.NewReader(result.Data)), nil
+	}
+
+	fileId, err := primitive.ObjectIDFromHex(result.File)
+	if err != nil {
+		return nil, err
+	}
+
+	bucket, err := repo.bucket()
+	if err != nil {
+		return nil, err
+	}
+
+	return bucket.OpenDownloadStream(fileId)
+}
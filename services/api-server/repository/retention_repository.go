@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/retention"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const JobsArchiveCollection = "jobs_archive"
+
+// RetentionRepository is the retention.Store of the jobs.
+type RetentionRepository interface {
+	CountExpired(status string, before int64) (int64, error)
+	// PurgeExpired archives and then deletes the jobs in batches, along
+	// with their results. A batch that cannot be archived is not deleted.
+	PurgeExpired(status string, before int64, archiver retention.Archiver) (int64, error)
+}
+
+type retentionRepository struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+	results    *mongo.Collection
+}
+
+// NewRetentionRepository purges the jobs of collection. Their results in
+// results, and the results' data in the ResultsBucket of its database, are
+// deleted rather than archived.
+func NewRetentionRepository(client *mongo.Client, collection, results *mongo.Collection) RetentionRepository {
+	return &retentionRepository{client: client, collection: collection, results: results}
+}
+
+func expiredJobs(status string, before int64) bson.M {
+	return bson.M{"status": status, "timestamp": bson.M{"$lt": before}}
+}
+
+func (repo *retentionRepository) CountExpired(status string, before int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return repo.collection.CountDocuments(ctx, expiredJobs(status, before))
+}
+
+// PurgeExpired deletes the results of a batch first, a batch whose results
+// could not be deleted is found again by the next purge.
+func (repo *retentionRepository) PurgeExpired(status string, before int64, archiver retention.Archiver) (int64, error) {
+	return retention.PurgeCollection(repo.collection, expiredJobs(status, before), archiver, func(ctx context.Context, docs []bson.Raw) error {
+		jobs := bson.A{}
+		for _, doc := range docs {
+			jobs = append(jobs, bson.M{"tenantId": doc.Lookup("tenantId"), "jobId": doc.Lookup("jobId")})
+		}
+
+		if err := repo.deleteResults(ctx, bson.M{"$or": jobs}); err != nil {
+			return fmt.Errorf("could not delete results: %w", err)
+		}
+		return nil
+	})
+}
+
+func (repo *retentionRepository) deleteResults(ctx context.Context, filter bson.M) error {
+	cursor, err := repo.results.Find(ctx, bson.M{"$and": bson.A{filter, bson.M{"file": bson.M{"$exists": true}}}}, options.Find().SetProjection(bson.M{"file": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	fileIds := []primitive.ObjectID{}
+	for cursor.Next(ctx) {
+		result := struct {
+			File string `bson:"file"`
+		}{}
+		if err := cursor.Decode(&result); err != nil {
+			return err
+		}
+		if fileId, err := primitive.ObjectIDFromHex(result.File); err == nil {
+			fileIds = append(fileIds, fileId)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	if len(fileIds) > 0 {
+		bucket, err := gridfs.NewBucket(repo.results.Database(), options.GridFSBucket().SetName(ResultsBucket))
+		if err != nil {
+			return err
+		}
+		bucket.SetWriteDeadline(time.Now().Add(time.Minute))
+
+		for _, fileId := range fileIds {
+			if err := bucket.Delete(fileId); err != nil && err != gridfs.ErrFileNotFound {
+				return err
+			}
+		}
+	}
+
+	_, err = repo.results.DeleteMany(ctx, filter)
+	return err
+}
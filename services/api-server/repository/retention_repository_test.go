@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/retention"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestMemoryPurgeDeletesResults(t *testing.T) {
+	jobRepo := NewMemoryRepository()
+	resultRepo := NewMemoryResultRepository()
+
+	testPurgeDeletesResults(t, jobRepo, resultRepo, NewMemoryRetentionRepository(jobRepo, resultRepo))
+}
+
+func TestMongoPurgeDeletesResults(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := client.Database("hasty_test_" + strings.ReplaceAll(uuid.NewString(), "-", ""))
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+
+	// Every result goes to GridFS
+	jobRepo := NewMongoRepository(client, db.Collection(JobsCollection))
+	resultRepo := NewResultRepository(client, db.Collection(ResultsCollection), 0)
+
+	testPurgeDeletesResults(t, jobRepo, resultRepo, NewRetentionRepository(client, db.Collection(JobsCollection), db.Collection(ResultsCollection)))
+}
+
+func testPurgeDeletesResults(t *testing.T, jobRepo JobRepository, resultRepo ResultRepository, retentionRepo RetentionRepository) {
+	now := time.Now().Unix()
+
+	for _, job := range []*domain.Job{
+		{JobId: "job-1", ObjectId: "object-1", TenantId: domain.DefaultTenantId, Status: domain.JobFinished, Timestamp: now - 3600},
+		{JobId: "job-2", ObjectId: "object-2", TenantId: domain.DefaultTenantId, Status: domain.JobFinished, Timestamp: now},
+	} {
+		if err := jobRepo.SetJob(job); err != nil {
+			t.Fatal(err)
+		}
+		if err := resultRepo.SetResult(&domain.JobResult{JobId: job.JobId, TenantId: job.TenantId, Data: []byte(`{"pages": 3}`)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expired, _ := resultRepo.GetResult(domain.DefaultTenantId, "job-1")
+
+	purged, err := retentionRepo.PurgeExpired(domain.JobFinished, now-60, retention.NewNoArchiver())
+	if err != nil || purged != 1 {
+		t.Fatalf("expected one purged job, got %v, %v", purged, err)
+	}
+
+	if _, err := resultRepo.GetResult(domain.DefaultTenantId, "job-1"); err == nil {
+		t.Error("expected the result of the purged job to be deleted")
+	}
+	if expired != nil && expired.File != "" {
+		if data, err := resultRepo.OpenResultData(expired); err == nil {
+			data.Close()
+			t.Error("expected the data of the purged result to be deleted")
+		}
+	}
+
+	kept, err := resultRepo.GetResult(domain.DefaultTenantId, "job-2")
+	if err != nil {
+		t.Fatalf("expected the result of the kept job, got %v", err)
+	}
+	data, err := resultRepo.OpenResultData(kept)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+	if document, _ := io.ReadAll(data); string(document) != `{"pages": 3}` {
+		t.Errorf("unexpected result %q", document)
+	}
+}
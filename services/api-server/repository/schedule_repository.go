@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ScheduleRepository interface {
+	GetScheduleByScheduleId(tenantId, scheduleId string) (*domain.Schedule, error)
+	GetSchedules(tenantId string) ([]*domain.Schedule, error)
+	GetDueSchedules(now int64) ([]*domain.Schedule, error)
+	SetSchedule(schedule *domain.Schedule) error
+	UpdateSchedulePaused(schedule *domain.Schedule) error
+	AdvanceSchedule(schedule *domain.Schedule, expectedNextRun int64) (bool, error)
+	DeleteSchedule(tenantId, scheduleId string) error
+}
+
+type scheduleRepository struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+}
+
+func NewScheduleRepository(client *mongo.Client, collection *mongo.Collection) ScheduleRepository {
+	return &scheduleRepository{client: client, collection: collection}
+}
+
+func (repo *scheduleRepository) GetScheduleByScheduleId(tenantId, scheduleId string) (*domain.Schedule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	schedule := domain.Schedule{}
+
+	if err := repo.collection.FindOne(ctx, bson.M{"tenantId": tenantId, "scheduleId": scheduleId}).Decode(&schedule); err != nil {
+		return nil, err
+	}
+
+	return &schedule, nil
+}
+
+func (repo *scheduleRepository) GetSchedules(tenantId string) ([]*domain.Schedule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"timestamp": 1})
+	return repo.find(ctx, bson.M{"tenantId": tenantId}, opts)
+}
+
+func (repo *scheduleRepository) GetDueSchedules(now int64) ([]*domain.Schedule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"nextRun": 1})
+	return repo.find(ctx, bson.M{"paused": false, "nextRun": bson.M{"$lte": now}}, opts)
+}
+
+func (repo *scheduleRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*domain.Schedule, error) {
+	cursor, err := repo.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := []*domain.Schedule{}
+	if err := cursor.All(ctx, &schedules); err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+func (repo *scheduleRepository) SetSchedule(schedule *domain.Schedule) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := repo.collection.InsertOne(ctx, bson.M{
+		"scheduleId":    schedule.ScheduleId,
+		"tenantId":      schedule.TenantId,
+		"objectId":      schedule.ObjectId,
+		"type":          schedule.Type,
+		"params":        schedule.Params,
+		"cronExpr":      schedule.CronExpr,
+		"misfirePolicy": schedule.MisfirePolicy,
+		"paused":        schedule.Paused,
+		"lastRun":       schedule.LastRun,
+		"nextRun":       schedule.NextRun,
+		"apiKeyId":      schedule.ApiKeyId,
+		"timestamp":     schedule.Timestamp,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	oid, ok := res.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return errors.New("could not type assert oid")
+	}
+	schedule.Id = oid.Hex()
+
+	return nil
+}
+
+func (repo *scheduleRepository) UpdateSchedulePaused(schedule *domain.Schedule) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := repo.collection.FindOneAndUpdate(ctx, bson.M{"tenantId": schedule.TenantId, "scheduleId": schedule.ScheduleId}, bson.M{"$set": bson.M{"paused": schedule.Paused, "nextRun": schedule.NextRun}}).Err(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AdvanceSchedule moves the schedule to its next run only if no other
+// api-server instance has claimed the tick in the meantime.
+func (repo *scheduleRepository) AdvanceSchedule(schedule *domain.Schedule, expectedNextRun int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"scheduleId": schedule.ScheduleId, "paused": false, "nextRun": expectedNextRun}
+	update := bson.M{"$set": bson.M{"lastRun": schedule.LastRun, "nextRun": schedule.NextRun}}
+
+	res, err := repo.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return res.ModifiedCount == 1, nil
+}
+
+func (repo *scheduleRepository) DeleteSchedule(tenantId, scheduleId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := repo.collection.DeleteOne(ctx, bson.M{"tenantId": tenantId, "scheduleId": scheduleId})
+	if err != nil {
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
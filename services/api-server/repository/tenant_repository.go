@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TenantRepository interface {
+	GetTenantByTenantId(tenantId string) (*domain.Tenant, error)
+	UpsertTenant(tenant *domain.Tenant) error
+}
+
+type tenantRepository struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+}
+
+func NewTenantRepository(client *mongo.Client, collection *mongo.Collection) TenantRepository {
+	return &tenantRepository{client: client, collection: collection}
+}
+
+func (repo *tenantRepository) GetTenantByTenantId(tenantId string) (*domain.Tenant, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tenant := domain.Tenant{}
+
+	if err := repo.collection.FindOne(ctx, bson.M{"tenantId": tenantId}).Decode(&tenant); err != nil {
+		return nil, err
+	}
+
+	return &tenant, nil
+}
+
+func (repo *tenantRepository) UpsertTenant(tenant *domain.Tenant) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{
+		"maxConcurrentJobs": tenant.MaxConcurrentJobs,
+		"maxDailyJobs":      tenant.MaxDailyJobs,
+	}}
+
+	if _, err := repo.collection.UpdateOne(ctx, bson.M{"tenantId": tenant.TenantId}, update, options.Update().SetUpsert(true)); err != nil {
+		return err
+	}
+
+	return nil
+}
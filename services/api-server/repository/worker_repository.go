@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type WorkerRepository interface {
+	GetWorkerByWorkerId(workerId string) (*domain.Worker, error)
+	GetWorkers() ([]*domain.Worker, error)
+	UpsertWorker(worker *domain.Worker) error
+}
+
+type workerRepository struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+}
+
+func NewWorkerRepository(client *mongo.Client, collection *mongo.Collection) WorkerRepository {
+	return &workerRepository{client: client, collection: collection}
+}
+
+func (repo *workerRepository) GetWorkerByWorkerId(workerId string) (*domain.Worker, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	worker := domain.Worker{}
+
+	if err := repo.collection.FindOne(ctx, bson.M{"workerId": workerId}).Decode(&worker); err != nil {
+		return nil, err
+	}
+
+	return &worker, nil
+}
+
+func (repo *workerRepository) GetWorkers() ([]*domain.Worker, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"workerId": 1})
+	cursor, err := repo.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := []*domain.Worker{}
+	if err := cursor.All(ctx, &workers); err != nil {
+		return nil, err
+	}
+
+	return workers, nil
+}
+
+func (repo *workerRepository) UpsertWorker(worker *domain.Worker) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{
+		"capacity":  worker.Capacity,
+		"busy":      worker.Busy,
+		"jobTypes":  worker.JobTypes,
+		"version":   worker.Version,
+		"startedAt": worker.StartedAt,
+		"lastSeen":  worker.LastSeen,
+	}}
+
+	if _, err := repo.collection.UpdateOne(ctx, bson.M{"workerId": worker.WorkerId}, update, options.Update().SetUpsert(true)); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type WorkflowRepository interface {
+	GetWorkflowByWorkflowId(tenantId, workflowId string) (*domain.Workflow, error)
+	SetWorkflow(workflow *domain.Workflow) error
+}
+
+type workflowRepository struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+}
+
+func NewWorkflowRepository(client *mongo.Client, collection *mongo.Collection) WorkflowRepository {
+	return &workflowRepository{client: client, collection: collection}
+}
+
+func (repo *workflowRepository) GetWorkflowByWorkflowId(tenantId, workflowId string) (*domain.Workflow, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	workflow := domain.Workflow{}
+
+	if err := repo.collection.FindOne(ctx, bson.M{"tenantId": tenantId, "workflowId": workflowId}).Decode(&workflow); err != nil {
+		return nil, err
+	}
+
+	return &workflow, nil
+}
+
+func (repo *workflowRepository) SetWorkflow(workflow *domain.Workflow) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	nodes := bson.A{}
+	for _, node := range workflow.Nodes {
+		nodes = append(nodes, bson.M{"key": node.Key, "jobId": node.JobId, "dependsOn": node.DependsOn})
+	}
+
+	res, err := repo.collection.InsertOne(ctx, bson.M{
+		"workflowId": workflow.WorkflowId,
+		"tenantId":   workflow.TenantId,
+		"nodes":      nodes,
+		"timestamp":  workflow.Timestamp,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	oid, ok := res.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return errors.New("could not type assert oid")
+	}
+	workflow.Id = oid.Hex()
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package scheduler
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/events"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/events/publishers"
+)
+
+const TickInterval = 5 * time.Second
+
+type SchedulerInterface interface {
+	Start()
+}
+
+type scheduler struct {
+	scheduleService   app.ScheduleService
+	apiService        app.ApiService
+	jobEventPublisher publishers.JobEventPublisher
+}
+
+func NewScheduler(scheduleService app.ScheduleService, apiService app.ApiService, jobEventPublisher publishers.JobEventPublisher) SchedulerInterface {
+	return &scheduler{
+		scheduleService:   scheduleService,
+		apiService:        apiService,
+		jobEventPublisher: jobEventPublisher,
+	}
+}
+
+func (s *scheduler) Start() {
+	go func() {
+		ticker := time.NewTicker(TickInterval)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			s.tick(now)
+		}
+	}()
+}
+
+func (s *scheduler) tick(now time.Time) {
+	schedules, err := s.scheduleService.ClaimDueSchedules(now)
+	if err != nil {
+		log.Printf("could not claim due schedules: %v\n", err.Error())
+		return
+	}
+
+	for _, schedule := range schedules {
+		job, created, err := s.apiService.ProcessJob(&domain.JobRequest{
+			ObjectId:   schedule.ObjectId,
+			Type:       schedule.Type,
+			Params:     schedule.Params,
+			ScheduleId: schedule.ScheduleId,
+			ApiKeyId:   schedule.ApiKeyId,
+			TenantId:   schedule.TenantId,
+		})
+		if err != nil {
+			log.Printf("schedule %v could not create job: %v\n", schedule.ScheduleId, err.Error())
+
+			// A rerun policy rejecting the run skips the tick, a database
+			// error hands it back for the next tick
+			if errors.Is(err, app.ErrInternal) || errors.Is(err, app.ErrDatabaseUnavailable) {
+				s.release(schedule)
+			}
+			continue
+		}
+
+		if !created {
+			continue
+		}
+
+		eventJob := events.NewJobEvent(contracts.SubjectJobCreated, job)
+
+		if err := s.jobEventPublisher.PublishData(eventJob); err != nil {
+			log.Printf("schedule %v could not publish job %v, retrying on the next tick: %v\n", schedule.ScheduleId, job.JobId, err.Error())
+			s.retry(schedule, job)
+		}
+	}
+}
+
+// retry undoes the job and the tick, so the job does not block the object
+// and the run is not lost.
+func (s *scheduler) retry(schedule *domain.Schedule, job *domain.Job) {
+	if err := s.apiService.DiscardJob(job); err != nil {
+		log.Printf("schedule %v could not discard unqueued job %v: %v\n", schedule.ScheduleId, job.JobId, err.Error())
+	}
+
+	s.release(schedule)
+}
+
+// release hands the tick back, so the next tick claims it again.
+func (s *scheduler) release(schedule *domain.Schedule) {
+	if err := s.scheduleService.ReleaseSchedule(schedule); err != nil {
+		log.Println(err.Error())
+	}
+}
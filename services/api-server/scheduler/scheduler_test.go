@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/app"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/repository"
+)
+
+type unlimitedTenantService struct {
+	app.TenantService
+}
+
+func (unlimitedTenantService) CheckQuota(tenantId string, newJobs int64) error {
+	return nil
+}
+
+type switchablePublisher struct {
+	err       error
+	published int
+}
+
+func (p *switchablePublisher) PublishData(jobEvent *contracts.JobEvent) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.published++
+	return nil
+}
+
+func TestTickRetriesRunsThatCouldNotBePublished(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	apiService := app.NewApiService(repo, unlimitedTenantService{}, app.RerunPolicies{
+		domain.DefaultJobType: app.NewFixedCooldownPolicy(app.DefaultCooldown),
+	})
+	scheduleService := app.NewScheduleService(repository.NewMemoryScheduleRepository())
+	publisher := &switchablePublisher{err: errors.New("the publish buffer is full")}
+
+	s := &scheduler{scheduleService: scheduleService, apiService: apiService, jobEventPublisher: publisher}
+
+	schedule, err := scheduleService.CreateSchedule(&domain.ScheduleRequest{ObjectId: "object-1", CronExpr: "* * * * *", TenantId: domain.DefaultTenantId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	due := time.Unix(schedule.NextRun, 0)
+
+	s.tick(due)
+
+	stored, _ := scheduleService.GetSchedule(domain.DefaultTenantId, schedule.ScheduleId)
+	if stored.NextRun != schedule.NextRun || stored.LastRun != 0 {
+		t.Errorf("expected the failed run to be handed back, got next run %v and last run %v", stored.NextRun, stored.LastRun)
+	}
+	if jobs, _ := repo.GetJobs(domain.JobFilter{TenantId: domain.DefaultTenantId, Limit: 10}); len(jobs) != 0 {
+		t.Errorf("expected the unqueued job to be deleted, got %v", len(jobs))
+	}
+
+	publisher.err = nil
+	s.tick(due.Add(TickInterval))
+
+	stored, _ = scheduleService.GetSchedule(domain.DefaultTenantId, schedule.ScheduleId)
+	if publisher.published != 1 || stored.NextRun <= schedule.NextRun {
+		t.Errorf("expected the next tick to run the schedule, got %v published and next run %v", publisher.published, stored.NextRun)
+	}
+	if jobs, _ := repo.GetJobs(domain.JobFilter{TenantId: domain.DefaultTenantId, Limit: 10}); len(jobs) != 1 || jobs[0].ScheduleId != schedule.ScheduleId {
+		t.Errorf("expected the scheduled job, got %v", jobs)
+	}
+}
+
+// rejectingApiService fails every job with err.
+type rejectingApiService struct {
+	app.ApiService
+	err error
+}
+
+func (s rejectingApiService) ProcessJob(jobRequest *domain.JobRequest) (*domain.Job, bool, error) {
+	return nil, false, s.err
+}
+
+func TestTickRetriesRunsOnlyAfterDatabaseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		released bool
+	}{
+		{name: "internal error", err: app.NewError(app.ErrInternal, "could not claim object in mongo"), released: true},
+		{name: "database unavailable", err: app.NewError(app.ErrDatabaseUnavailable, "the database is unavailable"), released: true},
+		{name: "cooldown", err: app.NewError(app.ErrCooldownActive, "the object ran recently")},
+		{name: "in progress", err: app.NewError(app.ErrJobInProgress, "a job for the object is running")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scheduleService := app.NewScheduleService(repository.NewMemoryScheduleRepository())
+			s := &scheduler{scheduleService: scheduleService, apiService: rejectingApiService{err: test.err}, jobEventPublisher: &switchablePublisher{}}
+
+			schedule, err := scheduleService.CreateSchedule(&domain.ScheduleRequest{ObjectId: "object-1", CronExpr: "* * * * *", TenantId: domain.DefaultTenantId})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s.tick(time.Unix(schedule.NextRun, 0))
+
+			stored, _ := scheduleService.GetSchedule(domain.DefaultTenantId, schedule.ScheduleId)
+			if released := stored.NextRun == schedule.NextRun; released != test.released {
+				t.Errorf("expected the run to be released %v, got next run %v after %v", test.released, stored.NextRun, schedule.NextRun)
+			}
+		})
+	}
+}
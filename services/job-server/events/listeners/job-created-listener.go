@@ -2,14 +2,16 @@ package listeners
 
 import (
 	"context"
-	"encoding/json"
 	"log"
-	"math/rand"
 	"time"
 
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/natsconn"
 	"github.com/bogdan-copocean/hasty-server/services/job-server/events"
 	"github.com/bogdan-copocean/hasty-server/services/job-server/events/publishers"
 	"github.com/bogdan-copocean/hasty-server/services/job-server/repository"
+	"github.com/bogdan-copocean/hasty-server/services/job-server/worker"
+	"github.com/google/uuid"
 	"github.com/nats-io/stan.go"
 )
 
@@ -24,22 +26,32 @@ type NatsListenerInterface interface {
 }
 
 type natsListener struct {
-	client             stan.Conn
+	client             natsconn.ConnectionManager
+	workerId           string
+	executors          worker.Executors
 	subject            string
 	queueGroupName     string
 	finishedPublisher  publishers.JobEventPublisher
 	cancelledPublisher publishers.JobEventPublisher
 	repository         repository.MongoRepository
+	quarantineRepo     repository.QuarantineRepository
+	resultRepo         repository.ResultRepository
+	pool               worker.Pool
 }
 
-func NewJobCreatedListener(client stan.Conn, subject, queueGroupName string, finishedPublisher, cancelledPublisher publishers.JobEventPublisher, repository repository.MongoRepository) NatsListenerInterface {
+func NewJobCreatedListener(client natsconn.ConnectionManager, workerId string, executors worker.Executors, subject, queueGroupName string, finishedPublisher, cancelledPublisher publishers.JobEventPublisher, repository repository.MongoRepository, quarantineRepo repository.QuarantineRepository, resultRepo repository.ResultRepository, pool worker.Pool) NatsListenerInterface {
 	return &natsListener{
 		client:             client,
+		workerId:           workerId,
+		executors:          executors,
 		subject:            subject,
 		queueGroupName:     queueGroupName,
 		finishedPublisher:  finishedPublisher,
 		cancelledPublisher: cancelledPublisher,
 		repository:         repository,
+		quarantineRepo:     quarantineRepo,
+		resultRepo:         resultRepo,
+		pool:               pool,
 	}
 }
 
@@ -47,14 +59,26 @@ func (nl *natsListener) ListenAndPublish() {
 
 	aw, _ := time.ParseDuration("50s")
 
-	_, err := nl.client.QueueSubscribe(nl.subject, nl.queueGroupName, func(msg *stan.Msg) {
-		go msgHandler(msg, nl.client, nl.finishedPublisher, nl.cancelledPublisher, nl.repository)
-	},
-		stan.SetManualAckMode(),
-		stan.AckWait(aw),
-		stan.DeliverAllAvailable(),
-		stan.DurableName("job-created-durable-name"),
-	)
+	// MaxInflight keeps messages beyond the pool size in the broker, where
+	// other members of the queue group can pick them up. The pool still
+	// guards the slots, since an unacked message is redelivered after AckWait
+	// while its first delivery may still be running.
+	err := nl.client.Subscribe(func(conn stan.Conn) error {
+		_, err := conn.QueueSubscribe(nl.subject, nl.queueGroupName, func(msg *stan.Msg) {
+			nl.pool.Acquire()
+			go func() {
+				defer nl.pool.Release()
+				msgHandler(msg, nl.workerId, nl.executors, nl.finishedPublisher, nl.cancelledPublisher, nl.repository, nl.quarantineRepo, nl.resultRepo)
+			}()
+		},
+			stan.SetManualAckMode(),
+			stan.MaxInflight(nl.pool.Size()),
+			stan.AckWait(aw),
+			stan.DeliverAllAvailable(),
+			stan.DurableName("job-created-durable-name"),
+		)
+		return err
+	})
 
 	if err != nil {
 		log.Fatalf("queue subscribe error: %v\n", err)
@@ -62,62 +86,100 @@ func (nl *natsListener) ListenAndPublish() {
 
 }
 
-func msgHandler(msg *stan.Msg, client stan.Conn, finishedPublisher, cancelledPublisher publishers.JobEventPublisher, repository repository.MongoRepository) {
-	jobEvent := events.JobEvent{}
-	doneCh := make(chan struct{})
+// msgHandler never stops the process. Messages that cannot be decoded, or
+// have a schema version this server does not know, are quarantined and
+// acked, since redelivering them cannot help. When storing or publishing
+// the result fails, the message is left unacked, so it is redelivered after
+// AckWait. Result data too large for the event is offloaded to resultRepo
+// before the job:finished event is published.
+func msgHandler(msg *stan.Msg, workerId string, executors worker.Executors, finishedPublisher, cancelledPublisher publishers.JobEventPublisher, repository repository.MongoRepository, quarantineRepo repository.QuarantineRepository, resultRepo repository.ResultRepository) {
+	jobEvent, err := contracts.DecodeJobEventMessage(nil, msg.Data)
+	if err != nil {
+		quarantine(msg, err, quarantineRepo)
+		return
+	}
 
-	// ctx to trigger cancellation inside sleeping goroutine
+	doneCh := make(chan bool, 1)
+
+	// ctx to trigger cancellation inside the executor
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	err := json.Unmarshal(msg.Data, &jobEvent)
-	if err != nil {
-		log.Fatal(err.Error())
-	}
-
-	sleepTimeUsed := rand.Intn(MaxSleepTime-MinSleepTime) + MinSleepTime
-	jobEvent.Job.SleepTimeUsed = sleepTimeUsed
+	jobEvent.Job.WorkerId = workerId
 
 	go func() {
-
-		time.Sleep(time.Duration(sleepTimeUsed) * time.Second)
-		jobEvent.Job.SleepTimeUsed = sleepTimeUsed
+		result := executors.Run(ctx, &jobEvent.Job)
 
 		select {
 		case <-ctx.Done():
 			jobEvent.Job.Status = "cancelled"
 
-			if err := repository.SetJob(&jobEvent); err != nil {
-				log.Fatalf("could not insert cancelled msg to repo: %v\n", err.Error())
-			}
-
 			// Publish job cancelled
-			if err := finishedPublisher.PublishData(&jobEvent); err != nil {
-				log.Fatalf("could not publish cancelled job event: %v", err.Error())
+			if storeAndPublish(jobEvent, repository, finishedPublisher) {
+				msg.Ack()
 			}
 
-			msg.Ack()
-
 		default:
 			jobEvent.Job.Status = "finished"
-
-			if err := repository.SetJob(&jobEvent); err != nil {
-				log.Fatalf("could not insert finished msg to repo: %v\n", err.Error())
+			if result != nil && result.Error != nil {
+				jobEvent.Job.Status = "failed"
 			}
+			jobEvent.Job.Result = result
 
 			// Publish job finished
-			if err := finishedPublisher.PublishData(&jobEvent); err != nil {
-				log.Fatalf("could not publish finished job event: %v", err.Error())
-			}
-			doneCh <- struct{}{}
+			doneCh <- offloadResult(jobEvent, resultRepo) && storeAndPublish(jobEvent, repository, finishedPublisher)
 		}
 
 	}()
 
 	select {
-	case <-doneCh:
-		msg.Ack()
+	case ok := <-doneCh:
+		if ok {
+			msg.Ack()
+		}
 	case <-time.After(CancellationJobTime):
 		return
 	}
 }
+
+func storeAndPublish(jobEvent *contracts.JobEvent, repository repository.MongoRepository, publisher publishers.JobEventPublisher) bool {
+	if err := repository.SetJob(jobEvent); err != nil {
+		log.Printf("could not insert %v msg to repo, waiting for redelivery: %v\n", jobEvent.Job.Status, err.Error())
+		return false
+	}
+
+	if err := publisher.PublishData(jobEvent); err != nil {
+		log.Printf("could not publish %v job event, waiting for redelivery: %v\n", jobEvent.Job.Status, err.Error())
+		return false
+	}
+
+	return true
+}
+
+func offloadResult(jobEvent *contracts.JobEvent, resultRepo repository.ResultRepository) bool {
+	if err := resultRepo.OffloadResult(jobEvent.TenantId, jobEvent.Job.JobId, jobEvent.Job.Result); err != nil {
+		log.Printf("could not store the result of job %v, waiting for redelivery: %v\n", jobEvent.Job.JobId, err.Error())
+		return false
+	}
+
+	return true
+}
+
+func quarantine(msg *stan.Msg, reason error, quarantineRepo repository.QuarantineRepository) {
+	message := events.QuarantinedMessage{
+		MessageId: uuid.New().String(),
+		Subject:   msg.Subject,
+		Sequence:  msg.Sequence,
+		Data:      msg.Data,
+		Error:     reason.Error(),
+		Timestamp: time.Now().Unix(),
+	}
+
+	if err := quarantineRepo.SetMessage(&message); err != nil {
+		log.Printf("could not quarantine msg %v on %v: %v\n", msg.Sequence, msg.Subject, err.Error())
+		return
+	}
+
+	log.Printf("quarantined msg %v on %v as %v: %v\n", msg.Sequence, msg.Subject, message.MessageId, reason.Error())
+	msg.Ack()
+}
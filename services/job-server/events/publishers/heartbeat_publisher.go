@@ -0,0 +1,55 @@
+package publishers
+
+import (
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/natsconn"
+	"github.com/nats-io/nats.go"
+)
+
+type HeartbeatPublisher interface {
+	PublishHeartbeat(heartbeat *contracts.WorkerHeartbeat) error
+}
+
+type heartbeatPublisher struct {
+	Client  natsconn.ConnectionManager
+	Subject string
+	Source  string
+	Mode    contracts.CloudEventsMode
+}
+
+// NewHeartbeatPublisher publishes on the plain NATS connection underneath
+// the streaming one. Heartbeats are only useful while they are fresh, so
+// there is no point in storing them in a channel. Plain NATS has headers,
+// so binary mode uses them.
+func NewHeartbeatPublisher(client natsconn.ConnectionManager, subject, source string, mode contracts.CloudEventsMode) HeartbeatPublisher {
+	return &heartbeatPublisher{
+		Client:  client,
+		Subject: subject,
+		Source:  source,
+		Mode:    mode,
+	}
+}
+
+func (hp *heartbeatPublisher) PublishHeartbeat(heartbeat *contracts.WorkerHeartbeat) error {
+	cloudEvent, err := contracts.NewWorkerHeartbeatCloudEvent(hp.Source, heartbeat)
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(hp.Subject)
+	if hp.Mode == contracts.CloudEventsBinary {
+		msg.Header, msg.Data = cloudEvent.Binary()
+	} else {
+		msg.Header.Set("content-type", contracts.ContentTypeCloudEventsJson)
+		if msg.Data, err = cloudEvent.Structured(); err != nil {
+			return err
+		}
+	}
+
+	conn, err := hp.Client.Conn()
+	if err != nil {
+		return err
+	}
+
+	return conn.NatsConn().PublishMsg(msg)
+}
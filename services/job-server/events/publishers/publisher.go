@@ -1,39 +1,45 @@
 package publishers
 
 import (
-	"encoding/json"
-	"log"
+	"fmt"
 
-	"github.com/bogdan-copocean/hasty-server/services/job-server/events"
-	"github.com/nats-io/stan.go"
-)
-
-const (
-	JobFinishedSubject  = "job:finished"
-	JobCancelledSubject = "job:cancelled"
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/natsconn"
 )
 
 type JobEventPublisher interface {
-	PublishData(jobEvent *events.JobEvent) error
+	PublishData(jobEvent *contracts.JobEvent) error
 }
 
 type jobEventPublisher struct {
-	Client  stan.Conn
-	Subject string
+	Client   natsconn.ConnectionManager
+	Subject  string
+	Source   string
+	Encoding contracts.Encoding
+	Mode     contracts.CloudEventsMode
 }
 
-func NewJobEventPublisher(client stan.Conn, subject string) JobEventPublisher {
+// NewJobEventPublisher wraps every event in a CloudEvent from source, sent
+// in mode with its data in encoding.
+func NewJobEventPublisher(client natsconn.ConnectionManager, subject, source string, encoding contracts.Encoding, mode contracts.CloudEventsMode) JobEventPublisher {
 	return &jobEventPublisher{
-		Client:  client,
-		Subject: subject,
+		Client:   client,
+		Subject:  subject,
+		Source:   source,
+		Encoding: encoding,
+		Mode:     mode,
 	}
 }
 
-func (nl *jobEventPublisher) PublishData(jobEvent *events.JobEvent) error {
+func (nl *jobEventPublisher) PublishData(jobEvent *contracts.JobEvent) error {
 
-	data, err := json.Marshal(jobEvent)
+	var data []byte
+	cloudEvent, err := contracts.NewJobCloudEvent(nl.Source, jobEvent, nl.Encoding)
+	if err == nil {
+		data, err = contracts.EncodeCloudEvent(cloudEvent, nl.Mode)
+	}
 	if err != nil {
-		log.Fatalf("could not marshal event with jobId: %v, reason: %v", jobEvent.Job.Id, err.Error())
+		return fmt.Errorf("could not marshal event with jobId: %v, reason: %v", jobEvent.Job.JobId, err.Error())
 	}
 
 	if err := nl.Client.Publish(nl.Subject, data); err != nil {
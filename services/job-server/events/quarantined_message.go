@@ -0,0 +1,13 @@
+package events
+
+// QuarantinedMessage is a job:created message that could not be decoded,
+// kept with its raw payload (base64 in JSON) for inspection.
+type QuarantinedMessage struct {
+	Id        string `json:"id,omitempty" bson:"_id"`
+	MessageId string `json:"message_id"`
+	Subject   string `json:"subject"`
+	Sequence  uint64 `json:"sequence"`
+	Data      []byte `json:"data"`
+	Error     string `json:"error"`
+	Timestamp int64  `json:"timestamp"`
+}
@@ -0,0 +1,38 @@
+package interfaces
+
+import (
+	"net/http"
+
+	"github.com/bogdan-copocean/hasty-server/natsconn"
+	"github.com/unrolled/render"
+)
+
+type HealthHandlerInterface interface {
+	GetHandler(w http.ResponseWriter, r *http.Request)
+}
+
+type healthHandler struct {
+	conn natsconn.ConnectionManager
+}
+
+func NewHealthHandler(conn natsconn.ConnectionManager) HealthHandlerInterface {
+	return &healthHandler{conn: conn}
+}
+
+// GetHandler answers 503 while NATS is reconnecting.
+func (handler *healthHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	nats := handler.conn.Status()
+
+	status := http.StatusOK
+	if nats.State != natsconn.ConnectionConnected {
+		status = http.StatusServiceUnavailable
+	}
+
+	render.JSON(w, status, map[string]interface{}{
+		"message": map[string]interface{}{
+			"nats": nats,
+		},
+	})
+}
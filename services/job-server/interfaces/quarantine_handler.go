@@ -0,0 +1,80 @@
+package interfaces
+
+import (
+	"net/http"
+
+	"github.com/bogdan-copocean/hasty-server/services/job-server/repository"
+	"github.com/go-chi/chi/v5"
+	"github.com/unrolled/render"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type QuarantineHandlerInterface interface {
+	GetAllHandler(w http.ResponseWriter, r *http.Request)
+	GetHandler(w http.ResponseWriter, r *http.Request)
+	DeleteHandler(w http.ResponseWriter, r *http.Request)
+}
+
+type quarantineHandler struct {
+	quarantineRepo repository.QuarantineRepository
+}
+
+func NewQuarantineHandler(quarantineRepo repository.QuarantineRepository) QuarantineHandlerInterface {
+	return &quarantineHandler{quarantineRepo: quarantineRepo}
+}
+
+func (handler *quarantineHandler) GetAllHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	messages, err := handler.quarantineRepo.GetMessages()
+	if err != nil {
+		render.JSON(w, http.StatusBadRequest, map[string]string{
+			"message": "could not get quarantined messages from mongo " + err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": messages,
+	})
+}
+
+func (handler *quarantineHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	messageId := chi.URLParam(r, "messageId")
+
+	message, err := handler.quarantineRepo.GetMessageByMessageId(messageId)
+	if err != nil {
+		render.JSON(w, http.StatusBadRequest, map[string]string{
+			"message": quarantineError(messageId, err),
+		})
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": message,
+	})
+}
+
+func (handler *quarantineHandler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	messageId := chi.URLParam(r, "messageId")
+
+	if err := handler.quarantineRepo.DeleteMessage(messageId); err != nil {
+		render.JSON(w, http.StatusBadRequest, map[string]string{
+			"message": quarantineError(messageId, err),
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func quarantineError(messageId string, err error) string {
+	if err == mongo.ErrNoDocuments {
+		return "no quarantined message with id: " + messageId
+	}
+	return "could not access quarantined message in mongo " + err.Error()
+}
@@ -0,0 +1,51 @@
+package interfaces
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/bogdan-copocean/hasty-server/retention"
+	"github.com/unrolled/render"
+)
+
+type RetentionHandlerInterface interface {
+	PurgeHandler(w http.ResponseWriter, r *http.Request)
+}
+
+type retentionHandler struct {
+	purger retention.Purger
+}
+
+func NewRetentionHandler(purger retention.Purger) RetentionHandlerInterface {
+	return &retentionHandler{purger: purger}
+}
+
+// PurgeHandler purges the expired job events right away, or with
+// ?dry_run=true reports how many would be purged.
+func (handler *retentionHandler) PurgeHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			render.JSON(w, http.StatusBadRequest, map[string]string{
+				"message": "dry_run must be true or false",
+			})
+			return
+		}
+		dryRun = parsed
+	}
+
+	report, err := handler.purger.Purge(dryRun)
+	if err != nil {
+		render.JSON(w, http.StatusInternalServerError, map[string]string{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": report,
+	})
+}
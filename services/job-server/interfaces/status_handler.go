@@ -0,0 +1,32 @@
+package interfaces
+
+import (
+	"net/http"
+
+	"github.com/bogdan-copocean/hasty-server/services/job-server/worker"
+	"github.com/unrolled/render"
+)
+
+type StatusHandlerInterface interface {
+	GetHandler(w http.ResponseWriter, r *http.Request)
+}
+
+type statusHandler struct {
+	instanceId string
+	pool       worker.Pool
+}
+
+func NewStatusHandler(instanceId string, pool worker.Pool) StatusHandlerInterface {
+	return &statusHandler{instanceId: instanceId, pool: pool}
+}
+
+func (handler *statusHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	render := render.New()
+
+	render.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": map[string]interface{}{
+			"instance_id": handler.instanceId,
+			"workers":     handler.pool.Stats(),
+		},
+	})
+}
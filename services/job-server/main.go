@@ -1,14 +1,22 @@
 package main
 
 import (
+	"expvar"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/bogdan-copocean/hasty-server/services/job-server/events"
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/natsconn"
+	"github.com/bogdan-copocean/hasty-server/retention"
 	"github.com/bogdan-copocean/hasty-server/services/job-server/events/listeners"
 	"github.com/bogdan-copocean/hasty-server/services/job-server/events/publishers"
+	"github.com/bogdan-copocean/hasty-server/services/job-server/interfaces"
 	"github.com/bogdan-copocean/hasty-server/services/job-server/repository"
+	"github.com/bogdan-copocean/hasty-server/services/job-server/worker"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
@@ -22,27 +30,147 @@ func main() {
 		log.Fatalf("could not get the host name: %v\n", err)
 	}
 
-	// Mongo Repository
-	repo := repository.ConnectToMongo()
+	// Mongo Repositories
+	client := repository.ConnectToMongo(repository.DefaultMongoUrl)
+	db := client.Database(repository.DatabaseName)
+	repo := repository.NewMongoRepository(client, db.Collection(repository.JobEventsCollection))
+	quarantineRepo := repository.NewQuarantineRepository(client, db.Collection(repository.QuarantineCollection))
 
-	// Nats
-	conn := events.ConnectToNats(clientId)
+	// Results larger than RESULT_EVENT_LIMIT bytes are written to the api
+	// server's database at RESULTS_MONGO_URL, and the job:finished event only
+	// references them
+	resultsMongoUrl := os.Getenv("RESULTS_MONGO_URL")
+	if resultsMongoUrl == "" {
+		resultsMongoUrl = repository.DefaultResultsMongoUrl
+	}
+	resultsClient := repository.ConnectToMongo(resultsMongoUrl)
+	resultRepo := repository.NewResultRepository(resultsClient, resultsClient.Database(repository.DatabaseName), getEnvInt("RESULT_EVENT_LIMIT", repository.DefaultResultEventLimit))
+
+	// Retention, e.g. RETENTION=finished=30d,cancelled=90d, archived to
+	// RETENTION_ARCHIVE before deletion
+	retentionPolicy, err := retention.ParsePolicy(os.Getenv("RETENTION"))
+	if err != nil {
+		log.Fatalf("could not parse RETENTION: %v\n", err)
+	}
+	archiver, err := retention.ParseArchiver(os.Getenv("RETENTION_ARCHIVE"), db.Collection(repository.JobEventsArchiveCollection), repository.JobEventsCollection)
+	if err != nil {
+		log.Fatalf("could not parse RETENTION_ARCHIVE: %v\n", err)
+	}
+	purger := retention.NewPurger(repository.NewRetentionRepository(client, db.Collection(repository.JobEventsCollection)), retentionPolicy, archiver, "job events")
+	purger.Start(getEnvDuration("RETENTION_INTERVAL", retention.DefaultPurgeInterval))
+
+	// Nats at NATS_URL, publishes are not buffered while reconnecting: a
+	// result that could not be published leaves its job:created message
+	// unacked instead
+	natsUrl := os.Getenv("NATS_URL")
+	if natsUrl == "" {
+		natsUrl = natsconn.DefaultNatsUrl
+	}
+	conn := natsconn.ConnectToNats(natsUrl, clientId, 0)
+	expvar.Publish("nats", expvar.Func(func() interface{} { return conn.Status() }))
+
+	// Job events are published as EVENT_ENCODING, consumers read both
+	eventEncoding, err := contracts.ParseEncoding(os.Getenv("EVENT_ENCODING"))
+	if err != nil {
+		log.Fatalf("could not parse EVENT_ENCODING: %v\n", err)
+	}
+
+	// Events are CloudEvents from this host, sent in CLOUDEVENTS_MODE,
+	// consumers read both modes
+	cloudEventsMode, err := contracts.ParseCloudEventsMode(os.Getenv("CLOUDEVENTS_MODE"))
+	if err != nil {
+		log.Fatalf("could not parse CLOUDEVENTS_MODE: %v\n", err)
+	}
+	eventSource := contracts.CloudEventSource("job-server", clientId)
 
 	// Job Finished Publisher
-	jobFinishedSubject := publishers.JobFinishedSubject
-	jobFinishedPublisher := publishers.NewJobEventPublisher(conn, jobFinishedSubject)
+	jobFinishedSubject := contracts.SubjectJobFinished
+	jobFinishedPublisher := publishers.NewJobEventPublisher(conn, jobFinishedSubject, eventSource, eventEncoding, cloudEventsMode)
 
 	// Job Cancelled Publisher
-	jobCancelledSubject := publishers.JobCancelledSubject
-	jobCancelledPublisher := publishers.NewJobEventPublisher(conn, jobCancelledSubject)
+	jobCancelledSubject := contracts.SubjectJobCancelled
+	jobCancelledPublisher := publishers.NewJobEventPublisher(conn, jobCancelledSubject, eventSource, eventEncoding, cloudEventsMode)
+
+	// Worker pool, WORKER_POOL_SIZE jobs run at once on this instance
+	pool := worker.NewPool(getEnvInt("WORKER_POOL_SIZE", worker.DefaultPoolSize))
+
+	// Executors by job type, every type sleeps unless it has its own
+	executors := worker.Executors{
+		worker.AnyJobType: worker.NewSleepExecutor(listeners.MinSleepTime, listeners.MaxSleepTime),
+	}
 
 	// Job Created Listener
-	jobCreatedListenerSubject := "job:created"
+	jobCreatedListenerSubject := contracts.SubjectJobCreated
 	jobCreatedQGroup := "job-created-group"
-	jobCreatedListener := listeners.NewJobCreatedListener(conn, jobCreatedListenerSubject, jobCreatedQGroup, jobFinishedPublisher, jobCancelledPublisher, repo)
+	jobCreatedListener := listeners.NewJobCreatedListener(conn, clientId, executors, jobCreatedListenerSubject, jobCreatedQGroup, jobFinishedPublisher, jobCancelledPublisher, repo, quarantineRepo, resultRepo, pool)
 
 	// Listen and publish events
 	jobCreatedListener.ListenAndPublish()
 
+	// Heartbeats, so the api server knows this worker and what it can run
+	heartbeatPublisher := publishers.NewHeartbeatPublisher(conn, contracts.SubjectWorkerHeartbeat, eventSource, cloudEventsMode)
+	heartbeater := worker.NewHeartbeater(clientId, pool, getEnvList("WORKER_JOB_TYPES", []string{worker.AnyJobType}), heartbeatPublisher)
+	heartbeater.Start()
+
+	statusHandler := interfaces.NewStatusHandler(clientId, pool)
+	r.Get("/status", statusHandler.GetHandler)
+	expvar.Publish("workers", expvar.Func(func() interface{} { return pool.Stats() }))
+
+	healthHandler := interfaces.NewHealthHandler(conn)
+	r.Get("/healthz", healthHandler.GetHandler)
+	r.Handle("/debug/vars", expvar.Handler())
+
+	quarantineHandler := interfaces.NewQuarantineHandler(quarantineRepo)
+	r.Get("/quarantine", quarantineHandler.GetAllHandler)
+	r.Get("/quarantine/{messageId}", quarantineHandler.GetHandler)
+	r.Delete("/quarantine/{messageId}", quarantineHandler.DeleteHandler)
+
+	retentionHandler := interfaces.NewRetentionHandler(purger)
+	r.Post("/retention/purge", retentionHandler.PurgeHandler)
+
 	http.ListenAndServe(":9091", r)
 }
+
+func getEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 1 {
+		log.Fatalf("%v must be a positive integer\n", key)
+	}
+
+	return parsed
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		log.Fatalf("%v must be a positive duration\n", key)
+	}
+
+	return parsed
+}
+
+func getEnvList(key string, fallback []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
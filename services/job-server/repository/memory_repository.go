@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"sync"
+
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/services/job-server/events"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The memory repositories keep job events and quarantined messages in
+// process memory, for "hasty-server dev".
+
+type memoryRepository struct {
+	mu        sync.Mutex
+	jobEvents []*contracts.JobEvent
+}
+
+func NewMemoryRepository() MongoRepository {
+	return &memoryRepository{}
+}
+
+func (repo *memoryRepository) SetJob(jobEvent *contracts.JobEvent) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	stored := *jobEvent
+	repo.jobEvents = append(repo.jobEvents, &stored)
+
+	return nil
+}
+
+type memoryQuarantineRepository struct {
+	mu       sync.Mutex
+	messages []*events.QuarantinedMessage
+}
+
+// NewMemoryQuarantineRepository returns mongo.ErrNoDocuments for missing
+// messages, like the mongo one.
+func NewMemoryQuarantineRepository() QuarantineRepository {
+	return &memoryQuarantineRepository{}
+}
+
+func (repo *memoryQuarantineRepository) GetMessageByMessageId(messageId string) (*events.QuarantinedMessage, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, message := range repo.messages {
+		if message.MessageId == messageId {
+			found := *message
+			return &found, nil
+		}
+	}
+
+	return nil, mongo.ErrNoDocuments
+}
+
+// GetMessages returns the newest messages first.
+func (repo *memoryQuarantineRepository) GetMessages() ([]*events.QuarantinedMessage, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	messages := []*events.QuarantinedMessage{}
+	for i := len(repo.messages) - 1; i >= 0; i-- {
+		found := *repo.messages[i]
+		messages = append(messages, &found)
+	}
+
+	return messages, nil
+}
+
+func (repo *memoryQuarantineRepository) SetMessage(message *events.QuarantinedMessage) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	stored := *message
+	stored.Id = uuid.NewString()
+	repo.messages = append(repo.messages, &stored)
+
+	return nil
+}
+
+func (repo *memoryQuarantineRepository) DeleteMessage(messageId string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for i, stored := range repo.messages {
+		if stored.MessageId == messageId {
+			repo.messages = append(repo.messages[:i], repo.messages[i+1:]...)
+			return nil
+		}
+	}
+
+	return mongo.ErrNoDocuments
+}
+
+type inlineResultRepository struct{}
+
+// NewInlineResultRepository leaves all result data in the event, since the
+// api server of "hasty-server dev" keeps its results in memory.
+func NewInlineResultRepository() ResultRepository {
+	return inlineResultRepository{}
+}
+
+func (inlineResultRepository) OffloadResult(tenantId, jobId string, result *contracts.JobResult) error {
+	return nil
+}
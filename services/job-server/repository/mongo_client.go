@@ -10,23 +10,56 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
-func ConnectToMongo() MongoRepository {
+const (
+	DatabaseName         = "jobs_db"
+	JobEventsCollection  = "job_events"
+	QuarantineCollection = "quarantine"
+)
+
+const (
+	DefaultMongoUrl = "mongodb://job_mongo_db:27017"
+
+	// DefaultResultsMongoUrl is the api server's mongo, which keeps the
+	// results. It is the only database the services share, deployments
+	// should give the job servers a user limited to contracts.ResultsBucket
+	DefaultResultsMongoUrl = "mongodb://api_mongo_db:27017"
+)
+
+const (
+	MinConnectWait = time.Second
+	MaxConnectWait = 30 * time.Second
+)
+
+// ConnectToMongo retries with backoff until mongo at url answers a ping,
+// so the service can start before the database is up.
+func ConnectToMongo(url string) *mongo.Client {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://job_mongo_db:27017"))
-	// client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://localhost:27017"))
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(url))
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
+	wait := MinConnectWait
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		err := client.Ping(ctx, readpref.Primary())
+		cancel()
 
-	if err = client.Ping(ctx, readpref.Primary()); err != nil {
-		log.Fatal(err)
+		if err == nil {
+			break
+		}
+
+		log.Printf("could not reach mongo: %v, retrying in %v\n", err, wait)
+
+		time.Sleep(wait)
+		if wait *= 2; wait > MaxConnectWait {
+			wait = MaxConnectWait
+		}
 	}
-	collection := client.Database("jobs_db").Collection("job_events")
 
-	return NewMongoRepository(client, collection)
+	log.Println("Connected to Mongo")
+
+	return client
 }
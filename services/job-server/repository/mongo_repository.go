@@ -4,13 +4,13 @@ import (
 	"context"
 	"time"
 
-	"github.com/bogdan-copocean/hasty-server/services/job-server/events"
+	"github.com/bogdan-copocean/hasty-server/contracts"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type MongoRepository interface {
-	SetJob(*events.JobEvent) error
+	SetJob(*contracts.JobEvent) error
 }
 
 type mongoRepository struct {
@@ -22,11 +22,11 @@ func NewMongoRepository(client *mongo.Client, collection *mongo.Collection) Mong
 	return &mongoRepository{client: client, collection: collection}
 }
 
-func (repo *mongoRepository) SetJob(jobEvent *events.JobEvent) error {
+func (repo *mongoRepository) SetJob(jobEvent *contracts.JobEvent) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	_, err := repo.collection.InsertOne(ctx, bson.M{"jobId": jobEvent.Job.JobId, "objectId": jobEvent.Job.ObjectId, "sleepTimeUsed": jobEvent.Job.SleepTimeUsed, "status": jobEvent.Job.Status})
+	_, err := repo.collection.InsertOne(ctx, bson.M{"jobId": jobEvent.Job.JobId, "tenantId": jobEvent.TenantId, "objectId": jobEvent.Job.ObjectId, "type": jobEvent.Job.Type, "sleepTimeUsed": jobEvent.Job.SleepTimeUsed, "status": jobEvent.Job.Status, "workerId": jobEvent.Job.WorkerId})
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultResultEventLimit is the largest result data sent in a job:finished
+// event, well below the 1MB NATS payload limit since a structured
+// CloudEvent carries Protobuf in base64.
+const DefaultResultEventLimit = 256 << 10
+
+type ResultRepository interface {
+	// OffloadResult moves the data of result to contracts.ResultsBucket
+	// when it is above the limit, leaving its File and Size in result.
+	OffloadResult(tenantId, jobId string, result *contracts.JobResult) error
+}
+
+type resultRepository struct {
+	client     *mongo.Client
+	db         *mongo.Database
+	eventLimit int
+}
+
+// NewResultRepository writes result data above eventLimit bytes to the
+// results bucket of db, the api server's database.
+func NewResultRepository(client *mongo.Client, db *mongo.Database, eventLimit int) ResultRepository {
+	return &resultRepository{client: client, db: db, eventLimit: eventLimit}
+}
+
+func (repo *resultRepository) OffloadResult(tenantId, jobId string, result *contracts.JobResult) error {
+	if result == nil || len(result.Data) <= repo.eventLimit {
+		return nil
+	}
+
+	bucket, err := gridfs.NewBucket(repo.db, options.GridFSBucket().SetName(contracts.ResultsBucket))
+	if err != nil {
+		return err
+	}
+	bucket.SetReadDeadline(time.Now().Add(10 * time.Second))
+	bucket.SetWriteDeadline(time.Now().Add(time.Minute))
+
+	name := tenantId + "/" + jobId
+
+	// A redelivered job reuses the data written the first time, so the
+	// api server keeps one file whichever event it stores
+	fileId, err := repo.findFile(bucket, name)
+	if err != nil {
+		return err
+	}
+	if fileId.IsZero() {
+		if fileId, err = bucket.UploadFromStream(name, bytes.NewReader(result.Data)); err != nil {
+			return err
+		}
+	}
+
+	result.File = fileId.Hex()
+	result.Size = int64(len(result.Data))
+	result.Data = nil
+
+	return nil
+}
+
+// findFile returns the id of the newest complete file called name, GridFS
+// writes a file's document after all of its chunks.
+func (repo *resultRepository) findFile(bucket *gridfs.Bucket, name string) (primitive.ObjectID, error) {
+	cursor, err := bucket.Find(bson.M{"filename": name}, options.GridFSFind().SetSort(bson.M{"uploadDate": -1}).SetLimit(1))
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	defer cursor.Close(ctx)
+
+	if !cursor.Next(ctx) {
+		return primitive.NilObjectID, cursor.Err()
+	}
+
+	file := struct {
+		Id primitive.ObjectID `bson:"_id"`
+	}{}
+	if err := cursor.Decode(&file); err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return file.Id, nil
+}
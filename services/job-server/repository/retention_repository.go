@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/retention"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const JobEventsArchiveCollection = "job_events_archive"
+
+type retentionRepository struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+}
+
+// NewRetentionRepository is the retention.Store of the job events in
+// collection.
+func NewRetentionRepository(client *mongo.Client, collection *mongo.Collection) retention.Store {
+	return &retentionRepository{client: client, collection: collection}
+}
+
+// expiredJobEvents finds job events by the creation time of their _id,
+// since they carry no timestamp of their own.
+func expiredJobEvents(status string, before int64) bson.M {
+	return bson.M{"status": status, "_id": bson.M{"$lt": primitive.NewObjectIDFromTimestamp(time.Unix(before, 0))}}
+}
+
+func (repo *retentionRepository) CountExpired(status string, before int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return repo.collection.CountDocuments(ctx, expiredJobEvents(status, before))
+}
+
+func (repo *retentionRepository) PurgeExpired(status string, before int64, archiver retention.Archiver) (int64, error) {
+	return retention.PurgeCollection(repo.collection, expiredJobEvents(status, before), archiver, nil)
+}
@@ -0,0 +1,92 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/contracts"
+)
+
+// Codes of the errors Run reports for executors that misbehaved.
+const (
+	ErrCodeNoExecutor     = "no_executor"
+	ErrCodeExecutorFailed = "executor_failed"
+	ErrCodeExecutorPanic  = "executor_panic"
+	ErrCodeInvalidResult  = "invalid_result"
+)
+
+// Executor runs jobs. It returns the result document, which must marshal to
+// JSON, or an error. A *contracts.JobError is reported as it is, with its
+// code and details.
+type Executor interface {
+	Execute(ctx context.Context, job *contracts.Job) (interface{}, error)
+}
+
+type ExecutorFunc func(ctx context.Context, job *contracts.Job) (interface{}, error)
+
+func (f ExecutorFunc) Execute(ctx context.Context, job *contracts.Job) (interface{}, error) {
+	return f(ctx, job)
+}
+
+// Executors runs every job type with its own executor, and the types
+// without one with the executor of AnyJobType.
+type Executors map[string]Executor
+
+// Run executes job and always returns a result: executors that fail,
+// panic or return something that is not JSON end up in its error.
+func (e Executors) Run(ctx context.Context, job *contracts.Job) (result *contracts.JobResult) {
+	executor, ok := e[job.Type]
+	if !ok {
+		executor, ok = e[AnyJobType]
+	}
+	if !ok {
+		return errorResult(&contracts.JobError{Code: ErrCodeNoExecutor, Message: fmt.Sprintf("no executor for job type %v", job.Type)})
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			result = errorResult(&contracts.JobError{Code: ErrCodeExecutorPanic, Message: fmt.Sprint(recovered)})
+		}
+	}()
+
+	document, err := executor.Execute(ctx, job)
+	if err != nil {
+		jobError := &contracts.JobError{}
+		if !errors.As(err, &jobError) {
+			jobError = &contracts.JobError{Code: ErrCodeExecutorFailed, Message: err.Error()}
+		}
+		return errorResult(jobError)
+	}
+
+	data, err := json.Marshal(document)
+	if err != nil {
+		return errorResult(&contracts.JobError{Code: ErrCodeInvalidResult, Message: err.Error()})
+	}
+
+	return &contracts.JobResult{Data: data}
+}
+
+func errorResult(jobError *contracts.JobError) *contracts.JobResult {
+	return &contracts.JobResult{Error: jobError}
+}
+
+// NewSleepExecutor sleeps between min and max seconds, or until ctx is
+// done, and reports how long it meant to.
+func NewSleepExecutor(min, max int) Executor {
+	return ExecutorFunc(func(ctx context.Context, job *contracts.Job) (interface{}, error) {
+		sleepTimeUsed := rand.Intn(max-min) + min
+		job.SleepTimeUsed = sleepTimeUsed
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Duration(sleepTimeUsed) * time.Second):
+		}
+
+		return map[string]int{"sleep_time_used": sleepTimeUsed}, nil
+	})
+}
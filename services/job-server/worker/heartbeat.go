@@ -0,0 +1,70 @@
+package worker
+
+import (
+	"log"
+	"time"
+
+	"github.com/bogdan-copocean/hasty-server/contracts"
+	"github.com/bogdan-copocean/hasty-server/services/job-server/events/publishers"
+)
+
+const HeartbeatInterval = 10 * time.Second
+
+// Version is set at build time with -ldflags "-X .../worker.Version=...".
+var Version = "dev"
+
+// AnyJobType is advertised by workers that run every job type.
+const AnyJobType = "*"
+
+type Heartbeater interface {
+	Start()
+}
+
+type heartbeater struct {
+	workerId  string
+	pool      Pool
+	jobTypes  []string
+	startedAt int64
+	publisher publishers.HeartbeatPublisher
+}
+
+func NewHeartbeater(workerId string, pool Pool, jobTypes []string, publisher publishers.HeartbeatPublisher) Heartbeater {
+	return &heartbeater{
+		workerId:  workerId,
+		pool:      pool,
+		jobTypes:  jobTypes,
+		startedAt: time.Now().Unix(),
+		publisher: publisher,
+	}
+}
+
+// Start publishes a heartbeat right away and then every HeartbeatInterval.
+func (h *heartbeater) Start() {
+	go func() {
+		ticker := time.NewTicker(HeartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			h.beat()
+			<-ticker.C
+		}
+	}()
+}
+
+func (h *heartbeater) beat() {
+	stats := h.pool.Stats()
+
+	heartbeat := contracts.WorkerHeartbeat{
+		WorkerId:  h.workerId,
+		Capacity:  stats.Size,
+		Busy:      stats.Busy,
+		JobTypes:  h.jobTypes,
+		Version:   Version,
+		StartedAt: h.startedAt,
+		Timestamp: time.Now().Unix(),
+	}
+
+	if err := h.publisher.PublishHeartbeat(&heartbeat); err != nil {
+		log.Printf("could not publish heartbeat: %v\n", err.Error())
+	}
+}
@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"sync/atomic"
+)
+
+const DefaultPoolSize = 10
+
+// Pool bounds how many jobs an instance processes at once.
+type Pool interface {
+	Size() int
+	Acquire()
+	Release()
+	Stats() Stats
+}
+
+type Stats struct {
+	Size        int     `json:"size"`
+	Busy        int64   `json:"busy"`
+	Idle        int64   `json:"idle"`
+	Utilisation float64 `json:"utilisation"`
+	Processed   uint64  `json:"processed"`
+}
+
+type pool struct {
+	slots     chan struct{}
+	busy      int64
+	processed uint64
+}
+
+func NewPool(size int) Pool {
+	if size < 1 {
+		size = DefaultPoolSize
+	}
+	return &pool{slots: make(chan struct{}, size)}
+}
+
+func (p *pool) Size() int {
+	return cap(p.slots)
+}
+
+// Acquire blocks until a worker slot is free.
+func (p *pool) Acquire() {
+	p.slots <- struct{}{}
+	atomic.AddInt64(&p.busy, 1)
+}
+
+func (p *pool) Release() {
+	atomic.AddInt64(&p.busy, -1)
+	atomic.AddUint64(&p.processed, 1)
+	<-p.slots
+}
+
+func (p *pool) Stats() Stats {
+	busy := atomic.LoadInt64(&p.busy)
+	size := p.Size()
+
+	return Stats{
+		Size:        size,
+		Busy:        busy,
+		Idle:        int64(size) - busy,
+		Utilisation: float64(busy) / float64(size),
+		Processed:   atomic.LoadUint64(&p.processed),
+	}
+}
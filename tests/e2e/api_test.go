@@ -1,14 +1,17 @@
 package e2e
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/bogdan-copocean/hasty-server/client"
+	"github.com/bogdan-copocean/hasty-server/services/api-server/domain"
 	"github.com/google/uuid"
 	"github.com/testcontainers/testcontainers-go"
 )
@@ -28,27 +31,8 @@ func setUpContainers() (*testcontainers.LocalDockerCompose, error) {
 	return compose, nil
 }
 
-type errorResponse struct {
-	Message string `json:"message"`
-}
-
-type detailResponse struct {
-	Id        string `json:"id,omitempty"`
-	JobId     string `json:"job_id"`
-	ObjectId  string `json:"object_id"`
-	Status    string `json:"status"`
-	Timestamp int64  `json:"timestamp"`
-}
-
-type getResponse struct {
-	Message detailResponse `json:"message"`
-}
-
-type createdResponse struct {
-	Message struct {
-		JobId string `json:"job_id"`
-	} `json:"message"`
-}
+// apiKey matches HASTY_ADMIN_KEY in the docker-compose file
+const apiKey = "hasty-e2e-admin-key"
 
 func TestUserCreateJobFlow(t *testing.T) {
 	compose, err := setUpContainers()
@@ -57,171 +41,227 @@ func TestUserCreateJobFlow(t *testing.T) {
 	}
 	defer compose.Down()
 
-	createdJob := createdResponse{}
+	c := client.NewClient("http://localhost", apiKey)
+	ctx := context.Background()
+
+	createdJob := &domain.ResponseJob{}
 
 	t.Run("create job", func(t *testing.T) {
 		objectId := "random-object-id"
-		payload := strings.NewReader(fmt.Sprintf(`{"object_id": "%v"}`, objectId))
 
-		res, err := http.Post("http://localhost/", "application/json", payload)
-		if err != nil {
-			t.Fatal(err.Error())
-		}
-
-		data, err := ioutil.ReadAll(res.Body)
+		responseJob, created, err := c.CreateJob(ctx, domain.JobRequest{ObjectId: objectId})
 		if err != nil {
 			t.Fatalf("error not expected, but got: %v", err.Error())
 		}
-		defer res.Body.Close()
 
-		if err := json.Unmarshal(data, &createdJob); err != nil {
-			t.Fatalf("error not expected, but got: %v", err.Error())
+		if !created {
+			t.Errorf("got an existing job, wanted a created one")
 		}
 
-		if res.StatusCode != http.StatusCreated {
-			t.Errorf("got: %v, wanted %v", res.StatusCode, http.StatusCreated)
-		}
+		createdJob = responseJob
 	})
 
-	t.Run("create job with the same object id in less than 5 minutes", func(t *testing.T) {
+	t.Run("create job with the same object id while it runs", func(t *testing.T) {
 		objectId := "random-object-id"
-		payload := strings.NewReader(fmt.Sprintf(`{"object_id": "%v"}`, objectId))
 
-		errRes := errorResponse{}
-		expected := errorResponse{Message: "you need to wait 5 minutes before rerunning the same job"}
+		expected := domain.Problem{Code: "job_in_progress", Detail: fmt.Sprintf("job %v for the same object is still processing", createdJob.JobId)}
 
-		res, err := http.Post("http://localhost/", "application/json", payload)
-		if err != nil {
-			t.Fatal(err.Error())
+		_, _, err := c.CreateJob(ctx, domain.JobRequest{ObjectId: objectId})
+
+		apiErr := &client.Error{}
+		if !errors.As(err, &apiErr) || apiErr.Problem == nil {
+			t.Fatalf("expected a problem, but got: %v", err)
 		}
 
-		data, err := ioutil.ReadAll(res.Body)
-		if err != nil {
-			t.Fatalf("error not expected, but got: %v", err.Error())
+		if apiErr.StatusCode != http.StatusConflict {
+			t.Errorf("got: %v, wanted %v", apiErr.StatusCode, http.StatusConflict)
 		}
-		defer res.Body.Close()
 
-		if err := json.Unmarshal(data, &errRes); err != nil {
-			t.Fatalf("error not expected, but got: %v", err.Error())
+		if apiErr.Header.Get("Content-Type") != "application/problem+json" {
+			t.Errorf("got: %v, wanted %v", apiErr.Header.Get("Content-Type"), "application/problem+json")
 		}
 
-		if res.StatusCode != http.StatusBadRequest {
-			t.Errorf("got: %v, wanted %v", res.StatusCode, http.StatusBadRequest)
+		if apiErr.Problem.Code != expected.Code {
+			t.Errorf("got: %v, wanted %v", apiErr.Problem.Code, expected.Code)
 		}
 
-		if errRes.Message != expected.Message {
-			t.Errorf("got: %v, wanted %v", errRes.Message, expected.Message)
+		if apiErr.Problem.Detail != expected.Detail {
+			t.Errorf("got: %v, wanted %v", apiErr.Problem.Detail, expected.Detail)
+		}
+	})
+
+	t.Run("create jobs for the same new object in parallel", func(t *testing.T) {
+		objectId := uuid.New().String()
+
+		statuses := make(chan int, 20)
+		var wg sync.WaitGroup
+		for i := 0; i < cap(statuses); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				_, _, err := c.CreateJob(ctx, domain.JobRequest{ObjectId: objectId})
+				if err == nil {
+					statuses <- http.StatusCreated
+					return
+				}
+
+				apiErr := &client.Error{}
+				if !errors.As(err, &apiErr) {
+					t.Error(err.Error())
+					return
+				}
+				statuses <- apiErr.StatusCode
+			}()
+		}
+		wg.Wait()
+		close(statuses)
+
+		created := 0
+		for status := range statuses {
+			switch status {
+			case http.StatusCreated:
+				created++
+			case http.StatusConflict:
+			default:
+				t.Errorf("got: %v, wanted %v or %v", status, http.StatusCreated, http.StatusConflict)
+			}
+		}
+
+		if created != 1 {
+			t.Errorf("got %v created jobs, wanted 1", created)
 		}
 	})
 
 	t.Run("get non existing job", func(t *testing.T) {
 		nonExistingJob := "non-existing-job"
 
-		errRes := errorResponse{}
-		expected := errorResponse{Message: "no job with id: " + nonExistingJob}
+		expected := domain.Problem{Code: "job_not_found", Detail: "no job with id: " + nonExistingJob}
 
-		res, err := http.Get("http://localhost/" + nonExistingJob)
-		if err != nil {
-			t.Fatal(err.Error())
+		_, err := c.GetJob(ctx, nonExistingJob)
+
+		apiErr := &client.Error{}
+		if !errors.As(err, &apiErr) || apiErr.Problem == nil {
+			t.Fatalf("expected a problem, but got: %v", err)
+		}
+
+		if apiErr.StatusCode != http.StatusNotFound {
+			t.Errorf("got: %v, wanted %v", apiErr.StatusCode, http.StatusNotFound)
+		}
+
+		if apiErr.Problem.Code != expected.Code {
+			t.Errorf("got: %v, wanted %v", apiErr.Problem.Code, expected.Code)
 		}
 
-		data, err := ioutil.ReadAll(res.Body)
+		if apiErr.Problem.Detail != expected.Detail {
+			t.Errorf("got: %v, wanted %v", apiErr.Problem.Detail, expected.Detail)
+		}
+	})
+
+	t.Run("get job and verify its status", func(t *testing.T) {
+		expected := domain.Job{ObjectId: "random-object-id", Status: "processing", JobId: createdJob.JobId}
+
+		job, err := c.GetJob(ctx, createdJob.JobId)
 		if err != nil {
 			t.Fatalf("error not expected, but got: %v", err.Error())
 		}
-		defer res.Body.Close()
 
-		if err := json.Unmarshal(data, &errRes); err != nil {
-			t.Fatalf("error not expected, but got: %v", err.Error())
+		if job.ObjectId != expected.ObjectId {
+			t.Errorf("got: %v, wanted %v", job.ObjectId, expected.ObjectId)
 		}
 
-		if res.StatusCode != http.StatusBadRequest {
-			t.Errorf("got: %v, wanted %v", res.StatusCode, http.StatusBadRequest)
+		if job.Status != expected.Status {
+			t.Errorf("got: %v, wanted %v", job.Status, expected.Status)
 		}
 
-		if errRes.Message != expected.Message {
-			t.Errorf("got: %v, wanted %v", errRes.Message, expected.Message)
+		if job.JobId != expected.JobId {
+			t.Errorf("got: %v, wanted %v", job.JobId, expected.JobId)
 		}
 	})
 
-	t.Run("get job and verify its status", func(t *testing.T) {
-		objectId := "random-object-id"
-		status := "processing"
+	t.Run("wait for the job to finish and verify updated status", func(t *testing.T) {
+		fmt.Println("[!] waiting up to 60 seconds for the job to finish...")
 
-		succRes := getResponse{Message: detailResponse{}}
-		expected := getResponse{Message: detailResponse{ObjectId: objectId, Status: status, JobId: createdJob.Message.JobId}}
+		waitCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+		defer cancel()
 
-		res, err := http.Get("http://localhost/" + createdJob.Message.JobId)
-		if err != nil {
-			t.Fatal(err.Error())
-		}
+		expected := domain.Job{ObjectId: "random-object-id", Status: "finished", JobId: createdJob.JobId}
 
-		data, err := ioutil.ReadAll(res.Body)
+		job, err := c.Wait(waitCtx, createdJob.JobId)
 		if err != nil {
 			t.Fatalf("error not expected, but got: %v", err.Error())
 		}
-		defer res.Body.Close()
 
-		if err := json.Unmarshal(data, &succRes); err != nil {
-			t.Fatalf("error not expected, but got: %v", err.Error())
+		if job.ObjectId != expected.ObjectId {
+			t.Errorf("got: %v, wanted %v", job.ObjectId, expected.ObjectId)
 		}
 
-		if res.StatusCode != http.StatusOK {
-			t.Errorf("got: %v, wanted %v", res.StatusCode, http.StatusOK)
+		if job.Status != expected.Status {
+			t.Errorf("got: %v, wanted %v", job.Status, expected.Status)
 		}
 
-		if succRes.Message.ObjectId != expected.Message.ObjectId {
-			t.Errorf("got: %v, wanted %v", succRes.Message.ObjectId, expected.Message.ObjectId)
+		if job.JobId != expected.JobId {
+			t.Errorf("got: %v, wanted %v", job.JobId, expected.JobId)
 		}
+	})
+
+	t.Run("create job with the same object id in less than 5 minutes", func(t *testing.T) {
+		objectId := "random-object-id"
 
-		if succRes.Message.Status != expected.Message.Status {
-			t.Errorf("got: %v, wanted %v", succRes.Message.Status, expected.Message.Status)
+		expected := domain.Problem{Code: "cooldown_active", Detail: "you need to wait 5 minutes before rerunning the same job"}
+
+		_, _, err := c.CreateJob(ctx, domain.JobRequest{ObjectId: objectId})
+
+		apiErr := &client.Error{}
+		if !errors.As(err, &apiErr) || apiErr.Problem == nil {
+			t.Fatalf("expected a problem, but got: %v", err)
 		}
 
-		if succRes.Message.JobId != expected.Message.JobId {
-			t.Errorf("got: %v, wanted %v", succRes.Message.JobId, expected.Message.JobId)
+		if apiErr.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("got: %v, wanted %v", apiErr.StatusCode, http.StatusTooManyRequests)
 		}
-	})
 
-	t.Run("sleep to finish job processing and verify updated status", func(t *testing.T) {
-		fmt.Println("[!] sleeping for 45 seconds to finish the job...")
-		time.Sleep(time.Second * 45)
+		if apiErr.Header.Get("Content-Type") != "application/problem+json" {
+			t.Errorf("got: %v, wanted %v", apiErr.Header.Get("Content-Type"), "application/problem+json")
+		}
 
-		objectId := "random-object-id"
-		status := "finished"
+		if apiErr.RetryAfter() == 0 {
+			t.Errorf("expected a Retry-After header")
+		}
 
-		succRes := getResponse{Message: detailResponse{}}
-		expected := getResponse{Message: detailResponse{ObjectId: objectId, Status: status, JobId: createdJob.Message.JobId}}
+		if apiErr.Problem.Code != expected.Code {
+			t.Errorf("got: %v, wanted %v", apiErr.Problem.Code, expected.Code)
+		}
 
-		res, err := http.Get("http://localhost/" + createdJob.Message.JobId)
-		if err != nil {
-			t.Fatal(err.Error())
+		if apiErr.Problem.Detail != expected.Detail {
+			t.Errorf("got: %v, wanted %v", apiErr.Problem.Detail, expected.Detail)
 		}
+	})
+
+	t.Run("cancel a job and list it", func(t *testing.T) {
+		objectId := uuid.New().String()
 
-		data, err := ioutil.ReadAll(res.Body)
+		responseJob, _, err := c.CreateJob(ctx, domain.JobRequest{ObjectId: objectId})
 		if err != nil {
 			t.Fatalf("error not expected, but got: %v", err.Error())
 		}
-		defer res.Body.Close()
 
-		if err := json.Unmarshal(data, &succRes); err != nil {
+		job, err := c.CancelJob(ctx, responseJob.JobId)
+		if err != nil {
 			t.Fatalf("error not expected, but got: %v", err.Error())
 		}
 
-		if res.StatusCode != http.StatusOK {
-			t.Errorf("got: %v, wanted %v", res.StatusCode, http.StatusOK)
+		if job.Status != domain.JobCancelled {
+			t.Errorf("got: %v, wanted %v", job.Status, domain.JobCancelled)
 		}
 
-		if succRes.Message.ObjectId != expected.Message.ObjectId {
-			t.Errorf("got: %v, wanted %v", succRes.Message.ObjectId, expected.Message.ObjectId)
-		}
-
-		if succRes.Message.Status != expected.Message.Status {
-			t.Errorf("got: %v, wanted %v", succRes.Message.Status, expected.Message.Status)
+		jobs, err := c.ListJobs(ctx, domain.JobFilter{ObjectId: objectId})
+		if err != nil {
+			t.Fatalf("error not expected, but got: %v", err.Error())
 		}
 
-		if succRes.Message.JobId != expected.Message.JobId {
-			t.Errorf("got: %v, wanted %v", succRes.Message.JobId, expected.Message.JobId)
+		if len(jobs) != 1 || jobs[0].Status != domain.JobCancelled {
+			t.Errorf("got: %v, wanted the cancelled job", jobs)
 		}
 	})
 }